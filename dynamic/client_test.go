@@ -36,6 +36,7 @@ import (
 	"k8s.io/apimachinery/pkg/watch"
 	restclient "k8s.io/client-go/rest"
 	restclientwatch "k8s.io/client-go/rest/watch"
+	"k8s.io/client-go/util/cbor"
 )
 
 func getJSON(version, kind, name string) []byte {
@@ -777,3 +778,111 @@ func TestInvalidSegments(t *testing.T) {
 		t.Fatalf("Expected `invalid namespace` error, got: %v", err)
 	}
 }
+
+func getCBORClientServer(h func(http.ResponseWriter, *http.Request)) (Interface, *httptest.Server, error) {
+	srv := httptest.NewServer(http.HandlerFunc(h))
+	cl, err := NewForConfig(&restclient.Config{
+		Host:          srv.URL,
+		ContentConfig: restclient.ContentConfig{ContentType: cbor.ContentTypeCBOR},
+	})
+	if err != nil {
+		srv.Close()
+		return nil, nil, err
+	}
+	return cl, srv, nil
+}
+
+func TestCBOR(t *testing.T) {
+	resource := schema.GroupVersionResource{Group: "gtest", Version: "vtest", Resource: "rtest"}
+	cl, srv, err := getCBORClientServer(func(w http.ResponseWriter, r *http.Request) {
+		if e, a := "application/cbor,application/json", r.Header.Get("Accept"); e != a {
+			t.Errorf("expected Accept %q, got %q", e, a)
+		}
+		w.Header().Set("Content-Type", cbor.ContentTypeCBOR)
+		switch {
+		case r.Method == "POST":
+			if e, a := cbor.ContentTypeCBOR, r.Header.Get("Content-Type"); e != a {
+				t.Errorf("expected Content-Type %q, got %q", e, a)
+			}
+			data, err := io.ReadAll(r.Body)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			w.Write(data)
+		case r.URL.Query().Get("watch") == "true":
+			enc := restclientwatch.NewEncoder(streaming.NewEncoder(cbor.Framer.NewFrameWriter(w), unstructuredCBOR), unstructuredCBOR)
+			enc.Encode(&watch.Event{Type: watch.Added, Object: getObject("gtest/vTest", "rTest", "item1")})
+		default:
+			list := &unstructured.UnstructuredList{Object: map[string]interface{}{"apiVersion": "gtest/vTest", "kind": "rTestList"}}
+			list.Items = append(list.Items, *getObject("gtest/vTest", "rTest", "item1"), *getObject("gtest/vTest", "rTest", "item2"))
+			if err := unstructuredCBOR.Encode(list, w); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}
+	})
+	if err != nil {
+		t.Fatalf("unexpected error when creating client: %v", err)
+	}
+	defer srv.Close()
+
+	want := getObject("gtest/vTest", "rTest", "item1")
+	got, err := cl.Resource(resource).Create(context.TODO(), want, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	list, err := cl.Resource(resource).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := 2, len(list.Items); e != a {
+		t.Fatalf("expected %d items, got %d", e, a)
+	}
+	if !reflect.DeepEqual(*want, list.Items[0]) {
+		t.Errorf("expected %v, got %v", want, list.Items[0])
+	}
+
+	watcher, err := cl.Resource(resource).Watch(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer watcher.Stop()
+	if e, a := (watch.Event{Type: watch.Added, Object: want}), <-watcher.ResultChan(); !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}
+
+func TestCBORFallback(t *testing.T) {
+	resource := schema.GroupVersionResource{Group: "gtest", Version: "vtest", Resource: "rtest"}
+	var contentTypes []string
+	cl, srv, err := getCBORClientServer(func(w http.ResponseWriter, r *http.Request) {
+		contentTypes = append(contentTypes, r.Header.Get("Content-Type"))
+		if r.Header.Get("Content-Type") == cbor.ContentTypeCBOR {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.Copy(w, r.Body)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error when creating client: %v", err)
+	}
+	defer srv.Close()
+
+	want := getObject("gtest/vTest", "rTest", "item1")
+	for i := 0; i < 2; i++ {
+		got, err := cl.Resource(resource).Create(context.TODO(), want, metav1.CreateOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	}
+	if e, a := []string{cbor.ContentTypeCBOR, "application/json", "application/json"}, contentTypes; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected content types %v, got %v", e, a)
+	}
+}
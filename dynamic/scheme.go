@@ -23,6 +23,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/apimachinery/pkg/runtime/serializer/json"
+	"k8s.io/client-go/util/cbor"
 )
 
 var watchScheme = runtime.NewScheme()
@@ -31,6 +32,7 @@ var deleteScheme = runtime.NewScheme()
 var parameterScheme = runtime.NewScheme()
 var deleteOptionsCodec = serializer.NewCodecFactory(deleteScheme)
 var dynamicParameterCodec = runtime.NewParameterCodec(parameterScheme)
+var unstructuredCBOR = cbor.NewSerializer(unstructuredCreater{basicScheme}, unstructuredTyper{basicScheme})
 
 var versionV1 = schema.GroupVersion{Version: "v1"}
 
@@ -59,6 +61,7 @@ func (s basicNegotiatedSerializer) SupportedMediaTypes() []runtime.SerializerInf
 				Framer:        json.Framer,
 			},
 		},
+		cbor.NewSerializerInfo(unstructuredCreater{basicScheme}, unstructuredTyper{basicScheme}),
 	}
 }
 
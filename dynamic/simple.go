@@ -29,20 +29,32 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/cbor"
 )
 
 type DynamicClient struct {
 	client rest.Interface
+	// cbor is whether the client sends objects encoded as CBOR.
+	cbor bool
 }
 
 var _ Interface = &DynamicClient{}
 
 // ConfigFor returns a copy of the provided config with the
 // appropriate dynamic client defaults set.
+//
+// The dynamic client sends and accepts JSON, unless the ContentType of the
+// provided config is "application/cbor", in which case it sends CBOR and
+// prefers it over JSON, falling back to JSON for servers which do not support
+// CBOR.
 func ConfigFor(inConfig *rest.Config) *rest.Config {
 	config := rest.CopyConfig(inConfig)
 	config.AcceptContentTypes = "application/json"
 	config.ContentType = "application/json"
+	if cbor.IsContentTypeCBOR(inConfig.ContentType) {
+		config.AcceptContentTypes = cbor.ContentTypeCBOR + ",application/json"
+		config.ContentType = cbor.ContentTypeCBOR
+	}
 	config.NegotiatedSerializer = basicNegotiatedSerializer{} // this gets used for discovery and error handling types
 	if config.UserAgent == "" {
 		config.UserAgent = rest.DefaultKubernetesUserAgent()
@@ -90,7 +102,32 @@ func NewForConfigAndClient(inConfig *rest.Config, h *http.Client) (*DynamicClien
 	if err != nil {
 		return nil, err
 	}
-	return &DynamicClient{client: restClient}, nil
+	return &DynamicClient{client: restClient, cbor: cbor.IsContentTypeCBOR(config.ContentType)}, nil
+}
+
+// body returns the body of a request sending obj: obj itself, for the request
+// to encode it as negotiated, if the client sends CBOR, and else its JSON
+// encoding.
+func (c *DynamicClient) body(obj *unstructured.Unstructured) (interface{}, error) {
+	if c.cbor {
+		return obj, nil
+	}
+	return runtime.Encode(unstructured.UnstructuredJSONScheme, obj)
+}
+
+// decodeUnstructured decodes the body of result, which is encoded as CBOR or
+// JSON depending on its content type.
+func decodeUnstructured(result rest.Result) (runtime.Object, error) {
+	var contentType string
+	retBytes, err := result.ContentType(&contentType).Raw()
+	if err != nil {
+		return nil, err
+	}
+	if cbor.IsContentTypeCBOR(contentType) {
+		obj, _, err := unstructuredCBOR.Decode(retBytes, nil, &unstructured.Unstructured{})
+		return obj, err
+	}
+	return runtime.Decode(unstructured.UnstructuredJSONScheme, retBytes)
 }
 
 type dynamicResourceClient struct {
@@ -110,7 +147,7 @@ func (c *dynamicResourceClient) Namespace(ns string) ResourceInterface {
 }
 
 func (c *dynamicResourceClient) Create(ctx context.Context, obj *unstructured.Unstructured, opts metav1.CreateOptions, subresources ...string) (*unstructured.Unstructured, error) {
-	outBytes, err := runtime.Encode(unstructured.UnstructuredJSONScheme, obj)
+	body, err := c.client.body(obj)
 	if err != nil {
 		return nil, err
 	}
@@ -133,18 +170,14 @@ func (c *dynamicResourceClient) Create(ctx context.Context, obj *unstructured.Un
 		Post().
 		AbsPath(append(c.makeURLSegments(name), subresources...)...).
 		SetHeader("Content-Type", runtime.ContentTypeJSON).
-		Body(outBytes).
+		Body(body).
 		SpecificallyVersionedParams(&opts, dynamicParameterCodec, versionV1).
 		Do(ctx)
 	if err := result.Error(); err != nil {
 		return nil, err
 	}
 
-	retBytes, err := result.Raw()
-	if err != nil {
-		return nil, err
-	}
-	uncastObj, err := runtime.Decode(unstructured.UnstructuredJSONScheme, retBytes)
+	uncastObj, err := decodeUnstructured(result)
 	if err != nil {
 		return nil, err
 	}
@@ -163,7 +196,7 @@ func (c *dynamicResourceClient) Update(ctx context.Context, obj *unstructured.Un
 	if err := validateNamespaceWithOptionalName(c.namespace, name); err != nil {
 		return nil, err
 	}
-	outBytes, err := runtime.Encode(unstructured.UnstructuredJSONScheme, obj)
+	body, err := c.client.body(obj)
 	if err != nil {
 		return nil, err
 	}
@@ -172,18 +205,14 @@ func (c *dynamicResourceClient) Update(ctx context.Context, obj *unstructured.Un
 		Put().
 		AbsPath(append(c.makeURLSegments(name), subresources...)...).
 		SetHeader("Content-Type", runtime.ContentTypeJSON).
-		Body(outBytes).
+		Body(body).
 		SpecificallyVersionedParams(&opts, dynamicParameterCodec, versionV1).
 		Do(ctx)
 	if err := result.Error(); err != nil {
 		return nil, err
 	}
 
-	retBytes, err := result.Raw()
-	if err != nil {
-		return nil, err
-	}
-	uncastObj, err := runtime.Decode(unstructured.UnstructuredJSONScheme, retBytes)
+	uncastObj, err := decodeUnstructured(result)
 	if err != nil {
 		return nil, err
 	}
@@ -202,7 +231,7 @@ func (c *dynamicResourceClient) UpdateStatus(ctx context.Context, obj *unstructu
 	if err := validateNamespaceWithOptionalName(c.namespace, name); err != nil {
 		return nil, err
 	}
-	outBytes, err := runtime.Encode(unstructured.UnstructuredJSONScheme, obj)
+	body, err := c.client.body(obj)
 	if err != nil {
 		return nil, err
 	}
@@ -211,18 +240,14 @@ func (c *dynamicResourceClient) UpdateStatus(ctx context.Context, obj *unstructu
 		Put().
 		AbsPath(append(c.makeURLSegments(name), "status")...).
 		SetHeader("Content-Type", runtime.ContentTypeJSON).
-		Body(outBytes).
+		Body(body).
 		SpecificallyVersionedParams(&opts, dynamicParameterCodec, versionV1).
 		Do(ctx)
 	if err := result.Error(); err != nil {
 		return nil, err
 	}
 
-	retBytes, err := result.Raw()
-	if err != nil {
-		return nil, err
-	}
-	uncastObj, err := runtime.Decode(unstructured.UnstructuredJSONScheme, retBytes)
+	uncastObj, err := decodeUnstructured(result)
 	if err != nil {
 		return nil, err
 	}
@@ -281,11 +306,7 @@ func (c *dynamicResourceClient) Get(ctx context.Context, name string, opts metav
 	if err := result.Error(); err != nil {
 		return nil, err
 	}
-	retBytes, err := result.Raw()
-	if err != nil {
-		return nil, err
-	}
-	uncastObj, err := runtime.Decode(unstructured.UnstructuredJSONScheme, retBytes)
+	uncastObj, err := decodeUnstructured(result)
 	if err != nil {
 		return nil, err
 	}
@@ -300,11 +321,7 @@ func (c *dynamicResourceClient) List(ctx context.Context, opts metav1.ListOption
 	if err := result.Error(); err != nil {
 		return nil, err
 	}
-	retBytes, err := result.Raw()
-	if err != nil {
-		return nil, err
-	}
-	uncastObj, err := runtime.Decode(unstructured.UnstructuredJSONScheme, retBytes)
+	uncastObj, err := decodeUnstructured(result)
 	if err != nil {
 		return nil, err
 	}
@@ -345,11 +362,7 @@ func (c *dynamicResourceClient) Patch(ctx context.Context, name string, pt types
 	if err := result.Error(); err != nil {
 		return nil, err
 	}
-	retBytes, err := result.Raw()
-	if err != nil {
-		return nil, err
-	}
-	uncastObj, err := runtime.Decode(unstructured.UnstructuredJSONScheme, retBytes)
+	uncastObj, err := decodeUnstructured(result)
 	if err != nil {
 		return nil, err
 	}
@@ -387,11 +400,7 @@ func (c *dynamicResourceClient) Apply(ctx context.Context, name string, obj *uns
 	if err := result.Error(); err != nil {
 		return nil, err
 	}
-	retBytes, err := result.Raw()
-	if err != nil {
-		return nil, err
-	}
-	uncastObj, err := runtime.Decode(unstructured.UnstructuredJSONScheme, retBytes)
+	uncastObj, err := decodeUnstructured(result)
 	if err != nil {
 		return nil, err
 	}
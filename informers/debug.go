@@ -0,0 +1,170 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// DebugState is the state of a SharedInformerFactory and of the named work
+// queues of the process, as served by the handler returned by
+// NewDebugHandler.
+type DebugState struct {
+	Informers  []InformerDebugState       `json:"informers"`
+	WorkQueues []workqueue.QueueDebugInfo `json:"workqueues"`
+}
+
+// InformerDebugState describes one informer of a SharedInformerFactory.
+type InformerDebugState struct {
+	// Type is the Go type of the informer's objects.
+	Type string `json:"type"`
+	// Group, Version and Resource identify the informer's resource. They
+	// are empty for types which ForResource does not know.
+	Group    string `json:"group"`
+	Version  string `json:"version"`
+	Resource string `json:"resource"`
+	// Started is true if the informer has been started by the factory.
+	Started bool `json:"started"`
+
+	cache.InformerDebugInfo
+}
+
+// NewDebugHandler returns an http.Handler that serves the state of every
+// informer of the given factory, and of the named work queues of the
+// process, as JSON. Work queues are only listed if
+// workqueue.EnableNamedQueueDebugInfo was called before they were created.
+//
+// The object stored under a specific key can be retrieved by passing the
+// resource and the key as query parameters, for example
+// ?group=apps&version=v1&resource=deployments&key=default/nginx.
+//
+// The handler exposes the contents of the informers' caches and should only
+// be served on a debugging endpoint with appropriate access control.
+func NewDebugHandler(factory SharedInformerFactory) http.Handler {
+	return &debugHandler{factory: factory}
+}
+
+type debugHandler struct {
+	factory SharedInformerFactory
+}
+
+func (h *debugHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var f *sharedInformerFactory
+	switch factory := h.factory.(type) {
+	case *sharedInformerFactory:
+		f = factory
+	case *multiNamespaceInformerFactory:
+		f = factory.factory
+	default:
+		http.Error(w, fmt.Sprintf("unsupported informer factory %T", h.factory), http.StatusInternalServerError)
+		return
+	}
+
+	query := req.URL.Query()
+	if key := query.Get("key"); key != "" {
+		gvr := schema.GroupVersionResource{Group: query.Get("group"), Version: query.Get("version"), Resource: query.Get("resource")}
+		h.serveObject(w, f, gvr, key)
+		return
+	}
+
+	state := DebugState{WorkQueues: workqueue.DebugInfoForNamedQueues()}
+	for _, informer := range f.debugInformers() {
+		state.Informers = append(state.Informers, InformerDebugState{
+			Type:              informer.informerType.String(),
+			Group:             informer.gvr.Group,
+			Version:           informer.gvr.Version,
+			Resource:          informer.gvr.Resource,
+			Started:           informer.started,
+			InformerDebugInfo: cache.GetInformerDebugInfo(informer.informer),
+		})
+	}
+	writeDebugJSON(w, state)
+}
+
+func (h *debugHandler) serveObject(w http.ResponseWriter, f *sharedInformerFactory, gvr schema.GroupVersionResource, key string) {
+	for _, informer := range f.debugInformers() {
+		if informer.gvr != gvr {
+			continue
+		}
+		obj, exists, err := informer.informer.GetStore().GetByKey(key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !exists {
+			http.Error(w, fmt.Sprintf("%s %q not found", gvr.String(), key), http.StatusNotFound)
+			return
+		}
+		writeDebugJSON(w, obj)
+		return
+	}
+	http.Error(w, fmt.Sprintf("no informer for %s", gvr.String()), http.StatusNotFound)
+}
+
+func writeDebugJSON(w http.ResponseWriter, v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+type debugInformer struct {
+	informerType reflect.Type
+	gvr          schema.GroupVersionResource
+	informer     cache.SharedIndexInformer
+	started      bool
+}
+
+// debugInformers returns the informers of the factory, sorted by resource.
+func (f *sharedInformerFactory) debugInformers() []debugInformer {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	informers := make([]debugInformer, 0, len(f.informers))
+	for informerType, informer := range f.informers {
+		informers = append(informers, debugInformer{
+			informerType: informerType,
+			gvr:          resourceForType(informerType),
+			informer:     informer,
+			started:      f.startedInformers[informerType],
+		})
+	}
+	sort.Slice(informers, func(i, j int) bool {
+		if informers[i].gvr != informers[j].gvr {
+			return informers[i].gvr.String() < informers[j].gvr.String()
+		}
+		return informers[i].informerType.String() < informers[j].informerType.String()
+	})
+	return informers
+}
+
+// resourceForType returns the resource of the informers of the given object
+// type, or an empty resource for types which ForResource does not know.
+func resourceForType(informerType reflect.Type) schema.GroupVersionResource {
+	return knownResources[informerType].gvr
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestKnownResources(t *testing.T) {
+	for informerType, resource := range knownResources {
+		gvr := resource.gvr
+		f := NewSharedInformerFactory(fake.NewSimpleClientset(), 0).(*sharedInformerFactory)
+		if _, err := f.ForResource(gvr); err != nil {
+			t.Errorf("%v: %v", gvr, err)
+			continue
+		}
+		if _, ok := f.informers[informerType]; !ok || len(f.informers) != 1 {
+			t.Errorf("expected the informer for %v to be of type %v", gvr, informerType)
+		}
+	}
+}
+
+func TestDebugHandler(t *testing.T) {
+	client := fake.NewSimpleClientset(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns"}})
+	f := NewSharedInformerFactory(client, 0)
+	f.Core().V1().Pods().Informer()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	f.Start(stop)
+	f.WaitForCacheSync(stop)
+
+	handler := NewDebugHandler(f)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var state DebugState
+	if err := json.Unmarshal(recorder.Body.Bytes(), &state); err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Informers) != 1 {
+		t.Fatalf("expected one informer, got %+v", state.Informers)
+	}
+	informer := state.Informers[0]
+	if informer.Type != reflect.TypeOf(&v1.Pod{}).String() || informer.Group != "" || informer.Version != "v1" || informer.Resource != "pods" {
+		t.Errorf("unexpected informer resource: %+v", informer)
+	}
+	if !informer.Started || !informer.Synced || informer.StoreSize != 1 {
+		t.Errorf("unexpected informer state: %+v", informer)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/?version=v1&resource=pods&key=ns/pod", nil))
+	pod := &v1.Pod{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), pod); err != nil {
+		t.Fatal(err)
+	}
+	if pod.Name != "pod" {
+		t.Errorf("expected pod ns/pod, got %s", recorder.Body.String())
+	}
+
+	for _, query := range []string{"/?version=v1&resource=pods&key=ns/missing", "/?version=v1&resource=secrets&key=ns/pod"} {
+		recorder = httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", query, nil))
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("%s: expected status %d, got %d", query, http.StatusNotFound, recorder.Code)
+		}
+	}
+}
@@ -0,0 +1,422 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// resources-gen generates the table of the resources of the informers of
+// k8s.io/client-go/informers, from the code informer-gen generated there.
+// Every resource which ForResource knows is listed, with the object type of
+// its informers and whether it is namespaced.
+//
+// It is run with go generate from the informers directory, after
+// informer-gen.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const informersPackage = "k8s.io/client-go/informers"
+
+func main() {
+	dir := flag.String("informers-dir", ".", "directory of the informers package")
+	output := flag.String("o", "resources.go", "file to write, relative to the informers directory")
+	flag.Parse()
+
+	data, err := generate(*dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "resources-gen: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(filepath.Join(*dir, *output), data, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "resources-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// resource is a case of ForResource.
+type resource struct {
+	// alias is the import alias of the API package in generic.go.
+	alias    string
+	resource string
+	// accessors are the methods of the factory which return the typed
+	// informer, for example Core, V1 and Pods.
+	accessors []string
+
+	kind       string
+	namespaced bool
+}
+
+// generate returns the content of the table for the informers package in
+// dir.
+func generate(dir string) ([]byte, error) {
+	generic, err := parseFile(filepath.Join(dir, "generic.go"))
+	if err != nil {
+		return nil, err
+	}
+	resources, err := forResourceCases(generic)
+	if err != nil {
+		return nil, err
+	}
+	factory, err := parseFile(filepath.Join(dir, "factory.go"))
+	if err != nil {
+		return nil, err
+	}
+	genericImports := imports(generic)
+
+	for i := range resources {
+		if err := resolve(dir, factory, genericImports, &resources[i]); err != nil {
+			return nil, err
+		}
+	}
+	return render(genericImports, resources)
+}
+
+// forResourceCases returns the resources of the cases of ForResource.
+func forResourceCases(file *ast.File) ([]resource, error) {
+	var resources []resource
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Name.Name != "ForResource" {
+			continue
+		}
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			clause, ok := n.(*ast.CaseClause)
+			if !ok || len(clause.List) != 1 || len(clause.Body) != 1 {
+				return true
+			}
+			r, ok := parseCase(clause)
+			if ok {
+				resources = append(resources, r)
+			}
+			return false
+		})
+	}
+	if len(resources) == 0 {
+		return nil, fmt.Errorf("no resources found in ForResource")
+	}
+	return resources, nil
+}
+
+// parseCase parses a case of the form
+//
+//	case v1.SchemeGroupVersion.WithResource("pods"):
+//		return &genericInformer{resource: resource.GroupResource(), informer: f.Core().V1().Pods().Informer()}, nil
+func parseCase(clause *ast.CaseClause) (resource, bool) {
+	call, ok := clause.List[0].(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 {
+		return resource{}, false
+	}
+	withResource, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || withResource.Sel.Name != "WithResource" {
+		return resource{}, false
+	}
+	groupVersion, ok := withResource.X.(*ast.SelectorExpr)
+	if !ok || groupVersion.Sel.Name != "SchemeGroupVersion" {
+		return resource{}, false
+	}
+	alias, ok := groupVersion.X.(*ast.Ident)
+	if !ok {
+		return resource{}, false
+	}
+	lit, ok := call.Args[0].(*ast.BasicLit)
+	if !ok {
+		return resource{}, false
+	}
+	name, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return resource{}, false
+	}
+
+	ret, ok := clause.Body[0].(*ast.ReturnStmt)
+	if !ok || len(ret.Results) == 0 {
+		return resource{}, false
+	}
+	var accessors []string
+	ast.Inspect(ret.Results[0], func(n ast.Node) bool {
+		kv, ok := n.(*ast.KeyValueExpr)
+		if !ok {
+			return true
+		}
+		if key, ok := kv.Key.(*ast.Ident); ok && key.Name == "informer" {
+			accessors = callChain(kv.Value)
+		}
+		return false
+	})
+	// The chain ends with Informer.
+	if len(accessors) != 4 {
+		return resource{}, false
+	}
+	return resource{alias: alias.Name, resource: name, accessors: accessors[:3]}, true
+}
+
+// callChain returns the names of the methods called by an expression of the
+// form f.A().B().C().
+func callChain(expr ast.Expr) []string {
+	var names []string
+	for {
+		call, ok := expr.(*ast.CallExpr)
+		if !ok {
+			break
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return nil
+		}
+		names = append([]string{sel.Sel.Name}, names...)
+		expr = sel.X
+	}
+	return names
+}
+
+// resolve fills the kind of r, and whether it is namespaced, by following
+// its accessors through the typed informer packages.
+func resolve(dir string, factory *ast.File, genericImports map[string]string, r *resource) error {
+	groupPkg, err := interfaceMethodPackage(factory, "SharedInformerFactory", r.accessors[0])
+	if err != nil {
+		return err
+	}
+	groupDir := filepath.Join(dir, filepath.FromSlash(strings.TrimPrefix(groupPkg, informersPackage+"/")))
+	group, err := parseFile(filepath.Join(groupDir, "interface.go"))
+	if err != nil {
+		return err
+	}
+	versionPkg, err := interfaceMethodPackage(group, "Interface", r.accessors[1])
+	if err != nil {
+		return err
+	}
+	versionDir := filepath.Join(dir, filepath.FromSlash(strings.TrimPrefix(versionPkg, informersPackage+"/")))
+	version, err := parseFile(filepath.Join(versionDir, "interface.go"))
+	if err != nil {
+		return err
+	}
+	result, err := interfaceMethodResult(version, "Interface", r.accessors[2])
+	if err != nil {
+		return err
+	}
+	ident, ok := result.(*ast.Ident)
+	if !ok || !strings.HasSuffix(ident.Name, "Informer") {
+		return fmt.Errorf("%s: unexpected result of %s", versionDir, r.accessors[2])
+	}
+	r.kind = strings.TrimSuffix(ident.Name, "Informer")
+
+	files, err := filepath.Glob(filepath.Join(versionDir, "*.go"))
+	if err != nil {
+		return err
+	}
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parseFile(name)
+		if err != nil {
+			return err
+		}
+		fn := findFunc(file, "NewFiltered"+r.kind+"Informer")
+		if fn == nil {
+			continue
+		}
+		for _, param := range fn.Type.Params.List {
+			for _, name := range param.Names {
+				if name.Name == "namespace" {
+					r.namespaced = true
+				}
+			}
+		}
+		objectPkg, ok := informerForPackage(file, r.kind)
+		if !ok {
+			return fmt.Errorf("%s: no InformerFor call for %s", name, r.kind)
+		}
+		if genericImports[r.alias] != objectPkg {
+			return fmt.Errorf("%s: %s is not in package %s", name, r.kind, genericImports[r.alias])
+		}
+		return nil
+	}
+	return fmt.Errorf("%s: no informer for %s", versionDir, r.kind)
+}
+
+// informerForPackage returns the import path of the package of kind, as
+// passed to InformerFor in file.
+func informerForPackage(file *ast.File, kind string) (string, bool) {
+	var pkg string
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) != 2 {
+			return true
+		}
+		if sel, ok := call.Fun.(*ast.SelectorExpr); !ok || sel.Sel.Name != "InformerFor" {
+			return true
+		}
+		unary, ok := call.Args[0].(*ast.UnaryExpr)
+		if !ok {
+			return true
+		}
+		lit, ok := unary.X.(*ast.CompositeLit)
+		if !ok {
+			return true
+		}
+		typ, ok := lit.Type.(*ast.SelectorExpr)
+		if !ok || typ.Sel.Name != kind {
+			return true
+		}
+		if alias, ok := typ.X.(*ast.Ident); ok {
+			pkg = imports(file)[alias.Name]
+		}
+		return false
+	})
+	return pkg, pkg != ""
+}
+
+// interfaceMethodPackage returns the import path of the package of the
+// result of a method of an interface, for a result of the form pkg.Type.
+func interfaceMethodPackage(file *ast.File, iface, method string) (string, error) {
+	result, err := interfaceMethodResult(file, iface, method)
+	if err != nil {
+		return "", err
+	}
+	sel, ok := result.(*ast.SelectorExpr)
+	if !ok {
+		return "", fmt.Errorf("unexpected result of %s.%s", iface, method)
+	}
+	alias, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return "", fmt.Errorf("unexpected result of %s.%s", iface, method)
+	}
+	pkg, ok := imports(file)[alias.Name]
+	if !ok {
+		return "", fmt.Errorf("unknown package %s", alias.Name)
+	}
+	return pkg, nil
+}
+
+// interfaceMethodResult returns the type of the single result of a method of
+// an interface.
+func interfaceMethodResult(file *ast.File, iface, method string) (ast.Expr, error) {
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			it, ok := typeSpec.Type.(*ast.InterfaceType)
+			if !ok || typeSpec.Name.Name != iface {
+				continue
+			}
+			for _, field := range it.Methods.List {
+				fn, ok := field.Type.(*ast.FuncType)
+				if !ok || len(field.Names) != 1 || field.Names[0].Name != method {
+					continue
+				}
+				if fn.Results == nil || len(fn.Results.List) != 1 {
+					return nil, fmt.Errorf("unexpected results of %s.%s", iface, method)
+				}
+				return fn.Results.List[0].Type, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no method %s.%s", iface, method)
+}
+
+func findFunc(file *ast.File, name string) *ast.FuncDecl {
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Name.Name == name {
+			return fn
+		}
+	}
+	return nil
+}
+
+// imports returns the import paths of a file by alias.
+func imports(file *ast.File) map[string]string {
+	ret := map[string]string{}
+	for _, spec := range file.Imports {
+		pkg, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		alias := path.Base(pkg)
+		if spec.Name != nil {
+			alias = spec.Name.Name
+		}
+		ret[alias] = pkg
+	}
+	return ret
+}
+
+func parseFile(name string) (*ast.File, error) {
+	return parser.ParseFile(token.NewFileSet(), name, nil, parser.SkipObjectResolution)
+}
+
+const header = `/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by resources-gen. DO NOT EDIT.
+
+package informers
+
+`
+
+func render(genericImports map[string]string, resources []resource) ([]byte, error) {
+	used := map[string]bool{}
+	for _, r := range resources {
+		used[r.alias] = true
+	}
+	aliases := make([]string, 0, len(used))
+	for alias := range used {
+		aliases = append(aliases, alias)
+	}
+	sort.Slice(aliases, func(i, j int) bool { return genericImports[aliases[i]] < genericImports[aliases[j]] })
+
+	var buf bytes.Buffer
+	buf.WriteString(header)
+	buf.WriteString("import (\n\t\"reflect\"\n\n")
+	for _, alias := range aliases {
+		fmt.Fprintf(&buf, "\t%s %q\n", alias, genericImports[alias])
+	}
+	buf.WriteString(")\n\n")
+	buf.WriteString("// knownResources maps the object types of the informers this factory\n")
+	buf.WriteString("// creates to their resource, following ForResource.\n")
+	buf.WriteString("var knownResources = map[reflect.Type]knownResource{\n")
+	for _, r := range resources {
+		fmt.Fprintf(&buf, "\treflect.TypeOf(&%s.%s{}): {gvr: %s.SchemeGroupVersion.WithResource(%q), namespaced: %t},\n", r.alias, r.kind, r.alias, r.resource, r.namespaced)
+	}
+	buf.WriteString("}\n")
+	return format.Source(buf.Bytes())
+}
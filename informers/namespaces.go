@@ -0,0 +1,297 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/informers/admissionregistration"
+	"k8s.io/client-go/informers/apiserverinternal"
+	"k8s.io/client-go/informers/apps"
+	"k8s.io/client-go/informers/autoscaling"
+	"k8s.io/client-go/informers/batch"
+	"k8s.io/client-go/informers/certificates"
+	"k8s.io/client-go/informers/coordination"
+	"k8s.io/client-go/informers/core"
+	"k8s.io/client-go/informers/discovery"
+	"k8s.io/client-go/informers/events"
+	"k8s.io/client-go/informers/extensions"
+	"k8s.io/client-go/informers/flowcontrol"
+	"k8s.io/client-go/informers/internalinterfaces"
+	"k8s.io/client-go/informers/networking"
+	"k8s.io/client-go/informers/node"
+	"k8s.io/client-go/informers/policy"
+	"k8s.io/client-go/informers/rbac"
+	"k8s.io/client-go/informers/resource"
+	"k8s.io/client-go/informers/scheduling"
+	"k8s.io/client-go/informers/storage"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+//go:generate go run ./internal/resources-gen
+
+// knownResource is the resource of the informers of an object type.
+type knownResource struct {
+	gvr        schema.GroupVersionResource
+	namespaced bool
+}
+
+// MultiNamespaceSharedInformerFactory is a SharedInformerFactory whose
+// informers of namespaced resources are limited to a set of namespaces. Each
+// such informer is a cache.MultiNamespaceInformer, which runs one reflector
+// per namespace but exposes a single store and event stream, so listers and
+// event handlers see the objects of all the namespaces. Informers of
+// cluster-scoped resources are not affected.
+type MultiNamespaceSharedInformerFactory interface {
+	SharedInformerFactory
+
+	// AddNamespaces adds namespaces to the factory. Its existing informers
+	// start watching them, and deliver their objects to the event handlers
+	// as additions.
+	AddNamespaces(namespaces ...string) error
+	// RemoveNamespaces removes namespaces from the factory. Its existing
+	// informers stop watching them, and deliver their objects to the event
+	// handlers as deletions.
+	RemoveNamespaces(namespaces ...string) error
+	// Namespaces returns the namespaces of the factory, sorted.
+	Namespaces() []string
+}
+
+// NewMultiNamespaceSharedInformerFactory constructs a new instance of
+// MultiNamespaceSharedInformerFactory for the given namespaces. The options
+// apply as for NewSharedInformerFactoryWithOptions, except WithNamespace,
+// which is ignored. There is no SharedInformerOption to limit a factory to a
+// set of namespaces: the factory returned by NewSharedInformerFactoryWithOptions
+// is generated, and can only watch a single namespace or all of them.
+//
+// InformerFor panics if it is passed an object which is not the object type
+// of one of the informers of the factory, since the informer newFunc returns
+// for it could only be limited to a single namespace. ForResource returns an
+// error for a resource the factory does not know.
+func NewMultiNamespaceSharedInformerFactory(client kubernetes.Interface, defaultResync time.Duration, namespaces []string, options ...SharedInformerOption) MultiNamespaceSharedInformerFactory {
+	return &multiNamespaceInformerFactory{
+		factory:    NewSharedInformerFactoryWithOptions(client, defaultResync, options...).(*sharedInformerFactory),
+		namespaces: sets.NewString(namespaces...),
+	}
+}
+
+// multiNamespaceInformerFactory stores its informers in a sharedInformerFactory,
+// which starts, syncs and shuts them down, but creates them itself.
+type multiNamespaceInformerFactory struct {
+	factory *sharedInformerFactory
+
+	// updateLock serializes AddNamespaces and RemoveNamespaces.
+	updateLock sync.Mutex
+	// lock guards namespaces. It is acquired after factory.lock.
+	lock       sync.Mutex
+	namespaces sets.String
+}
+
+var _ MultiNamespaceSharedInformerFactory = &multiNamespaceInformerFactory{}
+
+func (f *multiNamespaceInformerFactory) AddNamespaces(namespaces ...string) error {
+	return f.updateNamespaces(namespaces, true)
+}
+
+func (f *multiNamespaceInformerFactory) RemoveNamespaces(namespaces ...string) error {
+	return f.updateNamespaces(namespaces, false)
+}
+
+func (f *multiNamespaceInformerFactory) Namespaces() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.namespaces.List()
+}
+
+func (f *multiNamespaceInformerFactory) updateNamespaces(namespaces []string, add bool) error {
+	f.updateLock.Lock()
+	defer f.updateLock.Unlock()
+
+	// Informers created from now on watch the new namespaces, the existing
+	// ones are updated below.
+	f.lock.Lock()
+	if add {
+		f.namespaces.Insert(namespaces...)
+	} else {
+		f.namespaces.Delete(namespaces...)
+	}
+	f.lock.Unlock()
+
+	var informers []cache.MultiNamespaceInformer
+	f.factory.lock.Lock()
+	for _, informer := range f.factory.informers {
+		if m, ok := informer.(cache.MultiNamespaceInformer); ok {
+			informers = append(informers, m)
+		}
+	}
+	f.factory.lock.Unlock()
+
+	// Removing a namespace delivers deletions to the event handlers, which
+	// must not be done while holding the factory lock.
+	var errs []error
+	for _, informer := range informers {
+		for _, namespace := range namespaces {
+			var err error
+			if add {
+				err = informer.AddNamespace(namespace)
+			} else {
+				err = informer.RemoveNamespace(namespace)
+			}
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func (f *multiNamespaceInformerFactory) Start(stopCh <-chan struct{}) {
+	f.factory.Start(stopCh)
+}
+
+func (f *multiNamespaceInformerFactory) Shutdown() {
+	f.factory.Shutdown()
+}
+
+func (f *multiNamespaceInformerFactory) WaitForCacheSync(stopCh <-chan struct{}) map[reflect.Type]bool {
+	return f.factory.WaitForCacheSync(stopCh)
+}
+
+func (f *multiNamespaceInformerFactory) ForResource(gvr schema.GroupVersionResource) (GenericInformer, error) {
+	for informerType, known := range knownResources {
+		if known.gvr != gvr {
+			continue
+		}
+		if !known.namespaced {
+			return f.factory.ForResource(gvr)
+		}
+		obj := reflect.New(informerType.Elem()).Interface().(runtime.Object)
+		return &genericInformer{resource: gvr.GroupResource(), informer: f.namespacedInformerFor(obj, known)}, nil
+	}
+	return nil, fmt.Errorf("no informer found for %v", gvr)
+}
+
+func (f *multiNamespaceInformerFactory) InformerFor(obj runtime.Object, newFunc internalinterfaces.NewInformerFunc) cache.SharedIndexInformer {
+	known, ok := knownResources[reflect.TypeOf(obj)]
+	if !ok {
+		panic(fmt.Sprintf("cannot limit the informer for %T to namespaces: unknown resource", obj))
+	}
+	if !known.namespaced {
+		return f.factory.InformerFor(obj, newFunc)
+	}
+	return f.namespacedInformerFor(obj, known)
+}
+
+// namespacedInformerFor returns the informer for obj, of a namespaced
+// resource. The informer of each namespace comes from a factory limited to
+// that namespace, since the newFunc of the typed informers is bound to the
+// namespace of the factory which created them.
+func (f *multiNamespaceInformerFactory) namespacedInformerFor(obj runtime.Object, known knownResource) cache.SharedIndexInformer {
+	return f.factory.InformerFor(obj, func(client kubernetes.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+		return cache.NewMultiNamespaceInformer(f.Namespaces(), func(namespace string) cache.SharedIndexInformer {
+			factory := NewSharedInformerFactoryWithOptions(client, resyncPeriod, WithNamespace(namespace), WithTweakListOptions(f.factory.tweakListOptions))
+			// knownResources only holds resources which ForResource knows.
+			informer, _ := factory.ForResource(known.gvr)
+			return informer.Informer()
+		})
+	})
+}
+
+func (f *multiNamespaceInformerFactory) Admissionregistration() admissionregistration.Interface {
+	return admissionregistration.New(f, metav1.NamespaceAll, f.factory.tweakListOptions)
+}
+
+func (f *multiNamespaceInformerFactory) Internal() apiserverinternal.Interface {
+	return apiserverinternal.New(f, metav1.NamespaceAll, f.factory.tweakListOptions)
+}
+
+func (f *multiNamespaceInformerFactory) Apps() apps.Interface {
+	return apps.New(f, metav1.NamespaceAll, f.factory.tweakListOptions)
+}
+
+func (f *multiNamespaceInformerFactory) Autoscaling() autoscaling.Interface {
+	return autoscaling.New(f, metav1.NamespaceAll, f.factory.tweakListOptions)
+}
+
+func (f *multiNamespaceInformerFactory) Batch() batch.Interface {
+	return batch.New(f, metav1.NamespaceAll, f.factory.tweakListOptions)
+}
+
+func (f *multiNamespaceInformerFactory) Certificates() certificates.Interface {
+	return certificates.New(f, metav1.NamespaceAll, f.factory.tweakListOptions)
+}
+
+func (f *multiNamespaceInformerFactory) Coordination() coordination.Interface {
+	return coordination.New(f, metav1.NamespaceAll, f.factory.tweakListOptions)
+}
+
+func (f *multiNamespaceInformerFactory) Core() core.Interface {
+	return core.New(f, metav1.NamespaceAll, f.factory.tweakListOptions)
+}
+
+func (f *multiNamespaceInformerFactory) Discovery() discovery.Interface {
+	return discovery.New(f, metav1.NamespaceAll, f.factory.tweakListOptions)
+}
+
+func (f *multiNamespaceInformerFactory) Events() events.Interface {
+	return events.New(f, metav1.NamespaceAll, f.factory.tweakListOptions)
+}
+
+func (f *multiNamespaceInformerFactory) Extensions() extensions.Interface {
+	return extensions.New(f, metav1.NamespaceAll, f.factory.tweakListOptions)
+}
+
+func (f *multiNamespaceInformerFactory) Flowcontrol() flowcontrol.Interface {
+	return flowcontrol.New(f, metav1.NamespaceAll, f.factory.tweakListOptions)
+}
+
+func (f *multiNamespaceInformerFactory) Networking() networking.Interface {
+	return networking.New(f, metav1.NamespaceAll, f.factory.tweakListOptions)
+}
+
+func (f *multiNamespaceInformerFactory) Node() node.Interface {
+	return node.New(f, metav1.NamespaceAll, f.factory.tweakListOptions)
+}
+
+func (f *multiNamespaceInformerFactory) Policy() policy.Interface {
+	return policy.New(f, metav1.NamespaceAll, f.factory.tweakListOptions)
+}
+
+func (f *multiNamespaceInformerFactory) Rbac() rbac.Interface {
+	return rbac.New(f, metav1.NamespaceAll, f.factory.tweakListOptions)
+}
+
+func (f *multiNamespaceInformerFactory) Resource() resource.Interface {
+	return resource.New(f, metav1.NamespaceAll, f.factory.tweakListOptions)
+}
+
+func (f *multiNamespaceInformerFactory) Scheduling() scheduling.Interface {
+	return scheduling.New(f, metav1.NamespaceAll, f.factory.tweakListOptions)
+}
+
+func (f *multiNamespaceInformerFactory) Storage() storage.Interface {
+	return storage.New(f, metav1.NamespaceAll, f.factory.tweakListOptions)
+}
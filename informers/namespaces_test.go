@@ -0,0 +1,165 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestMultiNamespaceSharedInformerFactory(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "a"}},
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "a"}},
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "b"}},
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "c"}},
+	)
+	f := NewMultiNamespaceSharedInformerFactory(client, 0, []string{"a", "b"})
+	podInformer := f.Core().V1().Pods().Informer()
+	if _, ok := podInformer.(cache.MultiNamespaceInformer); !ok {
+		t.Fatalf("expected a multi-namespace informer for pods, got %T", podInformer)
+	}
+	namespaceInformer := f.Core().V1().Namespaces().Informer()
+	if _, ok := namespaceInformer.(cache.MultiNamespaceInformer); ok {
+		t.Fatalf("expected a plain informer for cluster-scoped namespaces")
+	}
+
+	var lock sync.Mutex
+	var events []string
+	record := func(event string, obj interface{}) {
+		key, _ := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+		lock.Lock()
+		defer lock.Unlock()
+		events = append(events, event+" "+key)
+	}
+	if _, err := podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { record("add", obj) },
+		UpdateFunc: func(_, obj interface{}) { record("update", obj) },
+		DeleteFunc: func(obj interface{}) { record("delete", obj) },
+	}); err != nil {
+		t.Fatal(err)
+	}
+	waitForEvents := func(expected ...string) {
+		t.Helper()
+		err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			return len(events) == len(expected), nil
+		})
+		lock.Lock()
+		defer lock.Unlock()
+		if err != nil || !sets.NewString(events...).Equal(sets.NewString(expected...)) {
+			t.Fatalf("expected events %v, got %v", expected, events)
+		}
+		events = nil
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	f.Start(stop)
+	for informerType, synced := range f.WaitForCacheSync(stop) {
+		if !synced {
+			t.Fatalf("informer for %v did not sync", informerType)
+		}
+	}
+	waitForEvents("add a/pod", "add b/pod")
+	if keys := namespaceInformer.GetStore().ListKeys(); len(keys) != 1 {
+		t.Errorf("expected the namespace informer to list all namespaces, got %v", keys)
+	}
+
+	if err := f.AddNamespaces("c"); err != nil {
+		t.Fatal(err)
+	}
+	waitForEvents("add c/pod")
+	if err := f.RemoveNamespaces("a"); err != nil {
+		t.Fatal(err)
+	}
+	waitForEvents("delete a/pod")
+
+	if keys := podInformer.GetStore().ListKeys(); !sets.NewString(keys...).Equal(sets.NewString("b/pod", "c/pod")) {
+		t.Errorf("unexpected pods %v", keys)
+	}
+	pods, err := f.Core().V1().Pods().Lister().List(labels.Everything())
+	if err != nil || len(pods) != 2 {
+		t.Errorf("expected the lister to see two pods, got %v, %v", pods, err)
+	}
+
+	// Informers created later on watch the current namespaces.
+	if _, err := client.CoreV1().ConfigMaps("c").Create(context.TODO(), &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm"}}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	configMapInformer := f.Core().V1().ConfigMaps().Informer()
+	f.Start(stop)
+	if !cache.WaitForCacheSync(stop, configMapInformer.HasSynced) {
+		t.Fatal("config map informer did not sync")
+	}
+	if got := configMapInformer.(cache.MultiNamespaceInformer).Namespaces(); !reflect.DeepEqual(got, []string{"b", "c"}) {
+		t.Errorf("expected namespaces [b c], got %v", got)
+	}
+	if keys := configMapInformer.GetStore().ListKeys(); !reflect.DeepEqual(keys, []string{"c/cm"}) {
+		t.Errorf("unexpected config maps %v", keys)
+	}
+}
+
+func TestMultiNamespaceSharedInformerFactoryForResource(t *testing.T) {
+	f := NewMultiNamespaceSharedInformerFactory(fake.NewSimpleClientset(), 0, []string{"a"})
+	pods, err := f.ForResource(v1.SchemeGroupVersion.WithResource("pods"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pods.Informer() != f.Core().V1().Pods().Informer() {
+		t.Errorf("expected ForResource and the typed informer to share the informer for pods")
+	}
+	if _, ok := pods.Informer().(cache.MultiNamespaceInformer); !ok {
+		t.Errorf("expected a multi-namespace informer for pods, got %T", pods.Informer())
+	}
+	nodes, err := f.ForResource(v1.SchemeGroupVersion.WithResource("nodes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := nodes.Informer().(cache.MultiNamespaceInformer); ok {
+		t.Errorf("expected a plain informer for cluster-scoped nodes")
+	}
+	if _, err := f.ForResource(v1.SchemeGroupVersion.WithResource("unknown")); err == nil {
+		t.Errorf("expected an error for an unknown resource")
+	}
+}
+
+func TestMultiNamespaceSharedInformerFactoryUnknownType(t *testing.T) {
+	f := NewMultiNamespaceSharedInformerFactory(fake.NewSimpleClientset(), 0, []string{"a"})
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected InformerFor to panic for an unknown type")
+		}
+	}()
+	f.InformerFor(&metav1.PartialObjectMetadata{}, func(kubernetes.Interface, time.Duration) cache.SharedIndexInformer {
+		t.Errorf("expected no informer to be created for an unknown type")
+		return nil
+	})
+}
@@ -0,0 +1,185 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by resources-gen. DO NOT EDIT.
+
+package informers
+
+import (
+	"reflect"
+
+	v1 "k8s.io/api/admissionregistration/v1"
+	v1alpha1 "k8s.io/api/admissionregistration/v1alpha1"
+	v1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	apiserverinternalv1alpha1 "k8s.io/api/apiserverinternal/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	appsv1beta1 "k8s.io/api/apps/v1beta1"
+	v1beta2 "k8s.io/api/apps/v1beta2"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	v2 "k8s.io/api/autoscaling/v2"
+	v2beta1 "k8s.io/api/autoscaling/v2beta1"
+	v2beta2 "k8s.io/api/autoscaling/v2beta2"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	certificatesv1alpha1 "k8s.io/api/certificates/v1alpha1"
+	certificatesv1beta1 "k8s.io/api/certificates/v1beta1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	coordinationv1beta1 "k8s.io/api/coordination/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	eventsv1 "k8s.io/api/events/v1"
+	eventsv1beta1 "k8s.io/api/events/v1beta1"
+	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	flowcontrolv1alpha1 "k8s.io/api/flowcontrol/v1alpha1"
+	flowcontrolv1beta1 "k8s.io/api/flowcontrol/v1beta1"
+	flowcontrolv1beta2 "k8s.io/api/flowcontrol/v1beta2"
+	v1beta3 "k8s.io/api/flowcontrol/v1beta3"
+	networkingv1 "k8s.io/api/networking/v1"
+	networkingv1alpha1 "k8s.io/api/networking/v1alpha1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	nodev1 "k8s.io/api/node/v1"
+	nodev1alpha1 "k8s.io/api/node/v1alpha1"
+	nodev1beta1 "k8s.io/api/node/v1beta1"
+	policyv1 "k8s.io/api/policy/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	rbacv1alpha1 "k8s.io/api/rbac/v1alpha1"
+	rbacv1beta1 "k8s.io/api/rbac/v1beta1"
+	v1alpha2 "k8s.io/api/resource/v1alpha2"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	schedulingv1alpha1 "k8s.io/api/scheduling/v1alpha1"
+	schedulingv1beta1 "k8s.io/api/scheduling/v1beta1"
+	storagev1 "k8s.io/api/storage/v1"
+	storagev1alpha1 "k8s.io/api/storage/v1alpha1"
+	storagev1beta1 "k8s.io/api/storage/v1beta1"
+)
+
+// knownResources maps the object types of the informers this factory
+// creates to their resource, following ForResource.
+var knownResources = map[reflect.Type]knownResource{
+	reflect.TypeOf(&v1.MutatingWebhookConfiguration{}):                {gvr: v1.SchemeGroupVersion.WithResource("mutatingwebhookconfigurations"), namespaced: false},
+	reflect.TypeOf(&v1.ValidatingWebhookConfiguration{}):              {gvr: v1.SchemeGroupVersion.WithResource("validatingwebhookconfigurations"), namespaced: false},
+	reflect.TypeOf(&v1alpha1.ValidatingAdmissionPolicy{}):             {gvr: v1alpha1.SchemeGroupVersion.WithResource("validatingadmissionpolicies"), namespaced: false},
+	reflect.TypeOf(&v1alpha1.ValidatingAdmissionPolicyBinding{}):      {gvr: v1alpha1.SchemeGroupVersion.WithResource("validatingadmissionpolicybindings"), namespaced: false},
+	reflect.TypeOf(&v1beta1.MutatingWebhookConfiguration{}):           {gvr: v1beta1.SchemeGroupVersion.WithResource("mutatingwebhookconfigurations"), namespaced: false},
+	reflect.TypeOf(&v1beta1.ValidatingAdmissionPolicy{}):              {gvr: v1beta1.SchemeGroupVersion.WithResource("validatingadmissionpolicies"), namespaced: false},
+	reflect.TypeOf(&v1beta1.ValidatingAdmissionPolicyBinding{}):       {gvr: v1beta1.SchemeGroupVersion.WithResource("validatingadmissionpolicybindings"), namespaced: false},
+	reflect.TypeOf(&v1beta1.ValidatingWebhookConfiguration{}):         {gvr: v1beta1.SchemeGroupVersion.WithResource("validatingwebhookconfigurations"), namespaced: false},
+	reflect.TypeOf(&appsv1.ControllerRevision{}):                      {gvr: appsv1.SchemeGroupVersion.WithResource("controllerrevisions"), namespaced: true},
+	reflect.TypeOf(&appsv1.DaemonSet{}):                               {gvr: appsv1.SchemeGroupVersion.WithResource("daemonsets"), namespaced: true},
+	reflect.TypeOf(&appsv1.Deployment{}):                              {gvr: appsv1.SchemeGroupVersion.WithResource("deployments"), namespaced: true},
+	reflect.TypeOf(&appsv1.ReplicaSet{}):                              {gvr: appsv1.SchemeGroupVersion.WithResource("replicasets"), namespaced: true},
+	reflect.TypeOf(&appsv1.StatefulSet{}):                             {gvr: appsv1.SchemeGroupVersion.WithResource("statefulsets"), namespaced: true},
+	reflect.TypeOf(&appsv1beta1.ControllerRevision{}):                 {gvr: appsv1beta1.SchemeGroupVersion.WithResource("controllerrevisions"), namespaced: true},
+	reflect.TypeOf(&appsv1beta1.Deployment{}):                         {gvr: appsv1beta1.SchemeGroupVersion.WithResource("deployments"), namespaced: true},
+	reflect.TypeOf(&appsv1beta1.StatefulSet{}):                        {gvr: appsv1beta1.SchemeGroupVersion.WithResource("statefulsets"), namespaced: true},
+	reflect.TypeOf(&v1beta2.ControllerRevision{}):                     {gvr: v1beta2.SchemeGroupVersion.WithResource("controllerrevisions"), namespaced: true},
+	reflect.TypeOf(&v1beta2.DaemonSet{}):                              {gvr: v1beta2.SchemeGroupVersion.WithResource("daemonsets"), namespaced: true},
+	reflect.TypeOf(&v1beta2.Deployment{}):                             {gvr: v1beta2.SchemeGroupVersion.WithResource("deployments"), namespaced: true},
+	reflect.TypeOf(&v1beta2.ReplicaSet{}):                             {gvr: v1beta2.SchemeGroupVersion.WithResource("replicasets"), namespaced: true},
+	reflect.TypeOf(&v1beta2.StatefulSet{}):                            {gvr: v1beta2.SchemeGroupVersion.WithResource("statefulsets"), namespaced: true},
+	reflect.TypeOf(&autoscalingv1.HorizontalPodAutoscaler{}):          {gvr: autoscalingv1.SchemeGroupVersion.WithResource("horizontalpodautoscalers"), namespaced: true},
+	reflect.TypeOf(&v2.HorizontalPodAutoscaler{}):                     {gvr: v2.SchemeGroupVersion.WithResource("horizontalpodautoscalers"), namespaced: true},
+	reflect.TypeOf(&v2beta1.HorizontalPodAutoscaler{}):                {gvr: v2beta1.SchemeGroupVersion.WithResource("horizontalpodautoscalers"), namespaced: true},
+	reflect.TypeOf(&v2beta2.HorizontalPodAutoscaler{}):                {gvr: v2beta2.SchemeGroupVersion.WithResource("horizontalpodautoscalers"), namespaced: true},
+	reflect.TypeOf(&batchv1.CronJob{}):                                {gvr: batchv1.SchemeGroupVersion.WithResource("cronjobs"), namespaced: true},
+	reflect.TypeOf(&batchv1.Job{}):                                    {gvr: batchv1.SchemeGroupVersion.WithResource("jobs"), namespaced: true},
+	reflect.TypeOf(&batchv1beta1.CronJob{}):                           {gvr: batchv1beta1.SchemeGroupVersion.WithResource("cronjobs"), namespaced: true},
+	reflect.TypeOf(&certificatesv1.CertificateSigningRequest{}):       {gvr: certificatesv1.SchemeGroupVersion.WithResource("certificatesigningrequests"), namespaced: false},
+	reflect.TypeOf(&certificatesv1alpha1.ClusterTrustBundle{}):        {gvr: certificatesv1alpha1.SchemeGroupVersion.WithResource("clustertrustbundles"), namespaced: false},
+	reflect.TypeOf(&certificatesv1beta1.CertificateSigningRequest{}):  {gvr: certificatesv1beta1.SchemeGroupVersion.WithResource("certificatesigningrequests"), namespaced: false},
+	reflect.TypeOf(&coordinationv1.Lease{}):                           {gvr: coordinationv1.SchemeGroupVersion.WithResource("leases"), namespaced: true},
+	reflect.TypeOf(&coordinationv1beta1.Lease{}):                      {gvr: coordinationv1beta1.SchemeGroupVersion.WithResource("leases"), namespaced: true},
+	reflect.TypeOf(&corev1.ComponentStatus{}):                         {gvr: corev1.SchemeGroupVersion.WithResource("componentstatuses"), namespaced: false},
+	reflect.TypeOf(&corev1.ConfigMap{}):                               {gvr: corev1.SchemeGroupVersion.WithResource("configmaps"), namespaced: true},
+	reflect.TypeOf(&corev1.Endpoints{}):                               {gvr: corev1.SchemeGroupVersion.WithResource("endpoints"), namespaced: true},
+	reflect.TypeOf(&corev1.Event{}):                                   {gvr: corev1.SchemeGroupVersion.WithResource("events"), namespaced: true},
+	reflect.TypeOf(&corev1.LimitRange{}):                              {gvr: corev1.SchemeGroupVersion.WithResource("limitranges"), namespaced: true},
+	reflect.TypeOf(&corev1.Namespace{}):                               {gvr: corev1.SchemeGroupVersion.WithResource("namespaces"), namespaced: false},
+	reflect.TypeOf(&corev1.Node{}):                                    {gvr: corev1.SchemeGroupVersion.WithResource("nodes"), namespaced: false},
+	reflect.TypeOf(&corev1.PersistentVolume{}):                        {gvr: corev1.SchemeGroupVersion.WithResource("persistentvolumes"), namespaced: false},
+	reflect.TypeOf(&corev1.PersistentVolumeClaim{}):                   {gvr: corev1.SchemeGroupVersion.WithResource("persistentvolumeclaims"), namespaced: true},
+	reflect.TypeOf(&corev1.Pod{}):                                     {gvr: corev1.SchemeGroupVersion.WithResource("pods"), namespaced: true},
+	reflect.TypeOf(&corev1.PodTemplate{}):                             {gvr: corev1.SchemeGroupVersion.WithResource("podtemplates"), namespaced: true},
+	reflect.TypeOf(&corev1.ReplicationController{}):                   {gvr: corev1.SchemeGroupVersion.WithResource("replicationcontrollers"), namespaced: true},
+	reflect.TypeOf(&corev1.ResourceQuota{}):                           {gvr: corev1.SchemeGroupVersion.WithResource("resourcequotas"), namespaced: true},
+	reflect.TypeOf(&corev1.Secret{}):                                  {gvr: corev1.SchemeGroupVersion.WithResource("secrets"), namespaced: true},
+	reflect.TypeOf(&corev1.Service{}):                                 {gvr: corev1.SchemeGroupVersion.WithResource("services"), namespaced: true},
+	reflect.TypeOf(&corev1.ServiceAccount{}):                          {gvr: corev1.SchemeGroupVersion.WithResource("serviceaccounts"), namespaced: true},
+	reflect.TypeOf(&discoveryv1.EndpointSlice{}):                      {gvr: discoveryv1.SchemeGroupVersion.WithResource("endpointslices"), namespaced: true},
+	reflect.TypeOf(&discoveryv1beta1.EndpointSlice{}):                 {gvr: discoveryv1beta1.SchemeGroupVersion.WithResource("endpointslices"), namespaced: true},
+	reflect.TypeOf(&eventsv1.Event{}):                                 {gvr: eventsv1.SchemeGroupVersion.WithResource("events"), namespaced: true},
+	reflect.TypeOf(&eventsv1beta1.Event{}):                            {gvr: eventsv1beta1.SchemeGroupVersion.WithResource("events"), namespaced: true},
+	reflect.TypeOf(&extensionsv1beta1.DaemonSet{}):                    {gvr: extensionsv1beta1.SchemeGroupVersion.WithResource("daemonsets"), namespaced: true},
+	reflect.TypeOf(&extensionsv1beta1.Deployment{}):                   {gvr: extensionsv1beta1.SchemeGroupVersion.WithResource("deployments"), namespaced: true},
+	reflect.TypeOf(&extensionsv1beta1.Ingress{}):                      {gvr: extensionsv1beta1.SchemeGroupVersion.WithResource("ingresses"), namespaced: true},
+	reflect.TypeOf(&extensionsv1beta1.NetworkPolicy{}):                {gvr: extensionsv1beta1.SchemeGroupVersion.WithResource("networkpolicies"), namespaced: true},
+	reflect.TypeOf(&extensionsv1beta1.ReplicaSet{}):                   {gvr: extensionsv1beta1.SchemeGroupVersion.WithResource("replicasets"), namespaced: true},
+	reflect.TypeOf(&flowcontrolv1alpha1.FlowSchema{}):                 {gvr: flowcontrolv1alpha1.SchemeGroupVersion.WithResource("flowschemas"), namespaced: false},
+	reflect.TypeOf(&flowcontrolv1alpha1.PriorityLevelConfiguration{}): {gvr: flowcontrolv1alpha1.SchemeGroupVersion.WithResource("prioritylevelconfigurations"), namespaced: false},
+	reflect.TypeOf(&flowcontrolv1beta1.FlowSchema{}):                  {gvr: flowcontrolv1beta1.SchemeGroupVersion.WithResource("flowschemas"), namespaced: false},
+	reflect.TypeOf(&flowcontrolv1beta1.PriorityLevelConfiguration{}):  {gvr: flowcontrolv1beta1.SchemeGroupVersion.WithResource("prioritylevelconfigurations"), namespaced: false},
+	reflect.TypeOf(&flowcontrolv1beta2.FlowSchema{}):                  {gvr: flowcontrolv1beta2.SchemeGroupVersion.WithResource("flowschemas"), namespaced: false},
+	reflect.TypeOf(&flowcontrolv1beta2.PriorityLevelConfiguration{}):  {gvr: flowcontrolv1beta2.SchemeGroupVersion.WithResource("prioritylevelconfigurations"), namespaced: false},
+	reflect.TypeOf(&v1beta3.FlowSchema{}):                             {gvr: v1beta3.SchemeGroupVersion.WithResource("flowschemas"), namespaced: false},
+	reflect.TypeOf(&v1beta3.PriorityLevelConfiguration{}):             {gvr: v1beta3.SchemeGroupVersion.WithResource("prioritylevelconfigurations"), namespaced: false},
+	reflect.TypeOf(&apiserverinternalv1alpha1.StorageVersion{}):       {gvr: apiserverinternalv1alpha1.SchemeGroupVersion.WithResource("storageversions"), namespaced: false},
+	reflect.TypeOf(&networkingv1.Ingress{}):                           {gvr: networkingv1.SchemeGroupVersion.WithResource("ingresses"), namespaced: true},
+	reflect.TypeOf(&networkingv1.IngressClass{}):                      {gvr: networkingv1.SchemeGroupVersion.WithResource("ingressclasses"), namespaced: false},
+	reflect.TypeOf(&networkingv1.NetworkPolicy{}):                     {gvr: networkingv1.SchemeGroupVersion.WithResource("networkpolicies"), namespaced: true},
+	reflect.TypeOf(&networkingv1alpha1.ClusterCIDR{}):                 {gvr: networkingv1alpha1.SchemeGroupVersion.WithResource("clustercidrs"), namespaced: false},
+	reflect.TypeOf(&networkingv1alpha1.IPAddress{}):                   {gvr: networkingv1alpha1.SchemeGroupVersion.WithResource("ipaddresses"), namespaced: false},
+	reflect.TypeOf(&networkingv1beta1.Ingress{}):                      {gvr: networkingv1beta1.SchemeGroupVersion.WithResource("ingresses"), namespaced: true},
+	reflect.TypeOf(&networkingv1beta1.IngressClass{}):                 {gvr: networkingv1beta1.SchemeGroupVersion.WithResource("ingressclasses"), namespaced: false},
+	reflect.TypeOf(&nodev1.RuntimeClass{}):                            {gvr: nodev1.SchemeGroupVersion.WithResource("runtimeclasses"), namespaced: false},
+	reflect.TypeOf(&nodev1alpha1.RuntimeClass{}):                      {gvr: nodev1alpha1.SchemeGroupVersion.WithResource("runtimeclasses"), namespaced: false},
+	reflect.TypeOf(&nodev1beta1.RuntimeClass{}):                       {gvr: nodev1beta1.SchemeGroupVersion.WithResource("runtimeclasses"), namespaced: false},
+	reflect.TypeOf(&policyv1.PodDisruptionBudget{}):                   {gvr: policyv1.SchemeGroupVersion.WithResource("poddisruptionbudgets"), namespaced: true},
+	reflect.TypeOf(&policyv1beta1.PodDisruptionBudget{}):              {gvr: policyv1beta1.SchemeGroupVersion.WithResource("poddisruptionbudgets"), namespaced: true},
+	reflect.TypeOf(&rbacv1.ClusterRole{}):                             {gvr: rbacv1.SchemeGroupVersion.WithResource("clusterroles"), namespaced: false},
+	reflect.TypeOf(&rbacv1.ClusterRoleBinding{}):                      {gvr: rbacv1.SchemeGroupVersion.WithResource("clusterrolebindings"), namespaced: false},
+	reflect.TypeOf(&rbacv1.Role{}):                                    {gvr: rbacv1.SchemeGroupVersion.WithResource("roles"), namespaced: true},
+	reflect.TypeOf(&rbacv1.RoleBinding{}):                             {gvr: rbacv1.SchemeGroupVersion.WithResource("rolebindings"), namespaced: true},
+	reflect.TypeOf(&rbacv1alpha1.ClusterRole{}):                       {gvr: rbacv1alpha1.SchemeGroupVersion.WithResource("clusterroles"), namespaced: false},
+	reflect.TypeOf(&rbacv1alpha1.ClusterRoleBinding{}):                {gvr: rbacv1alpha1.SchemeGroupVersion.WithResource("clusterrolebindings"), namespaced: false},
+	reflect.TypeOf(&rbacv1alpha1.Role{}):                              {gvr: rbacv1alpha1.SchemeGroupVersion.WithResource("roles"), namespaced: true},
+	reflect.TypeOf(&rbacv1alpha1.RoleBinding{}):                       {gvr: rbacv1alpha1.SchemeGroupVersion.WithResource("rolebindings"), namespaced: true},
+	reflect.TypeOf(&rbacv1beta1.ClusterRole{}):                        {gvr: rbacv1beta1.SchemeGroupVersion.WithResource("clusterroles"), namespaced: false},
+	reflect.TypeOf(&rbacv1beta1.ClusterRoleBinding{}):                 {gvr: rbacv1beta1.SchemeGroupVersion.WithResource("clusterrolebindings"), namespaced: false},
+	reflect.TypeOf(&rbacv1beta1.Role{}):                               {gvr: rbacv1beta1.SchemeGroupVersion.WithResource("roles"), namespaced: true},
+	reflect.TypeOf(&rbacv1beta1.RoleBinding{}):                        {gvr: rbacv1beta1.SchemeGroupVersion.WithResource("rolebindings"), namespaced: true},
+	reflect.TypeOf(&v1alpha2.PodSchedulingContext{}):                  {gvr: v1alpha2.SchemeGroupVersion.WithResource("podschedulingcontexts"), namespaced: true},
+	reflect.TypeOf(&v1alpha2.ResourceClaim{}):                         {gvr: v1alpha2.SchemeGroupVersion.WithResource("resourceclaims"), namespaced: true},
+	reflect.TypeOf(&v1alpha2.ResourceClaimTemplate{}):                 {gvr: v1alpha2.SchemeGroupVersion.WithResource("resourceclaimtemplates"), namespaced: true},
+	reflect.TypeOf(&v1alpha2.ResourceClass{}):                         {gvr: v1alpha2.SchemeGroupVersion.WithResource("resourceclasses"), namespaced: false},
+	reflect.TypeOf(&schedulingv1.PriorityClass{}):                     {gvr: schedulingv1.SchemeGroupVersion.WithResource("priorityclasses"), namespaced: false},
+	reflect.TypeOf(&schedulingv1alpha1.PriorityClass{}):               {gvr: schedulingv1alpha1.SchemeGroupVersion.WithResource("priorityclasses"), namespaced: false},
+	reflect.TypeOf(&schedulingv1beta1.PriorityClass{}):                {gvr: schedulingv1beta1.SchemeGroupVersion.WithResource("priorityclasses"), namespaced: false},
+	reflect.TypeOf(&storagev1.CSIDriver{}):                            {gvr: storagev1.SchemeGroupVersion.WithResource("csidrivers"), namespaced: false},
+	reflect.TypeOf(&storagev1.CSINode{}):                              {gvr: storagev1.SchemeGroupVersion.WithResource("csinodes"), namespaced: false},
+	reflect.TypeOf(&storagev1.CSIStorageCapacity{}):                   {gvr: storagev1.SchemeGroupVersion.WithResource("csistoragecapacities"), namespaced: true},
+	reflect.TypeOf(&storagev1.StorageClass{}):                         {gvr: storagev1.SchemeGroupVersion.WithResource("storageclasses"), namespaced: false},
+	reflect.TypeOf(&storagev1.VolumeAttachment{}):                     {gvr: storagev1.SchemeGroupVersion.WithResource("volumeattachments"), namespaced: false},
+	reflect.TypeOf(&storagev1alpha1.CSIStorageCapacity{}):             {gvr: storagev1alpha1.SchemeGroupVersion.WithResource("csistoragecapacities"), namespaced: true},
+	reflect.TypeOf(&storagev1alpha1.VolumeAttachment{}):               {gvr: storagev1alpha1.SchemeGroupVersion.WithResource("volumeattachments"), namespaced: false},
+	reflect.TypeOf(&storagev1beta1.CSIDriver{}):                       {gvr: storagev1beta1.SchemeGroupVersion.WithResource("csidrivers"), namespaced: false},
+	reflect.TypeOf(&storagev1beta1.CSINode{}):                         {gvr: storagev1beta1.SchemeGroupVersion.WithResource("csinodes"), namespaced: false},
+	reflect.TypeOf(&storagev1beta1.CSIStorageCapacity{}):              {gvr: storagev1beta1.SchemeGroupVersion.WithResource("csistoragecapacities"), namespaced: true},
+	reflect.TypeOf(&storagev1beta1.StorageClass{}):                    {gvr: storagev1beta1.SchemeGroupVersion.WithResource("storageclasses"), namespaced: false},
+	reflect.TypeOf(&storagev1beta1.VolumeAttachment{}):                {gvr: storagev1beta1.SchemeGroupVersion.WithResource("volumeattachments"), namespaced: false},
+}
@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"k8s.io/klog/v2"
@@ -31,8 +32,10 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/cbor"
 )
 
 var deleteScheme = runtime.NewScheme()
@@ -40,11 +43,16 @@ var parameterScheme = runtime.NewScheme()
 var deleteOptionsCodec = serializer.NewCodecFactory(deleteScheme)
 var dynamicParameterCodec = runtime.NewParameterCodec(parameterScheme)
 
+// cborScheme types the objects decoded from CBOR responses.
+var cborScheme = runtime.NewScheme()
+
 var versionV1 = schema.GroupVersion{Version: "v1"}
 
 func init() {
 	metav1.AddToGroupVersion(parameterScheme, versionV1)
 	metav1.AddToGroupVersion(deleteScheme, versionV1)
+	metav1.AddToGroupVersion(cborScheme, versionV1)
+	utilruntime.Must(metav1.AddMetaToScheme(cborScheme))
 }
 
 // Client allows callers to retrieve the object metadata for any
@@ -55,17 +63,26 @@ func init() {
 // convert the metadata.
 type Client struct {
 	client *rest.RESTClient
+	// cbor is true if the client accepts CBOR responses.
+	cbor bool
 }
 
 var _ Interface = &Client{}
 
 // ConfigFor returns a copy of the provided config with the
-// appropriate metadata client defaults set.
+// appropriate metadata client defaults set. If the content type of the
+// provided config is CBOR, the client accepts CBOR responses, which servers
+// prefer to JSON for custom resources, as protobuf is not available for them.
 func ConfigFor(inConfig *rest.Config) *rest.Config {
 	config := rest.CopyConfig(inConfig)
 	config.AcceptContentTypes = "application/vnd.kubernetes.protobuf,application/json"
 	config.ContentType = "application/vnd.kubernetes.protobuf"
 	config.NegotiatedSerializer = metainternalversionscheme.Codecs.WithoutConversion()
+	if cbor.IsContentTypeCBOR(inConfig.ContentType) {
+		config.AcceptContentTypes = "application/vnd.kubernetes.protobuf," + cbor.ContentTypeCBOR + ",application/json"
+		config.ContentType = cbor.ContentTypeCBOR
+		config.NegotiatedSerializer = cbor.NegotiatedSerializer(config.NegotiatedSerializer, cborScheme, cborScheme)
+	}
 	if config.UserAgent == "" {
 		config.UserAgent = rest.DefaultKubernetesUserAgent()
 	}
@@ -111,7 +128,21 @@ func NewForConfigAndClient(inConfig *rest.Config, h *http.Client) (Interface, er
 		return nil, err
 	}
 
-	return &Client{client: restClient}, nil
+	return &Client{client: restClient, cbor: cbor.IsContentTypeCBOR(config.ContentType)}, nil
+}
+
+// accept returns the Accept header of requests for the given kind of the
+// meta.k8s.io/v1 group, with a fallback to the JSON encoding of the resource.
+func (c *Client) accept(kind string) string {
+	mediaTypes := []string{runtime.ContentTypeProtobuf, runtime.ContentTypeJSON}
+	if c.cbor {
+		mediaTypes = []string{runtime.ContentTypeProtobuf, cbor.ContentTypeCBOR, runtime.ContentTypeJSON}
+	}
+	accept := make([]string, 0, len(mediaTypes)+1)
+	for _, mediaType := range mediaTypes {
+		accept = append(accept, mediaType+";as="+kind+";g=meta.k8s.io;v=v1")
+	}
+	return strings.Join(append(accept, runtime.ContentTypeJSON), ",")
 }
 
 type client struct {
@@ -183,7 +214,7 @@ func (c *client) Get(ctx context.Context, name string, opts metav1.GetOptions, s
 		return nil, fmt.Errorf("name is required")
 	}
 	result := c.client.client.Get().AbsPath(append(c.makeURLSegments(name), subresources...)...).
-		SetHeader("Accept", "application/vnd.kubernetes.protobuf;as=PartialObjectMetadata;g=meta.k8s.io;v=v1,application/json;as=PartialObjectMetadata;g=meta.k8s.io;v=v1,application/json").
+		SetHeader("Accept", c.client.accept("PartialObjectMetadata")).
 		SpecificallyVersionedParams(&opts, dynamicParameterCodec, versionV1).
 		Do(ctx)
 	if err := result.Error(); err != nil {
@@ -219,7 +250,7 @@ func (c *client) Get(ctx context.Context, name string, opts metav1.GetOptions, s
 // List returns all resources within the specified scope (namespace or cluster).
 func (c *client) List(ctx context.Context, opts metav1.ListOptions) (*metav1.PartialObjectMetadataList, error) {
 	result := c.client.client.Get().AbsPath(c.makeURLSegments("")...).
-		SetHeader("Accept", "application/vnd.kubernetes.protobuf;as=PartialObjectMetadataList;g=meta.k8s.io;v=v1,application/json;as=PartialObjectMetadataList;g=meta.k8s.io;v=v1,application/json").
+		SetHeader("Accept", c.client.accept("PartialObjectMetadataList")).
 		SpecificallyVersionedParams(&opts, dynamicParameterCodec, versionV1).
 		Do(ctx)
 	if err := result.Error(); err != nil {
@@ -258,7 +289,7 @@ func (c *client) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Inte
 	opts.Watch = true
 	return c.client.client.Get().
 		AbsPath(c.makeURLSegments("")...).
-		SetHeader("Accept", "application/vnd.kubernetes.protobuf;as=PartialObjectMetadata;g=meta.k8s.io;v=v1,application/json;as=PartialObjectMetadata;g=meta.k8s.io;v=v1,application/json").
+		SetHeader("Accept", c.client.accept("PartialObjectMetadata")).
 		SpecificallyVersionedParams(&opts, dynamicParameterCodec, versionV1).
 		Timeout(timeout).
 		Watch(ctx)
@@ -273,7 +304,7 @@ func (c *client) Patch(ctx context.Context, name string, pt types.PatchType, dat
 		Patch(pt).
 		AbsPath(append(c.makeURLSegments(name), subresources...)...).
 		Body(data).
-		SetHeader("Accept", "application/vnd.kubernetes.protobuf;as=PartialObjectMetadata;g=meta.k8s.io;v=v1,application/json;as=PartialObjectMetadata;g=meta.k8s.io;v=v1,application/json").
+		SetHeader("Accept", c.client.accept("PartialObjectMetadata")).
 		SpecificallyVersionedParams(&opts, dynamicParameterCodec, versionV1).
 		Do(ctx)
 	if err := result.Error(); err != nil {
@@ -32,6 +32,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/cbor"
 )
 
 func TestClient(t *testing.T) {
@@ -302,3 +303,75 @@ func TestClient(t *testing.T) {
 		})
 	}
 }
+
+func TestClientCBOR(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "group", Version: "v1", Resource: "resource"}
+	serializer := cbor.NewSerializer(cborScheme, cborScheme)
+	item := metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "meta.k8s.io/v1", Kind: "PartialObjectMetadata"},
+		ObjectMeta: metav1.ObjectMeta{Name: "name", Namespace: "ns", UID: "123"},
+	}
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		kind := "PartialObjectMetadata"
+		if !strings.HasSuffix(req.URL.Path, "/name") && req.URL.Query().Get("watch") != "true" {
+			kind = "PartialObjectMetadataList"
+		}
+		accept := "application/vnd.kubernetes.protobuf;as=" + kind + ";g=meta.k8s.io;v=v1,application/cbor;as=" + kind + ";g=meta.k8s.io;v=v1,application/json;as=" + kind + ";g=meta.k8s.io;v=v1,application/json"
+		if a := req.Header.Get("Accept"); a != accept {
+			t.Errorf("expected Accept %q, got %q", accept, a)
+		}
+		w.Header().Set("Content-Type", cbor.ContentTypeCBOR)
+		var obj runtime.Object
+		switch kind {
+		case "PartialObjectMetadataList":
+			obj = &metav1.PartialObjectMetadataList{
+				TypeMeta: metav1.TypeMeta{APIVersion: "meta.k8s.io/v1", Kind: "PartialObjectMetadataList"},
+				Items:    []metav1.PartialObjectMetadata{item},
+			}
+		default:
+			obj = &item
+		}
+		if req.URL.Query().Get("watch") == "true" {
+			data, err := runtime.Encode(serializer, obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			obj = &metav1.WatchEvent{Type: "ADDED", Object: runtime.RawExtension{Raw: data}}
+		}
+		if err := serializer.Encode(obj, w); err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer s.Close()
+
+	client := NewForConfigOrDie(&rest.Config{Host: s.URL, ContentConfig: rest.ContentConfig{ContentType: cbor.ContentTypeCBOR}}).(*Client)
+	want := item
+	want.TypeMeta = metav1.TypeMeta{}
+
+	obj, err := client.Resource(gvr).Namespace("ns").Get(context.TODO(), "name", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&want, obj) {
+		t.Fatal(cmp.Diff(&want, obj))
+	}
+
+	list, err := client.Resource(gvr).Namespace("ns").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual([]metav1.PartialObjectMetadata{item}, list.Items) {
+		t.Fatal(cmp.Diff([]metav1.PartialObjectMetadata{item}, list.Items))
+	}
+
+	w, err := client.Resource(gvr).Namespace("ns").Watch(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+	event := <-w.ResultChan()
+	if !reflect.DeepEqual(&want, event.Object) {
+		t.Fatal(cmp.Diff(&want, event.Object))
+	}
+}
@@ -22,11 +22,13 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/cbor"
 	"k8s.io/client-go/util/flowcontrol"
 )
 
@@ -61,6 +63,10 @@ type ClientContentConfig struct {
 	// This value will be set as the Accept header on requests made to the server if
 	// AcceptContentTypes is not set, and as the default content type on any object
 	// sent to the server. If not set, "application/json" is used.
+	//
+	// If it is "application/cbor" and the server rejects a CBOR request body as an
+	// unsupported media type, the client falls back to "application/json" for that
+	// request and the following ones, and drops CBOR from AcceptContentTypes.
 	ContentType string
 	// GroupVersion is the API version to talk to. Must be provided when initializing
 	// a RESTClient directly. When initializing a Client, will be set with the default
@@ -87,6 +93,10 @@ type RESTClient struct {
 	// content describes how a RESTClient encodes and decodes responses.
 	content ClientContentConfig
 
+	// cborUnsupported is set to 1 once the server rejected a CBOR request body
+	// as an unsupported media type, after which JSON is used in place of CBOR.
+	cborUnsupported int32
+
 	// creates BackoffManager that is passed to requests.
 	createBackoffMgr func() BackoffManager
 
@@ -98,6 +108,12 @@ type RESTClient struct {
 	// If not set, defaultWarningHandler is used.
 	warningHandler WarningHandler
 
+	// tracer traces the requests created by this client, if set.
+	tracer Tracer
+
+	// interceptors intercept the execution of the requests created by this client.
+	interceptors []RequestInterceptor
+
 	// Set specific behavior of the client.  If not set http.DefaultClient will be used.
 	Client *http.Client
 }
@@ -127,6 +143,34 @@ func NewRESTClient(baseURL *url.URL, versionedAPIPath string, config ClientConte
 	}, nil
 }
 
+// contentConfig returns the content config of the requests of the client.
+func (c *RESTClient) contentConfig() ClientContentConfig {
+	if atomic.LoadInt32(&c.cborUnsupported) == 0 {
+		return c.content
+	}
+	return withoutCBOR(c.content)
+}
+
+// withoutCBOR returns config with JSON in place of CBOR.
+func withoutCBOR(config ClientContentConfig) ClientContentConfig {
+	if cbor.IsContentTypeCBOR(config.ContentType) {
+		config.ContentType = runtime.ContentTypeJSON
+	}
+	if len(config.AcceptContentTypes) > 0 {
+		var acceptContentTypes []string
+		for _, contentType := range strings.Split(config.AcceptContentTypes, ",") {
+			if !cbor.IsContentTypeCBOR(contentType) {
+				acceptContentTypes = append(acceptContentTypes, contentType)
+			}
+		}
+		if len(acceptContentTypes) == 0 {
+			acceptContentTypes = []string{runtime.ContentTypeJSON}
+		}
+		config.AcceptContentTypes = strings.Join(acceptContentTypes, ",")
+	}
+	return config
+}
+
 // GetRateLimiter returns rate limiter for a given client, or nil if it's called on a nil client
 func (c *RESTClient) GetRateLimiter() flowcontrol.RateLimiter {
 	if c == nil {
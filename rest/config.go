@@ -55,6 +55,16 @@ type Config struct {
 	// be appended to all request URIs used to access the apiserver. This allows a frontend
 	// proxy to easily relocate all of the apiserver endpoints.
 	Host string
+	// Endpoints optionally lists further hosts of the apiserver, in the same form
+	// as Host, e.g. the other apiservers of a control plane without a load balancer
+	// in front of them. If set, requests are spread across Host and Endpoints as
+	// chosen by EndpointSelection, and fail over from unavailable endpoints to the
+	// others. Endpoints only apply to the transports of TransportFor and
+	// HTTPClientFor, streaming connections always use Host.
+	Endpoints []string
+	// EndpointSelection chooses which of Host and Endpoints serves a request. It
+	// defaults to EndpointSelectionRoundRobin.
+	EndpointSelection EndpointSelection
 	// APIPath is a sub-path that points to an API root.
 	APIPath string
 
@@ -140,6 +150,24 @@ type Config struct {
 	// socks5 proxying does not currently support spdy streaming endpoints.
 	Proxy func(*http.Request) (*url.URL, error)
 
+	// Tracer optionally traces the requests made by clients created from this
+	// config, see Tracer. Use k8s.io/client-go/rest/tracing.NewTracer to trace
+	// them with OpenTelemetry.
+	Tracer Tracer
+
+	// ResponseCache optionally caches the responses to GET requests per user
+	// identity, e.g. transport.NewMemoryResponseCache() or
+	// transport.NewDiskResponseCache(dir). Only responses with an ETag or
+	// Last-Modified header are cached; they are revalidated with conditional
+	// requests, and served from the cache if they were not modified. Lists
+	// and watches are never cached.
+	ResponseCache transport.ResponseCache
+
+	// Interceptors optionally intercept the execution of every request made by
+	// clients created from this config, the first one being the outermost.
+	// See RequestInterceptor.
+	Interceptors []RequestInterceptor
+
 	// Version forces a specific version to be used (if registered)
 	// Do we need this?
 	// Version string
@@ -378,6 +406,10 @@ func RESTClientForConfigAndClient(config *Config, httpClient *http.Client) (*RES
 	if err == nil && config.WarningHandler != nil {
 		restClient.warningHandler = config.WarningHandler
 	}
+	if err == nil {
+		restClient.tracer = config.Tracer
+		restClient.interceptors = config.Interceptors
+	}
 	return restClient, err
 }
 
@@ -445,6 +477,10 @@ func UnversionedRESTClientForConfigAndClient(config *Config, httpClient *http.Cl
 	if err == nil && config.WarningHandler != nil {
 		restClient.warningHandler = config.WarningHandler
 	}
+	if err == nil {
+		restClient.tracer = config.Tracer
+		restClient.interceptors = config.Interceptors
+	}
 	return restClient, err
 }
 
@@ -596,13 +632,15 @@ func AddUserAgent(config *Config, userAgent string) *Config {
 	return config
 }
 
-// AnonymousClientConfig returns a copy of the given config with all user credentials (cert/key, bearer token, and username/password) and custom transports (WrapTransport, Transport) removed
+// AnonymousClientConfig returns a copy of the given config with all user credentials (cert/key, bearer token, and username/password), custom transports (WrapTransport, Transport) and request interceptors removed
 func AnonymousClientConfig(config *Config) *Config {
 	// copy only known safe fields
 	return &Config{
-		Host:          config.Host,
-		APIPath:       config.APIPath,
-		ContentConfig: config.ContentConfig,
+		Host:              config.Host,
+		Endpoints:         config.Endpoints,
+		EndpointSelection: config.EndpointSelection,
+		APIPath:           config.APIPath,
+		ContentConfig:     config.ContentConfig,
 		TLSClientConfig: TLSClientConfig{
 			Insecure:   config.Insecure,
 			ServerName: config.ServerName,
@@ -619,19 +657,22 @@ func AnonymousClientConfig(config *Config) *Config {
 		Timeout:            config.Timeout,
 		Dial:               config.Dial,
 		Proxy:              config.Proxy,
+		Tracer:             config.Tracer,
 	}
 }
 
 // CopyConfig returns a copy of the given config
 func CopyConfig(config *Config) *Config {
 	c := &Config{
-		Host:            config.Host,
-		APIPath:         config.APIPath,
-		ContentConfig:   config.ContentConfig,
-		Username:        config.Username,
-		Password:        config.Password,
-		BearerToken:     config.BearerToken,
-		BearerTokenFile: config.BearerTokenFile,
+		Host:              config.Host,
+		Endpoints:         config.Endpoints,
+		EndpointSelection: config.EndpointSelection,
+		APIPath:           config.APIPath,
+		ContentConfig:     config.ContentConfig,
+		Username:          config.Username,
+		Password:          config.Password,
+		BearerToken:       config.BearerToken,
+		BearerTokenFile:   config.BearerTokenFile,
 		Impersonate: ImpersonationConfig{
 			UserName: config.Impersonate.UserName,
 			UID:      config.Impersonate.UID,
@@ -663,6 +704,9 @@ func CopyConfig(config *Config) *Config {
 		Timeout:            config.Timeout,
 		Dial:               config.Dial,
 		Proxy:              config.Proxy,
+		Tracer:             config.Tracer,
+		ResponseCache:      config.ResponseCache,
+		Interceptors:       config.Interceptors,
 	}
 	if config.ExecProvider != nil && config.ExecProvider.Config != nil {
 		c.ExecProvider.Config = config.ExecProvider.Config.DeepCopyObject()
@@ -0,0 +1,362 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+)
+
+// EndpointSelection is the policy by which clients choose which of the hosts
+// of the apiserver, Config.Host and Config.Endpoints, serves a request.
+type EndpointSelection string
+
+const (
+	// EndpointSelectionRoundRobin spreads the requests across the healthy
+	// endpoints in turn.
+	EndpointSelectionRoundRobin EndpointSelection = "RoundRobin"
+	// EndpointSelectionFirstHealthy sends the requests to the first healthy
+	// endpoint, in the order of Config.Host and Config.Endpoints, i.e. it only
+	// fails over from Config.Host while Config.Host is unhealthy.
+	EndpointSelectionFirstHealthy EndpointSelection = "FirstHealthy"
+)
+
+const (
+	// endpointFailureThreshold is the number of consecutive 5xx responses of
+	// an endpoint after which it is ejected. Connection errors eject an
+	// endpoint right away.
+	endpointFailureThreshold = 3
+	// endpointMinEjection and endpointMaxEjection bound the time an ejected
+	// endpoint is not used, which doubles with every consecutive ejection.
+	endpointMinEjection = 1 * time.Second
+	endpointMaxEjection = 1 * time.Minute
+	// endpointProbeTimeout is the timeout of the readiness probes of ejected
+	// endpoints.
+	endpointProbeTimeout = 5 * time.Second
+)
+
+// endpoint is a host of the apiserver. Its fields other than url are guarded
+// by the lock of its endpointRoundTripper.
+type endpoint struct {
+	url *url.URL
+	// failures is the number of consecutive 5xx responses of the endpoint.
+	failures int
+	// ejections is the number of consecutive ejections of the endpoint.
+	ejections int
+	// ejectedUntil is the time until which the endpoint is not used, and is
+	// zero if the endpoint is healthy. Once it has passed, the endpoint is
+	// probed and used again if it is ready.
+	ejectedUntil time.Time
+	// probing is true while the endpoint is probed.
+	probing bool
+}
+
+// endpointRoundTripper sends the requests for the host of a Config to one of
+// its endpoints, as chosen by the EndpointSelection of the Config, and fails
+// over to other endpoints.
+//
+// Endpoints are ejected passively, i.e. on connection errors, broken watch
+// streams and consecutive 5xx responses, and are not used until they are
+// ready again according to their /readyz endpoint, which is probed once their
+// ejection expires. Requests fail over to another endpoint on connection errors
+// if they can be sent again, i.e. if they failed to connect or are idempotent.
+// Clients reconnect their broken watches by sending a new request, which is
+// sent to a healthy endpoint.
+type endpointRoundTripper struct {
+	delegate  http.RoundTripper
+	selection EndpointSelection
+	clock     clock.PassiveClock
+
+	lock sync.Mutex
+	// endpoints holds the endpoints in the order of Config.Host and
+	// Config.Endpoints.
+	endpoints []*endpoint
+	// next is the index of the endpoint of the next request, for round-robin
+	// selection.
+	next int
+}
+
+// newEndpointRoundTripper returns a round tripper which spreads the requests
+// of config across its Host and Endpoints by means of rt.
+func newEndpointRoundTripper(config *Config, rt http.RoundTripper) (*endpointRoundTripper, error) {
+	switch config.EndpointSelection {
+	case "", EndpointSelectionRoundRobin, EndpointSelectionFirstHealthy:
+	default:
+		return nil, fmt.Errorf("unknown endpoint selection %q", config.EndpointSelection)
+	}
+	hosts := append([]string{config.Host}, config.Endpoints...)
+	if config.Host == "" {
+		hosts[0] = "localhost"
+	}
+	endpoints := make([]*endpoint, 0, len(hosts))
+	for _, host := range hosts {
+		hostURL, _, err := DefaultServerURL(host, "", schema.GroupVersion{}, defaultTLS(config))
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, &endpoint{url: hostURL})
+	}
+	return &endpointRoundTripper{
+		delegate:  rt,
+		selection: config.EndpointSelection,
+		clock:     clock.RealClock{},
+		endpoints: endpoints,
+	}, nil
+}
+
+func (rt *endpointRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := rt.endpoints[0].url
+	if req.URL.Scheme != host.Scheme || req.URL.Host != host.Host {
+		// Not a request for the apiserver, e.g. for a redirect.
+		return rt.delegate.RoundTrip(req)
+	}
+
+	var lastErr error
+	tried := make(map[*endpoint]bool, len(rt.endpoints))
+	for {
+		e := rt.choose(tried)
+		if e == nil {
+			return nil, lastErr
+		}
+		tried[e] = true
+
+		resp, err := rt.delegate.RoundTrip(rt.requestFor(req, e))
+		if err != nil {
+			if req.Context().Err() != nil {
+				return nil, err
+			}
+			rt.eject(e)
+			if !canSendAgain(req, err) {
+				return nil, err
+			}
+			lastErr = err
+			if req.GetBody != nil {
+				body, bodyErr := req.GetBody()
+				if bodyErr != nil {
+					return nil, err
+				}
+				req = req.Clone(req.Context())
+				req.Body = body
+			}
+			klog.V(4).Infof("Failing over request %s %s from apiserver endpoint %s: %v", req.Method, req.URL, e.url, err)
+			continue
+		}
+
+		if resp.StatusCode >= http.StatusInternalServerError && resp.StatusCode != http.StatusNotImplemented {
+			rt.fail(e)
+		} else {
+			rt.succeed(e)
+		}
+		if req.URL.Query().Get("watch") == "true" && resp.StatusCode == http.StatusOK {
+			resp.Body = &endpointBody{ReadCloser: resp.Body, ctx: req.Context(), rt: rt, endpoint: e}
+		}
+		return resp, nil
+	}
+}
+
+// WrappedRoundTripper returns the round tripper of the endpoints.
+func (rt *endpointRoundTripper) WrappedRoundTripper() http.RoundTripper {
+	return rt.delegate
+}
+
+// requestFor returns req sent to e.
+func (rt *endpointRoundTripper) requestFor(req *http.Request, e *endpoint) *http.Request {
+	host := rt.endpoints[0].url
+	if e.url == host {
+		return req
+	}
+	r := req.Clone(req.Context())
+	r.Host = ""
+	r.URL.Scheme = e.url.Scheme
+	r.URL.Host = e.url.Host
+	if prefix, endpointPrefix := strings.TrimSuffix(host.Path, "/"), strings.TrimSuffix(e.url.Path, "/"); prefix != endpointPrefix && strings.HasPrefix(r.URL.Path, prefix) {
+		r.URL.Path = endpointPrefix + strings.TrimPrefix(r.URL.Path, prefix)
+		r.URL.RawPath = ""
+	}
+	return r
+}
+
+// canSendAgain returns whether req, which failed with err, can be sent to
+// another endpoint.
+func canSendAgain(req *http.Request, err error) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// choose returns the endpoint to send a request to, out of the ones not
+// tried yet, or nil if all of them were tried. If all of them are ejected, it
+// returns the one whose ejection expires first. It also starts probing the
+// endpoints whose ejection expired.
+func (rt *endpointRoundTripper) choose(tried map[*endpoint]bool) *endpoint {
+	rt.lock.Lock()
+	defer rt.lock.Unlock()
+
+	now := rt.clock.Now()
+	for _, e := range rt.endpoints {
+		if !e.ejectedUntil.IsZero() && !now.Before(e.ejectedUntil) && !e.probing {
+			e.probing = true
+			go rt.probe(e)
+		}
+	}
+
+	start := 0
+	if rt.selection != EndpointSelectionFirstHealthy {
+		start = rt.next
+		rt.next = (rt.next + 1) % len(rt.endpoints)
+	}
+	var ejected *endpoint
+	for i := range rt.endpoints {
+		e := rt.endpoints[(start+i)%len(rt.endpoints)]
+		if tried[e] {
+			continue
+		}
+		if e.ejectedUntil.IsZero() {
+			return e
+		}
+		if ejected == nil || e.ejectedUntil.Before(ejected.ejectedUntil) {
+			ejected = e
+		}
+	}
+	return ejected
+}
+
+// probe checks whether the ejected endpoint e is ready, and uses it again if
+// it is or ejects it again if it is not.
+func (rt *endpointRoundTripper) probe(e *endpoint) {
+	ready := false
+	ctx, cancel := context.WithTimeout(context.Background(), endpointProbeTimeout)
+	defer cancel()
+	probeURL := *e.url
+	probeURL.Path = strings.TrimSuffix(probeURL.Path, "/") + "/readyz"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, probeURL.String(), nil)
+	if err == nil {
+		var resp *http.Response
+		resp, err = rt.delegate.RoundTrip(req)
+		if err == nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			ready = resp.StatusCode == http.StatusOK
+			if !ready {
+				err = fmt.Errorf("unexpected status %d", resp.StatusCode)
+			}
+		}
+	}
+
+	rt.lock.Lock()
+	defer rt.lock.Unlock()
+	e.probing = false
+	if !ready {
+		klog.V(4).Infof("Apiserver endpoint %s is not ready: %v", e.url, err)
+		rt.ejectLocked(e)
+		return
+	}
+	klog.V(2).Infof("Apiserver endpoint %s is ready again", e.url)
+	e.ejectedUntil = time.Time{}
+}
+
+// fail records a 5xx response of e, and ejects e once they reach
+// endpointFailureThreshold.
+func (rt *endpointRoundTripper) fail(e *endpoint) {
+	rt.lock.Lock()
+	defer rt.lock.Unlock()
+	e.failures++
+	if e.failures >= endpointFailureThreshold {
+		rt.ejectLocked(e)
+	}
+}
+
+// succeed records a successful response of e, which uses e again if it was
+// ejected.
+func (rt *endpointRoundTripper) succeed(e *endpoint) {
+	rt.lock.Lock()
+	defer rt.lock.Unlock()
+	e.failures = 0
+	e.ejections = 0
+	e.ejectedUntil = time.Time{}
+}
+
+// eject stops using e until its ejection expires and it is ready again.
+func (rt *endpointRoundTripper) eject(e *endpoint) {
+	rt.lock.Lock()
+	defer rt.lock.Unlock()
+	rt.ejectLocked(e)
+}
+
+func (rt *endpointRoundTripper) ejectLocked(e *endpoint) {
+	now := rt.clock.Now()
+	if now.Before(e.ejectedUntil) {
+		// Already ejected, e.g. by another request.
+		return
+	}
+	ejection := endpointMaxEjection
+	if e.ejections < 6 {
+		ejection = endpointMinEjection << e.ejections
+	}
+	if ejection > endpointMaxEjection {
+		ejection = endpointMaxEjection
+	}
+	e.ejections++
+	e.failures = 0
+	e.ejectedUntil = now.Add(ejection)
+	klog.V(2).Infof("Ejecting apiserver endpoint %s for %v", e.url, ejection)
+}
+
+// endpointBody is the body of a watch response, which ejects its endpoint if
+// the watch stream breaks.
+type endpointBody struct {
+	io.ReadCloser
+	ctx      context.Context
+	rt       *endpointRoundTripper
+	endpoint *endpoint
+	closed   int32
+}
+
+func (b *endpointBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF && b.ctx.Err() == nil && atomic.LoadInt32(&b.closed) == 0 {
+		b.rt.eject(b.endpoint)
+	}
+	return n, err
+}
+
+func (b *endpointBody) Close() error {
+	atomic.StoreInt32(&b.closed, 1)
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,238 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+// endpointServer is an apiserver endpoint which records the paths of the
+// requests it serves.
+type endpointServer struct {
+	*httptest.Server
+
+	lock   sync.Mutex
+	paths  []string
+	status int
+}
+
+func newEndpointServer(t *testing.T) *endpointServer {
+	s := &endpointServer{status: http.StatusOK}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.paths = append(s.paths, r.URL.Path)
+		if r.URL.Path == "/readyz" {
+			w.WriteHeader(s.status)
+			return
+		}
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(s.status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *endpointServer) setStatus(status int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.status = status
+}
+
+func (s *endpointServer) served() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	paths := s.paths
+	s.paths = nil
+	return paths
+}
+
+func newEndpointTestRoundTripper(t *testing.T, config *Config) (*endpointRoundTripper, *testingclock.FakeClock) {
+	rt, err := newEndpointRoundTripper(config, http.DefaultTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	rt.clock = fakeClock
+	return rt, fakeClock
+}
+
+func sendEndpointRequests(t *testing.T, rt http.RoundTripper, method, url string, n int) {
+	for i := 0; i < n; i++ {
+		req, err := http.NewRequest(method, url, strings.NewReader("body"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+}
+
+func TestEndpointFailover(t *testing.T) {
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+	live := newEndpointServer(t)
+
+	rt, err := TransportFor(&Config{Host: dead.URL, Endpoints: []string{live.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Requests fail over to the live endpoint, which serves all of them once
+	// the dead one is ejected.
+	sendEndpointRequests(t, rt, http.MethodGet, dead.URL+"/api", 2)
+	sendEndpointRequests(t, rt, http.MethodPost, dead.URL+"/api/v1/namespaces", 2)
+	expected := []string{"/api", "/api", "/api/v1/namespaces", "/api/v1/namespaces"}
+	if served := live.served(); !equalStrings(expected, served) {
+		t.Errorf("expected %v to be served, got %v", expected, served)
+	}
+}
+
+func TestEndpointEjection(t *testing.T) {
+	a, b := newEndpointServer(t), newEndpointServer(t)
+	rt, fakeClock := newEndpointTestRoundTripper(t, &Config{Host: a.URL, Endpoints: []string{b.URL}})
+
+	sendEndpointRequests(t, rt, http.MethodGet, a.URL+"/api", 4)
+	if e, a := 2, len(a.served()); e != a {
+		t.Errorf("expected %d requests to be served by the first endpoint, got %d", e, a)
+	}
+	if e, a := 2, len(b.served()); e != a {
+		t.Errorf("expected %d requests to be served by the second endpoint, got %d", e, a)
+	}
+
+	// The first endpoint is ejected after endpointFailureThreshold 5xx
+	// responses.
+	a.setStatus(http.StatusServiceUnavailable)
+	sendEndpointRequests(t, rt, http.MethodGet, a.URL+"/api", 2*endpointFailureThreshold+4)
+	if e, a := endpointFailureThreshold, len(a.served()); e != a {
+		t.Errorf("expected %d requests to be served by the first endpoint, got %d", e, a)
+	}
+	b.served()
+
+	// Once its ejection expires, it is probed and not used until it is ready.
+	fakeClock.Step(endpointMinEjection)
+	sendEndpointRequests(t, rt, http.MethodGet, a.URL+"/api", 1)
+	waitForEjection(t, rt, rt.endpoints[0], true)
+	if e, a := []string{"/readyz"}, a.served(); !equalStrings(e, a) {
+		t.Errorf("expected %v to be served by the first endpoint, got %v", e, a)
+	}
+
+	a.setStatus(http.StatusOK)
+	fakeClock.Step(2 * endpointMinEjection)
+	sendEndpointRequests(t, rt, http.MethodGet, a.URL+"/api", 1)
+	waitForEjection(t, rt, rt.endpoints[0], false)
+	if e, a := []string{"/readyz"}, a.served(); !equalStrings(e, a) {
+		t.Errorf("expected %v to be served by the first endpoint, got %v", e, a)
+	}
+	sendEndpointRequests(t, rt, http.MethodGet, a.URL+"/api", 4)
+	if e, a := 2, len(a.served()); e != a {
+		t.Errorf("expected %d requests to be served by the first endpoint, got %d", e, a)
+	}
+}
+
+func waitForEjection(t *testing.T, rt *endpointRoundTripper, e *endpoint, ejected bool) {
+	err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		rt.lock.Lock()
+		defer rt.lock.Unlock()
+		return !e.probing && e.ejectedUntil.IsZero() != ejected, nil
+	})
+	if err != nil {
+		t.Fatalf("endpoint %s was not probed: %v", e.url, err)
+	}
+}
+
+func TestEndpointSelectionFirstHealthy(t *testing.T) {
+	a, b := newEndpointServer(t), newEndpointServer(t)
+	rt, _ := newEndpointTestRoundTripper(t, &Config{
+		Host:              a.URL + "/a",
+		Endpoints:         []string{b.URL + "/b/"},
+		EndpointSelection: EndpointSelectionFirstHealthy,
+	})
+
+	sendEndpointRequests(t, rt, http.MethodGet, a.URL+"/a/api", 2)
+	if e, a := []string{"/a/api", "/a/api"}, a.served(); !equalStrings(e, a) {
+		t.Errorf("expected %v to be served by the first endpoint, got %v", e, a)
+	}
+
+	rt.eject(rt.endpoints[0])
+	sendEndpointRequests(t, rt, http.MethodGet, a.URL+"/a/api", 2)
+	if e, a := []string{"/b/api", "/b/api"}, b.served(); !equalStrings(e, a) {
+		t.Errorf("expected %v to be served by the second endpoint, got %v", e, a)
+	}
+}
+
+func TestEndpointWatchBroken(t *testing.T) {
+	a := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("event"))
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+	}))
+	defer a.Close()
+	b := newEndpointServer(t)
+	rt, _ := newEndpointTestRoundTripper(t, &Config{
+		Host:              a.URL,
+		Endpoints:         []string{b.URL},
+		EndpointSelection: EndpointSelectionFirstHealthy,
+	})
+
+	req, err := http.NewRequest(http.MethodGet, a.URL+"/api/v1/pods?watch=true", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(resp.Body); err == nil {
+		t.Fatal("expected the watch to break")
+	}
+	resp.Body.Close()
+
+	// The watch is restarted on the second endpoint.
+	sendEndpointRequests(t, rt, http.MethodGet, a.URL+"/api/v1/pods?watch=true", 1)
+	if e, a := []string{"/api/v1/pods"}, b.served(); !equalStrings(e, a) {
+		t.Errorf("expected %v to be served by the second endpoint, got %v", e, a)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -242,6 +242,15 @@ func TestConfigToExecClusterRoundtrip(t *testing.T) {
 		func(h *WarningHandler, f fuzz.Continue) {
 			*h = &fakeWarningHandler{}
 		},
+		func(tr *Tracer, f fuzz.Continue) {
+			*tr = fakeTracer{}
+		},
+		func(c *transport.ResponseCache, f fuzz.Continue) {
+			*c = fakeResponseCache{}
+		},
+		func(fn *RequestInterceptor, f fuzz.Continue) {
+			*fn = fakeInterceptor
+		},
 		// Authentication does not require fuzzer
 		func(r *AuthProviderConfigPersister, f fuzz.Continue) {},
 		func(r *clientcmdapi.AuthProviderConfig, f fuzz.Continue) {
@@ -265,6 +274,8 @@ func TestConfigToExecClusterRoundtrip(t *testing.T) {
 
 		// This is the list of known fields that this roundtrip doesn't care about. We should add new
 		// fields to this list if we don't want to roundtrip them on exec cluster conversion.
+		expected.Endpoints = nil
+		expected.EndpointSelection = ""
 		expected.APIPath = ""
 		expected.ContentConfig = ContentConfig{}
 		expected.Username = ""
@@ -290,6 +301,9 @@ func TestConfigToExecClusterRoundtrip(t *testing.T) {
 		expected.RateLimiter = nil
 		expected.WarningHandler = nil
 		expected.Timeout = 0
+		expected.Tracer = nil
+		expected.ResponseCache = nil
+		expected.Interceptors = nil
 		expected.Dial = nil
 
 		// Manually set URLs so we don't get an error when parsing these during the roundtrip.
@@ -0,0 +1,111 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// RequestInfo describes a Request being executed to the RequestInterceptors.
+type RequestInfo struct {
+	// Operation is the method executing the request: "Do", "DoRaw", "Watch",
+	// "Stream" or "EachListItem".
+	Operation string
+
+	Verb        string
+	Namespace   string
+	Resource    string
+	Name        string
+	Subresource string
+
+	// URL is the URL of the request.
+	URL *url.URL
+
+	// Header holds the headers of the request. Interceptors may change them
+	// before calling the next interceptor.
+	Header http.Header
+
+	// Object is the object the body of the request was encoded from, if any.
+	// It must not be modified.
+	Object runtime.Object
+}
+
+// RequestInvoker executes a request, see RequestInterceptor.
+type RequestInvoker func(ctx context.Context, info *RequestInfo) Result
+
+// RequestInterceptor intercepts the execution of a Request. It is called with
+// the request about to be executed and next, which calls the next interceptor
+// of the chain or, at the end of the chain, executes the request. It can:
+//   - change info.Header or ctx before calling next,
+//   - inspect or replace the Result returned by next, e.g. to observe the
+//     decoded errors of the server by means of Result.Error,
+//   - short-circuit the request by returning a Result without calling next,
+//     e.g. the Result of an earlier execution or one built by ErrorResult.
+//
+// For Watch, Stream and EachListItem, whose responses are streamed, the Result
+// only carries the error of the call, and an interceptor short-circuiting them
+// must return an error.
+type RequestInterceptor func(ctx context.Context, info *RequestInfo, next RequestInvoker) Result
+
+// ErrorResult returns the Result of a request which failed with err.
+func ErrorResult(err error) Result {
+	return Result{err: err}
+}
+
+var errShortCircuitedWithoutError = errors.New("a request interceptor short-circuited a streaming request without an error")
+
+// intercepted tells whether r is executed through interceptors. A request
+// which could not be built is not.
+func (r *Request) intercepted() bool {
+	return len(r.interceptors) > 0 && r.err == nil
+}
+
+// intercept executes r with invoke through the interceptors of r, the first
+// interceptor being the outermost one.
+func (r *Request) intercept(ctx context.Context, operation string, invoke func(ctx context.Context) Result) Result {
+	if r.headers == nil {
+		r.headers = http.Header{}
+	}
+	info := &RequestInfo{
+		Operation:   operation,
+		Verb:        r.verb,
+		Namespace:   r.namespace,
+		Resource:    r.resource,
+		Name:        r.resourceName,
+		Subresource: r.subresource,
+		URL:         r.URL(),
+		Header:      r.headers,
+		Object:      r.object,
+	}
+
+	next := func(ctx context.Context, info *RequestInfo) Result {
+		r.headers = info.Header
+		return invoke(ctx)
+	}
+	for i := len(r.interceptors) - 1; i >= 0; i-- {
+		interceptor, inner := r.interceptors[i], next
+		next = func(ctx context.Context, info *RequestInfo) Result {
+			return interceptor(ctx, info, inner)
+		}
+	}
+	return next(ctx, info)
+}
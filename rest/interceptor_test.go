@@ -0,0 +1,175 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+func TestRequestInterceptors(t *testing.T) {
+	var audit []string
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		audit = append(audit, "server "+req.Header.Get("X-Audit-ID"))
+		status := &metav1.Status{Status: metav1.StatusFailure, Reason: metav1.StatusReasonNotFound, Code: http.StatusNotFound}
+		w.Header().Set("Content-Type", runtime.ContentTypeJSON)
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(runtime.EncodeOrDie(scheme.Codecs.LegacyCodec(v1.SchemeGroupVersion), status)))
+	}))
+	defer testServer.Close()
+
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}
+	c := testRESTClient(t, testServer)
+	c.interceptors = []RequestInterceptor{
+		func(ctx context.Context, info *RequestInfo, next RequestInvoker) Result {
+			expected := RequestInfo{
+				Operation:   "Do",
+				Verb:        "PUT",
+				Namespace:   "ns",
+				Resource:    "pods",
+				Name:        "foo",
+				Subresource: "status",
+				URL:         info.URL,
+				Header:      info.Header,
+				Object:      pod,
+			}
+			if !reflect.DeepEqual(expected, *info) {
+				t.Errorf("expected %#v, got %#v", expected, *info)
+			}
+			if e, a := "/api/v1/namespaces/ns/pods/foo/status", info.URL.Path; e != a {
+				t.Errorf("expected path %s, got %s", e, a)
+			}
+			audit = append(audit, "outer")
+			info.Header.Set("X-Audit-ID", "42")
+			result := next(ctx, info)
+			audit = append(audit, "outer done")
+			return result
+		},
+		func(ctx context.Context, info *RequestInfo, next RequestInvoker) Result {
+			audit = append(audit, "inner "+info.Header.Get("X-Audit-ID"))
+			result := next(ctx, info)
+			if !apierrors.IsNotFound(result.Error()) {
+				t.Errorf("expected a not found error, got %v", result.Error())
+			}
+			audit = append(audit, "inner done")
+			return result
+		},
+	}
+
+	err := c.Put().Namespace("ns").Resource("pods").Name("foo").SubResource("status").Body(pod).Do(context.Background()).Error()
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+	expected := []string{"outer", "inner 42", "server 42", "inner done", "outer done"}
+	if !reflect.DeepEqual(expected, audit) {
+		t.Errorf("expected %v, got %v", expected, audit)
+	}
+}
+
+func TestRequestInterceptorShortCircuit(t *testing.T) {
+	requests := 0
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", runtime.ContentTypeJSON)
+		w.Write([]byte(runtime.EncodeOrDie(scheme.Codecs.LegacyCodec(v1.SchemeGroupVersion), &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo"}})))
+	}))
+	defer testServer.Close()
+
+	// A caching interceptor serves the Result of the first GET.
+	var cached *Result
+	denied := errors.New("denied")
+	c := testRESTClient(t, testServer)
+	c.interceptors = []RequestInterceptor{
+		func(ctx context.Context, info *RequestInfo, next RequestInvoker) Result {
+			switch {
+			case info.Verb == "DELETE":
+				return ErrorResult(denied)
+			case info.Verb == "GET" && cached != nil:
+				return *cached
+			}
+			result := next(ctx, info)
+			if info.Verb == "GET" {
+				cached = &result
+			}
+			return result
+		},
+	}
+
+	for i := 0; i < 2; i++ {
+		pod := &v1.Pod{}
+		if err := c.Get().Namespace("ns").Resource("pods").Name("foo").Do(context.Background()).Into(pod); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if e, a := "foo", pod.Name; e != a {
+			t.Errorf("expected pod %s, got %s", e, a)
+		}
+	}
+	if _, err := c.Delete().Namespace("ns").Resource("pods").Name("foo").DoRaw(context.Background()); err != denied {
+		t.Errorf("expected %v, got %v", denied, err)
+	}
+	if e, a := 1, requests; e != a {
+		t.Errorf("expected %d requests to the server, got %d", e, a)
+	}
+}
+
+func TestRequestInterceptorWatch(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+
+	denied := errors.New("denied")
+	var operations []string
+	c := testRESTClient(t, testServer)
+	c.interceptors = []RequestInterceptor{
+		func(ctx context.Context, info *RequestInfo, next RequestInvoker) Result {
+			operations = append(operations, info.Operation)
+			switch info.Name {
+			case "denied":
+				return ErrorResult(denied)
+			case "dropped":
+				return Result{}
+			}
+			return next(ctx, info)
+		},
+	}
+
+	w, err := c.Get().Resource("pods").Watch(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w.Stop()
+	if _, err := c.Get().Resource("pods").Name("denied").Watch(context.Background()); err != denied {
+		t.Errorf("expected %v, got %v", denied, err)
+	}
+	if _, err := c.Get().Resource("pods").Name("dropped").Stream(context.Background()); err != errShortCircuitedWithoutError {
+		t.Errorf("expected %v, got %v", errShortCircuitedWithoutError, err)
+	}
+	if e, a := []string{"Watch", "Watch", "Stream"}, operations; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}
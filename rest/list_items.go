@@ -0,0 +1,421 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// protobufEncodingPrefix is the prefix of the protobuf encoding of objects, see
+// k8s.io/apimachinery/pkg/runtime/serializer/protobuf.
+var protobufEncodingPrefix = []byte{0x6b, 0x38, 0x73, 0x00}
+
+// EachListItem executes a LIST request and calls fn with every item of the
+// returned list, decoding the items one at a time as the response is read, and
+// returns the metadata of the list. Unlike Do, it never holds the whole
+// response or list in memory, only the item being decoded, which bounds the
+// memory used by huge lists.
+//
+// JSON and protobuf responses are decoded incrementally, other responses are
+// decoded as a whole. If fn returns an error, reading the response stops and
+// that error is returned.
+//
+// Error type:
+//   - If the server responds with a status: *errors.StatusError or *errors.UnexpectedObjectError
+//   - http.Client.Do errors are returned directly.
+func (r *Request) EachListItem(ctx context.Context, fn func(item runtime.Object) error) (*metav1.ListMeta, error) {
+	if !r.intercepted() {
+		return r.eachListItem(ctx, fn)
+	}
+	var listMeta *metav1.ListMeta
+	result := r.intercept(ctx, "EachListItem", func(ctx context.Context) Result {
+		var err error
+		listMeta, err = r.eachListItem(ctx, fn)
+		return Result{err: err}
+	})
+	switch {
+	case result.err != nil:
+		return nil, result.err
+	case listMeta == nil:
+		return nil, errShortCircuitedWithoutError
+	}
+	return listMeta, nil
+}
+
+func (r *Request) eachListItem(ctx context.Context, fn func(item runtime.Object) error) (*metav1.ListMeta, error) {
+	var listMeta *metav1.ListMeta
+	var decodeErr error
+	err := r.request(ctx, "EachListItem", func(req *http.Request, resp *http.Response) {
+		if resp.StatusCode < http.StatusOK || resp.StatusCode > http.StatusPartialContent {
+			decodeErr = r.transformResponse(resp, req).Error()
+			return
+		}
+
+		contentType := resp.Header.Get("Content-Type")
+		if len(contentType) == 0 {
+			contentType = r.c.contentConfig().ContentType
+		}
+		mediaType, params, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			decodeErr = errors.NewInternalError(err)
+			return
+		}
+		decoder, err := r.c.content.Negotiator.Decoder(mediaType, params)
+		if err != nil {
+			decodeErr = err
+			return
+		}
+		handleWarnings(resp.Header, r.warningHandler, r.c.content.GroupVersion, r.resource)
+
+		switch mediaType {
+		case runtime.ContentTypeJSON:
+			listMeta, decodeErr = decodeJSONListItems(resp.Body, decoder, fn)
+		case runtime.ContentTypeProtobuf:
+			listMeta, decodeErr = decodeProtobufListItems(resp.Body, decoder, fn)
+		default:
+			listMeta, decodeErr = decodeListItems(r.transformResponse(resp, req), fn)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+	return listMeta, nil
+}
+
+// decodeListItems calls fn with every item of the list of result.
+func decodeListItems(result Result, fn func(item runtime.Object) error) (*metav1.ListMeta, error) {
+	list, err := result.Get()
+	if err != nil {
+		return nil, err
+	}
+	m, err := meta.ListAccessor(list)
+	if err != nil {
+		return nil, fmt.Errorf("returned object must be a list: %v", err)
+	}
+	if err := meta.EachListItem(list, fn); err != nil {
+		return nil, err
+	}
+	return &metav1.ListMeta{
+		SelfLink:           m.GetSelfLink(),
+		ResourceVersion:    m.GetResourceVersion(),
+		Continue:           m.GetContinue(),
+		RemainingItemCount: m.GetRemainingItemCount(),
+	}, nil
+}
+
+// itemDefaults returns the default group, version and kind of the items of a
+// list of the given apiVersion and kind.
+func itemDefaults(apiVersion, kind string) (*schema.GroupVersionKind, error) {
+	if len(kind) == 0 {
+		return nil, nil
+	}
+	if !strings.HasSuffix(kind, "List") {
+		return nil, fmt.Errorf("returned object must be a list, got %s", kind)
+	}
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return nil, err
+	}
+	gvk := gv.WithKind(strings.TrimSuffix(kind, "List"))
+	return &gvk, nil
+}
+
+// decodeJSONListItems decodes the JSON encoded list read from body one item at
+// a time, and calls fn with every item.
+func decodeJSONListItems(body io.Reader, decoder runtime.Decoder, fn func(item runtime.Object) error) (*metav1.ListMeta, error) {
+	d := json.NewDecoder(body)
+	if err := expectJSONDelim(d, '{'); err != nil {
+		return nil, err
+	}
+	listMeta := &metav1.ListMeta{}
+	var apiVersion, kind string
+	for d.More() {
+		token, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch token {
+		case "apiVersion":
+			err = d.Decode(&apiVersion)
+		case "kind":
+			err = d.Decode(&kind)
+		case "metadata":
+			err = d.Decode(listMeta)
+		case "items":
+			err = decodeJSONItems(d, apiVersion, kind, decoder, fn)
+		default:
+			var skipped json.RawMessage
+			err = d.Decode(&skipped)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _, err := itemDefaults(apiVersion, kind); err != nil {
+		return nil, err
+	}
+	return listMeta, expectJSONDelim(d, '}')
+}
+
+func decodeJSONItems(d *json.Decoder, apiVersion, kind string, decoder runtime.Decoder, fn func(item runtime.Object) error) error {
+	token, err := d.Token()
+	if err != nil || token == nil {
+		return err
+	}
+	if token != json.Delim('[') {
+		return fmt.Errorf("unexpected %v, expected items", token)
+	}
+	// The kind of the list precedes its items in the lists encoded by the server.
+	defaults, err := itemDefaults(apiVersion, kind)
+	if err != nil {
+		return err
+	}
+	for d.More() {
+		var data json.RawMessage
+		if err := d.Decode(&data); err != nil {
+			return err
+		}
+		item, _, err := decoder.Decode(data, defaults, nil)
+		if runtime.IsMissingKind(err) && defaults != nil && len(data) > 1 {
+			// Not every decoder falls back to the defaults, e.g. the
+			// unstructured one, so set the kind of the item explicitly.
+			item, _, err = decoder.Decode(withJSONTypeMeta(data, defaults), defaults, nil)
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	return expectJSONDelim(d, ']')
+}
+
+// withJSONTypeMeta returns the JSON encoded object data with the apiVersion and
+// kind of gvk.
+func withJSONTypeMeta(data []byte, gvk *schema.GroupVersionKind) []byte {
+	apiVersion, kind := gvk.ToAPIVersionAndKind()
+	typeMeta, _ := json.Marshal(metav1.TypeMeta{APIVersion: apiVersion, Kind: kind})
+	if len(bytes.TrimSpace(data[1:len(data)-1])) == 0 {
+		return typeMeta
+	}
+	// Replace the closing brace of typeMeta with a comma followed by the
+	// fields of data.
+	return append(append(typeMeta[:len(typeMeta)-1], ','), data[1:]...)
+}
+
+func expectJSONDelim(d *json.Decoder, delim json.Delim) error {
+	token, err := d.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("unexpected %v, expected %v", token, delim)
+	}
+	return nil
+}
+
+// decodeProtobufListItems decodes the protobuf encoded list read from body one
+// item at a time, and calls fn with every item.
+//
+// The body is a runtime.Unknown holding the type of the list and its raw
+// encoding, made of its ListMeta in field 1 and of its items in field 2, like
+// every list of the API.
+func decodeProtobufListItems(body io.Reader, decoder runtime.Decoder, fn func(item runtime.Object) error) (*metav1.ListMeta, error) {
+	r := bufio.NewReader(body)
+	prefix := make([]byte, len(protobufEncodingPrefix))
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return nil, err
+	}
+	if string(prefix) != string(protobufEncodingPrefix) {
+		return nil, fmt.Errorf("provided data does not appear to be a protobuf message, expected prefix %v", protobufEncodingPrefix)
+	}
+
+	var listMeta *metav1.ListMeta
+	var typeMeta runtime.TypeMeta
+	for {
+		field, wireType, err := readProtobufTag(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case field == 1 && wireType == protobufWireBytes:
+			data, err := readProtobufBytes(r)
+			if err != nil {
+				return nil, err
+			}
+			if err := typeMeta.Unmarshal(data); err != nil {
+				return nil, err
+			}
+		case field == 2 && wireType == protobufWireBytes:
+			defaults, err := itemDefaults(typeMeta.APIVersion, typeMeta.Kind)
+			if err != nil {
+				return nil, err
+			}
+			n, err := binary.ReadUvarint(r)
+			if err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			raw := bufio.NewReader(&io.LimitedReader{R: r, N: int64(n)})
+			if listMeta, err = decodeProtobufList(raw, defaults, decoder, fn); err != nil {
+				return nil, err
+			}
+		default:
+			if err := skipProtobufField(r, wireType); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if listMeta == nil {
+		return nil, fmt.Errorf("returned object must be a list, got %s", typeMeta.Kind)
+	}
+	return listMeta, nil
+}
+
+func decodeProtobufList(r *bufio.Reader, defaults *schema.GroupVersionKind, decoder runtime.Decoder, fn func(item runtime.Object) error) (*metav1.ListMeta, error) {
+	if defaults == nil {
+		return nil, fmt.Errorf("the kind of the returned list is unknown")
+	}
+	listMeta := &metav1.ListMeta{}
+	for {
+		field, wireType, err := readProtobufTag(r)
+		if err == io.EOF {
+			return listMeta, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if wireType != protobufWireBytes || (field != 1 && field != 2) {
+			if err := skipProtobufField(r, wireType); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		data, err := readProtobufBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		if field == 1 {
+			if err := listMeta.Unmarshal(data); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		// Wrap the item in a runtime.Unknown of the kind of the items, which
+		// is what the decoder expects.
+		unknown := runtime.Unknown{
+			TypeMeta: runtime.TypeMeta{
+				APIVersion: defaults.GroupVersion().String(),
+				Kind:       defaults.Kind,
+			},
+			Raw:         data,
+			ContentType: runtime.ContentTypeProtobuf,
+		}
+		encoded := make([]byte, len(protobufEncodingPrefix)+unknown.Size())
+		copy(encoded, protobufEncodingPrefix)
+		if _, err := unknown.MarshalTo(encoded[len(protobufEncodingPrefix):]); err != nil {
+			return nil, err
+		}
+		item, _, err := decoder.Decode(encoded, defaults, nil)
+		if err != nil {
+			return nil, err
+		}
+		if err := fn(item); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// The protobuf wire types, see https://protobuf.dev/programming-guides/encoding/.
+const (
+	protobufWireVarint  = 0
+	protobufWireFixed64 = 1
+	protobufWireBytes   = 2
+	protobufWireFixed32 = 5
+)
+
+func readProtobufTag(r *bufio.Reader) (field uint64, wireType uint64, err error) {
+	tag, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	return tag >> 3, tag & 0x7, nil
+}
+
+func readProtobufBytes(r *bufio.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return data, nil
+}
+
+func skipProtobufField(r *bufio.Reader, wireType uint64) error {
+	var n uint64
+	switch wireType {
+	case protobufWireVarint:
+		_, err := binary.ReadUvarint(r)
+		return unexpectedEOF(err)
+	case protobufWireFixed64:
+		n = 8
+	case protobufWireBytes:
+		var err error
+		if n, err = binary.ReadUvarint(r); err != nil {
+			return unexpectedEOF(err)
+		}
+	case protobufWireFixed32:
+		n = 4
+	default:
+		return fmt.Errorf("unsupported protobuf wire type %d", wireType)
+	}
+	_, err := r.Discard(int(n))
+	return unexpectedEOF(err)
+}
+
+// unexpectedEOF turns the io.EOF met in the middle of a protobuf field into an
+// io.ErrUnexpectedEOF.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer/protobuf"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+func testPodList() *v1.PodList {
+	return &v1.PodList{
+		ListMeta: metav1.ListMeta{ResourceVersion: "42", Continue: "next"},
+		Items: []v1.Pod{
+			{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "ns"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "ns"}, Spec: v1.PodSpec{NodeName: "node"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "c", Namespace: "ns"}},
+		},
+	}
+}
+
+func testListServer(t *testing.T, contentType string, encoder runtime.Encoder) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		if err := encoder.Encode(testPodList(), w); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}))
+}
+
+func TestRequestEachListItem(t *testing.T) {
+	protobufSerializer := protobuf.NewSerializer(scheme.Scheme, scheme.Scheme)
+	for _, test := range []struct {
+		name        string
+		contentType string
+		encoder     runtime.Encoder
+	}{
+		{
+			name:        "json",
+			contentType: runtime.ContentTypeJSON,
+			encoder:     scheme.Codecs.LegacyCodec(v1.SchemeGroupVersion),
+		},
+		{
+			name:        "protobuf",
+			contentType: runtime.ContentTypeProtobuf,
+			encoder:     scheme.Codecs.EncoderForVersion(protobufSerializer, v1.SchemeGroupVersion),
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			testServer := testListServer(t, test.contentType, test.encoder)
+			defer testServer.Close()
+
+			contentConfig := defaultContentConfig()
+			contentConfig.ContentType = test.contentType
+			c := testRESTClientWithConfig(t, testServer, contentConfig)
+
+			var pods []v1.Pod
+			listMeta, err := c.Get().Resource("pods").EachListItem(context.Background(), func(item runtime.Object) error {
+				pods = append(pods, *item.(*v1.Pod))
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expected := testPodList()
+			if !reflect.DeepEqual(&expected.ListMeta, listMeta) {
+				t.Errorf("expected %#v, got %#v", &expected.ListMeta, listMeta)
+			}
+			if !reflect.DeepEqual(expected.Items, pods) {
+				t.Errorf("expected %#v, got %#v", expected.Items, pods)
+			}
+		})
+	}
+}
+
+func TestRequestEachListItemUnstructured(t *testing.T) {
+	testServer := testListServer(t, runtime.ContentTypeJSON, scheme.Codecs.LegacyCodec(v1.SchemeGroupVersion))
+	defer testServer.Close()
+
+	contentConfig := defaultContentConfig()
+	contentConfig.Negotiator = runtime.NewClientNegotiator(runtime.NewSimpleNegotiatedSerializer(runtime.SerializerInfo{
+		MediaType:        runtime.ContentTypeJSON,
+		MediaTypeType:    "application",
+		MediaTypeSubType: "json",
+		Serializer:       unstructured.UnstructuredJSONScheme,
+	}), v1.SchemeGroupVersion)
+	c := testRESTClientWithConfig(t, testServer, contentConfig)
+
+	var names []string
+	if _, err := c.Get().Resource("pods").EachListItem(context.Background(), func(item runtime.Object) error {
+		u := item.(*unstructured.Unstructured)
+		if e, a := v1.SchemeGroupVersion.WithKind("Pod"), u.GroupVersionKind(); e != a {
+			t.Errorf("expected %v, got %v", e, a)
+		}
+		names = append(names, u.GetName())
+		return nil
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := []string{"a", "b", "c"}, names; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}
+
+func TestRequestEachListItemErrors(t *testing.T) {
+	testServer := testListServer(t, runtime.ContentTypeJSON, scheme.Codecs.LegacyCodec(v1.SchemeGroupVersion))
+	defer testServer.Close()
+	c := testRESTClient(t, testServer)
+
+	stop := errors.New("stop")
+	count := 0
+	if _, err := c.Get().Resource("pods").EachListItem(context.Background(), func(item runtime.Object) error {
+		count++
+		return stop
+	}); err != stop {
+		t.Errorf("expected %v, got %v", stop, err)
+	}
+	if count != 1 {
+		t.Errorf("expected a single item, got %d", count)
+	}
+
+	notFoundServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", runtime.ContentTypeJSON)
+		w.WriteHeader(http.StatusNotFound)
+		status := &metav1.Status{Status: metav1.StatusFailure, Reason: metav1.StatusReasonNotFound, Code: http.StatusNotFound}
+		w.Write([]byte(runtime.EncodeOrDie(scheme.Codecs.LegacyCodec(v1.SchemeGroupVersion), status)))
+	}))
+	defer notFoundServer.Close()
+	c = testRESTClient(t, notFoundServer)
+	if _, err := c.Get().Resource("pods").EachListItem(context.Background(), func(item runtime.Object) error {
+		return nil
+	}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
@@ -32,6 +32,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/net/http2"
@@ -45,6 +46,8 @@ import (
 	"k8s.io/apimachinery/pkg/watch"
 	restclientwatch "k8s.io/client-go/rest/watch"
 	"k8s.io/client-go/tools/metrics"
+	"k8s.io/client-go/transport"
+	"k8s.io/client-go/util/cbor"
 	"k8s.io/client-go/util/flowcontrol"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/clock"
@@ -97,6 +100,8 @@ type Request struct {
 	c *RESTClient
 
 	warningHandler WarningHandler
+	tracer         Tracer
+	interceptors   []RequestInterceptor
 
 	rateLimiter flowcontrol.RateLimiter
 	backoff     BackoffManager
@@ -123,6 +128,8 @@ type Request struct {
 	// only one of body / bodyBytes may be set. requests using body are not retriable.
 	body      io.Reader
 	bodyBytes []byte
+	// object is the object bodyBytes was encoded from, if any.
+	object runtime.Object
 
 	retryFn requestRetryFunc
 }
@@ -158,17 +165,27 @@ func NewRequest(c *RESTClient) *Request {
 		maxRetries:     10,
 		retryFn:        defaultRequestRetryFn,
 		warningHandler: c.warningHandler,
+		tracer:         c.tracer,
+		interceptors:   c.interceptors,
 	}
 
-	switch {
-	case len(c.content.AcceptContentTypes) > 0:
-		r.SetHeader("Accept", c.content.AcceptContentTypes)
-	case len(c.content.ContentType) > 0:
-		r.SetHeader("Accept", c.content.ContentType+", */*")
+	if accept := acceptHeader(c.contentConfig()); len(accept) > 0 {
+		r.SetHeader("Accept", accept)
 	}
 	return r
 }
 
+// acceptHeader returns the Accept header of the requests made with config.
+func acceptHeader(config ClientContentConfig) string {
+	switch {
+	case len(config.AcceptContentTypes) > 0:
+		return config.AcceptContentTypes
+	case len(config.ContentType) > 0:
+		return config.ContentType + ", */*"
+	}
+	return ""
+}
+
 // NewRequestWithClient creates a Request with an embedded RESTClient for use in test scenarios.
 func NewRequestWithClient(base *url.URL, versionedAPIPath string, content ClientContentConfig, client *http.Client) *Request {
 	return NewRequest(&RESTClient{
@@ -450,19 +467,23 @@ func (r *Request) Body(obj interface{}) *Request {
 		glogBody("Request Body", data)
 		r.body = nil
 		r.bodyBytes = data
+		r.object = nil
 	case []byte:
 		glogBody("Request Body", t)
 		r.body = nil
 		r.bodyBytes = t
+		r.object = nil
 	case io.Reader:
 		r.body = t
 		r.bodyBytes = nil
+		r.object = nil
 	case runtime.Object:
 		// callers may pass typed interface pointers, therefore we must check nil with reflection
 		if reflect.ValueOf(t).IsNil() {
 			return r
 		}
-		encoder, err := r.c.content.Negotiator.Encoder(r.c.content.ContentType, nil)
+		content := r.c.contentConfig()
+		encoder, err := content.Negotiator.Encoder(content.ContentType, nil)
 		if err != nil {
 			r.err = err
 			return r
@@ -475,7 +496,8 @@ func (r *Request) Body(obj interface{}) *Request {
 		glogBody("Request Body", data)
 		r.body = nil
 		r.bodyBytes = data
-		r.SetHeader("Content-Type", r.c.content.ContentType)
+		r.object = t
+		r.SetHeader("Content-Type", content.ContentType)
 	default:
 		r.err = fmt.Errorf("unknown type used for body: %+v", obj)
 	}
@@ -609,6 +631,10 @@ func (r *Request) tryThrottleWithInfo(ctx context.Context, retryInfo string) err
 		return nil
 	}
 
+	if _, ok := r.rateLimiter.(flowcontrol.AdaptiveRateLimiter); ok {
+		ctx = flowcontrol.WithRequestKey(ctx, r.rateLimiterKey())
+	}
+
 	now := time.Now()
 
 	err := r.rateLimiter.Wait(ctx)
@@ -616,6 +642,9 @@ func (r *Request) tryThrottleWithInfo(ctx context.Context, retryInfo string) err
 		err = fmt.Errorf("client rate limiter Wait returned an error: %w", err)
 	}
 	latency := time.Since(now)
+	if span, ok := spanFromContext(ctx); ok {
+		span.SetAttributes(Attribute{Key: SpanAttributeThrottleWait, Value: latency})
+	}
 
 	var message string
 	switch {
@@ -642,6 +671,39 @@ func (r *Request) tryThrottle(ctx context.Context) error {
 	return r.tryThrottleWithInfo(ctx, "")
 }
 
+// observeServerFeedback reports the API Priority and Fairness feedback of the
+// server in resp to the rate limiter, if it adapts to it.
+func (r *Request) observeServerFeedback(ctx context.Context, resp *http.Response) {
+	limiter, ok := r.rateLimiter.(flowcontrol.AdaptiveRateLimiter)
+	if !ok || resp == nil {
+		return
+	}
+
+	// priority and fairness sets the UIDs of the FlowSchema and of the
+	// PriorityLevelConfiguration associated with a request in these
+	// response headers.
+	const (
+		responseHeaderMatchedFlowSchemaUID    = "X-Kubernetes-PF-FlowSchema-UID"
+		responseHeaderMatchedPriorityLevelUID = "X-Kubernetes-PF-PriorityLevel-UID"
+	)
+	feedback := flowcontrol.ServerFeedback{
+		FlowSchemaUID:    resp.Header.Get(responseHeaderMatchedFlowSchemaUID),
+		PriorityLevelUID: resp.Header.Get(responseHeaderMatchedPriorityLevelUID),
+		Throttled:        resp.StatusCode == http.StatusTooManyRequests,
+	}
+	if seconds, ok := retryAfterSeconds(resp); ok {
+		feedback.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	limiter.Observe(flowcontrol.WithRequestKey(ctx, r.rateLimiterKey()), feedback)
+}
+
+// rateLimiterKey returns the key of r for an adaptive rate limiter, made of the
+// verb and the URL template of the request.
+func (r *Request) rateLimiterKey() string {
+	u := r.finalURLTemplate()
+	return r.verb + " " + u.Path
+}
+
 type throttleSettings struct {
 	logLevel       klog.Level
 	minLogInterval time.Duration
@@ -701,12 +763,37 @@ func (b *throttledLogger) Infof(message string, args ...interface{}) {
 // Watch attempts to begin watching the requested location.
 // Returns a watch.Interface, or an error.
 func (r *Request) Watch(ctx context.Context) (watch.Interface, error) {
+	if !r.intercepted() {
+		return r.watch(ctx)
+	}
+	var w watch.Interface
+	result := r.intercept(ctx, "Watch", func(ctx context.Context) Result {
+		var err error
+		w, err = r.watch(ctx)
+		return Result{err: err}
+	})
+	switch {
+	case result.err != nil:
+		if w != nil {
+			w.Stop()
+		}
+		return nil, result.err
+	case w == nil:
+		return nil, errShortCircuitedWithoutError
+	}
+	return w, nil
+}
+
+func (r *Request) watch(ctx context.Context) (_ watch.Interface, err error) {
 	// We specifically don't want to rate limit watches, so we
 	// don't use r.rateLimiter here.
 	if r.err != nil {
 		return nil, r.err
 	}
 
+	ctx, trace := r.startTrace(ctx, "Watch")
+	defer func() { trace.end(err) }()
+
 	client := r.c.Client
 	if client == nil {
 		client = http.DefaultClient
@@ -723,17 +810,19 @@ func (r *Request) Watch(ctx context.Context) (watch.Interface, error) {
 	retry := r.retryFn(r.maxRetries)
 	url := r.URL().String()
 	for {
-		if err := retry.Before(ctx, r); err != nil {
+		attemptCtx := trace.startAttempt(ctx)
+		if err := retry.Before(attemptCtx, r); err != nil {
 			return nil, retry.WrapPreviousError(err)
 		}
 
-		req, err := r.newHTTPRequest(ctx)
+		req, err := r.newHTTPRequest(attemptCtx)
 		if err != nil {
 			return nil, err
 		}
 
 		resp, err := client.Do(req)
 		retry.After(ctx, r, resp, err)
+		trace.attemptDone(resp, err)
 		if err == nil && resp.StatusCode == http.StatusOK {
 			return r.newStreamWatcher(resp)
 		}
@@ -779,7 +868,7 @@ func (r *Request) newStreamWatcher(resp *http.Response) (watch.Interface, error)
 		return nil, err
 	}
 
-	handleWarnings(resp.Header, r.warningHandler)
+	handleWarnings(resp.Header, r.warningHandler, r.c.content.GroupVersion, r.resource)
 
 	frameReader := framer.NewFrameReader(resp.Body)
 	watchEventDecoder := streaming.NewDecoder(frameReader, streamingSerializer)
@@ -829,10 +918,35 @@ func sanitize(req *Request, resp *http.Response, err error) (string, string) {
 // Any non-2xx http status code causes an error.  If we get a non-2xx code, we try to convert the body into an APIStatus object.
 // If we can, we return that as an error.  Otherwise, we create an error that lists the http status and the content of the response.
 func (r *Request) Stream(ctx context.Context) (io.ReadCloser, error) {
+	if !r.intercepted() {
+		return r.stream(ctx)
+	}
+	var body io.ReadCloser
+	result := r.intercept(ctx, "Stream", func(ctx context.Context) Result {
+		var err error
+		body, err = r.stream(ctx)
+		return Result{err: err}
+	})
+	switch {
+	case result.err != nil:
+		if body != nil {
+			body.Close()
+		}
+		return nil, result.err
+	case body == nil:
+		return nil, errShortCircuitedWithoutError
+	}
+	return body, nil
+}
+
+func (r *Request) stream(ctx context.Context) (_ io.ReadCloser, err error) {
 	if r.err != nil {
 		return nil, r.err
 	}
 
+	ctx, trace := r.startTrace(ctx, "Stream")
+	defer func() { trace.end(err) }()
+
 	if err := r.tryThrottle(ctx); err != nil {
 		return nil, err
 	}
@@ -845,16 +959,18 @@ func (r *Request) Stream(ctx context.Context) (io.ReadCloser, error) {
 	retry := r.retryFn(r.maxRetries)
 	url := r.URL().String()
 	for {
-		if err := retry.Before(ctx, r); err != nil {
+		attemptCtx := trace.startAttempt(ctx)
+		if err := retry.Before(attemptCtx, r); err != nil {
 			return nil, err
 		}
 
-		req, err := r.newHTTPRequest(ctx)
+		req, err := r.newHTTPRequest(attemptCtx)
 		if err != nil {
 			return nil, err
 		}
 		resp, err := client.Do(req)
 		retry.After(ctx, r, resp, err)
+		trace.attemptDone(resp, err)
 		if err != nil {
 			// we only retry on an HTTP response with 'Retry-After' header
 			return nil, err
@@ -862,7 +978,7 @@ func (r *Request) Stream(ctx context.Context) (io.ReadCloser, error) {
 
 		switch {
 		case (resp.StatusCode >= 200) && (resp.StatusCode < 300):
-			handleWarnings(resp.Header, r.warningHandler)
+			handleWarnings(resp.Header, r.warningHandler, r.c.content.GroupVersion, r.resource)
 			return resp.Body, nil
 
 		default:
@@ -925,12 +1041,26 @@ func (r *Request) newHTTPRequest(ctx context.Context) (*http.Request, error) {
 		body = bytes.NewReader(r.bodyBytes)
 	}
 
+	if r.verb == "GET" && len(r.resource) > 0 && len(r.resourceName) == 0 {
+		// List responses may be arbitrarily large, so they are never cached.
+		ctx = transport.WithoutResponseCache(ctx)
+	}
+
 	url := r.URL().String()
 	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, newDNSMetricsTrace(ctx)), r.verb, url, body)
 	if err != nil {
 		return nil, err
 	}
 	req.Header = r.headers
+	if r.tracer != nil {
+		// The trace context differs between attempts, so it must not be
+		// written into the headers shared by them.
+		req.Header = r.headers.Clone()
+		if req.Header == nil {
+			req.Header = http.Header{}
+		}
+		r.tracer.Inject(ctx, req.Header)
+	}
 	return req, nil
 }
 
@@ -962,7 +1092,7 @@ func newDNSMetricsTrace(ctx context.Context) *httptrace.ClientTrace {
 // received. It handles retry behavior and up front validation of requests. It will invoke
 // fn at most once. It will return an error if a problem occurred prior to connecting to the
 // server - the provided function is responsible for handling server errors.
-func (r *Request) request(ctx context.Context, fn func(*http.Request, *http.Response)) error {
+func (r *Request) request(ctx context.Context, operation string, fn func(*http.Request, *http.Response)) (err error) {
 	// Metrics for total request latency
 	start := time.Now()
 	defer func() {
@@ -983,6 +1113,9 @@ func (r *Request) request(ctx context.Context, fn func(*http.Request, *http.Resp
 		client = http.DefaultClient
 	}
 
+	ctx, trace := r.startTrace(ctx, operation)
+	defer func() { trace.end(err) }()
+
 	// Throttle the first try before setting up the timeout configured on the
 	// client. We don't want a throttled client to return timeouts to callers
 	// before it makes a single request.
@@ -1013,10 +1146,11 @@ func (r *Request) request(ctx context.Context, fn func(*http.Request, *http.Resp
 	// Right now we make about ten retry attempts if we get a Retry-After response.
 	retry := r.retryFn(r.maxRetries)
 	for {
-		if err := retry.Before(ctx, r); err != nil {
+		attemptCtx := trace.startAttempt(ctx)
+		if err := retry.Before(attemptCtx, r); err != nil {
 			return retry.WrapPreviousError(err)
 		}
-		req, err := r.newHTTPRequest(ctx)
+		req, err := r.newHTTPRequest(attemptCtx)
 		if err != nil {
 			return err
 		}
@@ -1027,6 +1161,7 @@ func (r *Request) request(ctx context.Context, fn func(*http.Request, *http.Resp
 			metrics.RequestSize.Observe(ctx, r.verb, r.URL().Host, float64(req.ContentLength))
 		}
 		retry.After(ctx, r, resp, err)
+		trace.attemptDone(resp, err)
 
 		done := func() bool {
 			defer readAndCloseResponseBody(resp)
@@ -1039,6 +1174,9 @@ func (r *Request) request(ctx context.Context, fn func(*http.Request, *http.Resp
 				fn(req, resp)
 			}
 
+			if r.fallBackFromCBOR(req, resp) {
+				return false
+			}
 			if retry.IsNextRetry(ctx, r, req, resp, err, isErrRetryableFunc) {
 				return false
 			}
@@ -1052,6 +1190,38 @@ func (r *Request) request(ctx context.Context, fn func(*http.Request, *http.Resp
 	}
 }
 
+// fallBackFromCBOR handles the server rejecting the CBOR body of req as an
+// unsupported media type: the client falls back to JSON for its subsequent
+// requests, and so does r if its body was encoded from an object, in which
+// case it returns true for r to be retried.
+func (r *Request) fallBackFromCBOR(req *http.Request, resp *http.Response) bool {
+	if resp == nil || resp.StatusCode != http.StatusUnsupportedMediaType || !cbor.IsContentTypeCBOR(req.Header.Get("Content-Type")) {
+		return false
+	}
+	if atomic.CompareAndSwapInt32(&r.c.cborUnsupported, 0, 1) {
+		klog.V(2).Infof("The server does not support CBOR request bodies, falling back to JSON")
+	}
+	if r.object == nil {
+		return false
+	}
+
+	content := r.c.contentConfig()
+	encoder, err := content.Negotiator.Encoder(content.ContentType, nil)
+	if err != nil {
+		return false
+	}
+	data, err := runtime.Encode(encoder, r.object)
+	if err != nil {
+		return false
+	}
+	r.bodyBytes = data
+	r.SetHeader("Content-Type", content.ContentType)
+	if r.headers.Get("Accept") == acceptHeader(r.c.content) {
+		r.SetHeader("Accept", acceptHeader(content))
+	}
+	return true
+}
+
 // Do formats and executes the request. Returns a Result object for easy response
 // processing.
 //
@@ -1059,8 +1229,15 @@ func (r *Request) request(ctx context.Context, fn func(*http.Request, *http.Resp
 //   - If the server responds with a status: *errors.StatusError or *errors.UnexpectedObjectError
 //   - http.Client.Do errors are returned directly.
 func (r *Request) Do(ctx context.Context) Result {
+	if !r.intercepted() {
+		return r.do(ctx)
+	}
+	return r.intercept(ctx, "Do", r.do)
+}
+
+func (r *Request) do(ctx context.Context) Result {
 	var result Result
-	err := r.request(ctx, func(req *http.Request, resp *http.Response) {
+	err := r.request(ctx, "Do", func(req *http.Request, resp *http.Response) {
 		result = r.transformResponse(resp, req)
 	})
 	if err != nil {
@@ -1075,7 +1252,18 @@ func (r *Request) Do(ctx context.Context) Result {
 // DoRaw executes the request but does not process the response body.
 func (r *Request) DoRaw(ctx context.Context) ([]byte, error) {
 	var result Result
-	err := r.request(ctx, func(req *http.Request, resp *http.Response) {
+	if !r.intercepted() {
+		result = r.doRaw(ctx)
+	} else {
+		result = r.intercept(ctx, "DoRaw", r.doRaw)
+	}
+	return result.body, result.err
+}
+
+func (r *Request) doRaw(ctx context.Context) Result {
+	var result Result
+	err := r.request(ctx, "DoRaw", func(req *http.Request, resp *http.Response) {
+		result.statusCode = resp.StatusCode
 		result.body, result.err = io.ReadAll(resp.Body)
 		glogBody("Response Body", result.body)
 		if resp.StatusCode < http.StatusOK || resp.StatusCode > http.StatusPartialContent {
@@ -1083,12 +1271,12 @@ func (r *Request) DoRaw(ctx context.Context) ([]byte, error) {
 		}
 	})
 	if err != nil {
-		return nil, err
+		return Result{err: err}
 	}
 	if result.err == nil || len(result.body) > 0 {
 		metrics.ResponseSize.Observe(ctx, r.verb, r.URL().Host, float64(len(result.body)))
 	}
-	return result.body, result.err
+	return result
 }
 
 // transformResponse converts an API response into a structured API object
@@ -1127,7 +1315,7 @@ func (r *Request) transformResponse(resp *http.Response, req *http.Request) Resu
 	var decoder runtime.Decoder
 	contentType := resp.Header.Get("Content-Type")
 	if len(contentType) == 0 {
-		contentType = r.c.content.ContentType
+		contentType = r.c.contentConfig().ContentType
 	}
 	if len(contentType) > 0 {
 		var err error
@@ -1148,7 +1336,7 @@ func (r *Request) transformResponse(resp *http.Response, req *http.Request) Resu
 				body:        body,
 				contentType: contentType,
 				statusCode:  resp.StatusCode,
-				warnings:    handleWarnings(resp.Header, r.warningHandler),
+				warnings:    handleWarnings(resp.Header, r.warningHandler, r.c.content.GroupVersion, r.resource),
 			}
 		}
 	}
@@ -1167,7 +1355,7 @@ func (r *Request) transformResponse(resp *http.Response, req *http.Request) Resu
 			statusCode:  resp.StatusCode,
 			decoder:     decoder,
 			err:         err,
-			warnings:    handleWarnings(resp.Header, r.warningHandler),
+			warnings:    handleWarnings(resp.Header, r.warningHandler, r.c.content.GroupVersion, r.resource),
 		}
 	}
 
@@ -1176,7 +1364,7 @@ func (r *Request) transformResponse(resp *http.Response, req *http.Request) Resu
 		contentType: contentType,
 		statusCode:  resp.StatusCode,
 		decoder:     decoder,
-		warnings:    handleWarnings(resp.Header, r.warningHandler),
+		warnings:    handleWarnings(resp.Header, r.warningHandler, r.c.content.GroupVersion, r.resource),
 	}
 }
 
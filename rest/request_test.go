@@ -51,6 +51,8 @@ import (
 	"k8s.io/client-go/kubernetes/scheme"
 	restclientwatch "k8s.io/client-go/rest/watch"
 	"k8s.io/client-go/tools/metrics"
+	"k8s.io/client-go/transport"
+	"k8s.io/client-go/util/cbor"
 	"k8s.io/client-go/util/flowcontrol"
 	utiltesting "k8s.io/client-go/util/testing"
 	"k8s.io/klog/v2"
@@ -101,6 +103,39 @@ func TestRequestSetsHeaders(t *testing.T) {
 	_, _ = r.Stream(context.Background())
 }
 
+func TestRequestDoesNotCacheLists(t *testing.T) {
+	var ifNoneMatch []string
+	server := clientForFunc(func(req *http.Request) (*http.Response, error) {
+		ifNoneMatch = append(ifNoneMatch, req.Header.Get("If-None-Match"))
+		if req.Header.Get("If-None-Match") == `"1"` {
+			return &http.Response{StatusCode: http.StatusNotModified, Header: http.Header{"Etag": []string{`"1"`}}, Body: http.NoBody, Request: req}, nil
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Etag": []string{`"1"`}, "Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader("{}")),
+			Request:    req,
+		}, nil
+	})
+	server.Transport = transport.NewCacheRoundTripper(transport.NewMemoryResponseCache(), server.Transport)
+	c := &RESTClient{base: &url.URL{}, content: ClientContentConfig{ContentType: "application/json", Negotiator: runtime.NewClientNegotiator(scheme.Codecs.WithoutConversion(), v1.SchemeGroupVersion)}, Client: server}
+
+	for i := 0; i < 2; i++ {
+		if err := c.Get().Namespace("ns").Resource("pods").Do(context.Background()).Error(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if err := c.Get().Namespace("ns").Resource("pods").Name("a").Do(context.Background()).Error(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Only the second request for the named pod is revalidated.
+	if want := []string{"", "", "", `"1"`}; !reflect.DeepEqual(ifNoneMatch, want) {
+		t.Errorf("expected If-None-Match headers %q, got %q", want, ifNoneMatch)
+	}
+}
+
 func TestRequestWithErrorWontChange(t *testing.T) {
 	gvCopy := v1.SchemeGroupVersion
 	original := Request{
@@ -2697,7 +2732,7 @@ func TestRequestWithRetry(t *testing.T) {
 			}
 
 			var transformFuncInvoked int
-			err := req.request(context.Background(), func(request *http.Request, response *http.Response) {
+			err := req.request(context.Background(), "Do", func(request *http.Request, response *http.Response) {
 				transformFuncInvoked++
 			})
 
@@ -4065,3 +4100,198 @@ func TestRequestConcurrencyWithRetry(t *testing.T) {
 		t.Errorf("Expected attempts: %d, but got: %d", expected, attempts)
 	}
 }
+
+type recordingAdaptiveLimiter struct {
+	flowcontrol.RateLimiter
+
+	lock        sync.Mutex
+	waitKeys    []string
+	observeKeys []string
+	feedbacks   []flowcontrol.ServerFeedback
+}
+
+func (l *recordingAdaptiveLimiter) Wait(ctx context.Context) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.waitKeys = append(l.waitKeys, flowcontrol.RequestKeyFrom(ctx))
+	return nil
+}
+
+func (l *recordingAdaptiveLimiter) Observe(ctx context.Context, feedback flowcontrol.ServerFeedback) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.observeKeys = append(l.observeKeys, flowcontrol.RequestKeyFrom(ctx))
+	l.feedbacks = append(l.feedbacks, feedback)
+}
+
+func TestRequestAdaptiveRateLimiterFeedback(t *testing.T) {
+	count := 0
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-Kubernetes-PF-FlowSchema-UID", "fs")
+		w.Header().Set("X-Kubernetes-PF-PriorityLevel-UID", "pl")
+		count++
+		if count == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+
+	limiter := &recordingAdaptiveLimiter{}
+	c := testRESTClient(t, testServer)
+	c.rateLimiter = limiter
+	if err := c.Get().Namespace("ns").Resource("pods").Name("foo").Do(context.Background()).Error(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []flowcontrol.ServerFeedback{
+		{FlowSchemaUID: "fs", PriorityLevelUID: "pl", Throttled: true},
+		{FlowSchemaUID: "fs", PriorityLevelUID: "pl"},
+	}
+	if !reflect.DeepEqual(expected, limiter.feedbacks) {
+		t.Errorf("expected feedbacks %v, got %v", expected, limiter.feedbacks)
+	}
+	key := "GET /api/v1/namespaces/{namespace}/pods/{name}"
+	if e, a := []string{key, key}, limiter.waitKeys; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected throttled request keys %v, got %v", e, a)
+	}
+	if e, a := []string{key, key}, limiter.observeKeys; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected observed request keys %v, got %v", e, a)
+	}
+}
+
+func cborContentConfig() ClientContentConfig {
+	gvCopy := v1.SchemeGroupVersion
+	return ClientContentConfig{
+		AcceptContentTypes: "application/cbor,application/json",
+		ContentType:        "application/cbor",
+		GroupVersion:       gvCopy,
+		Negotiator:         runtime.NewClientNegotiator(cbor.NegotiatedSerializer(scheme.Codecs.WithoutConversion(), scheme.Scheme, scheme.Scheme), gvCopy),
+	}
+}
+
+func TestRequestCBOR(t *testing.T) {
+	serializer := scheme.Codecs.EncoderForVersion(cbor.NewSerializer(scheme.Scheme, scheme.Scheme), v1.SchemeGroupVersion)
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if e, a := "application/cbor,application/json", req.Header.Get("Accept"); e != a {
+			t.Errorf("expected Accept %q, got %q", e, a)
+		}
+		w.Header().Set("Content-Type", "application/cbor")
+		if req.URL.Query().Get("watch") != "true" {
+			if err := serializer.Encode(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "a"}}, w); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			return
+		}
+		framed := cbor.Framer.NewFrameWriter(w)
+		for _, name := range []string{"a", "b"} {
+			pod := &v1.Pod{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"}, ObjectMeta: metav1.ObjectMeta{Name: name}}
+			event := &metav1.WatchEvent{Type: string(watch.Added), Object: runtime.RawExtension{Object: pod}}
+			if err := serializer.Encode(event, framed); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}
+	}))
+	defer testServer.Close()
+	c := testRESTClientWithConfig(t, testServer, cborContentConfig())
+
+	obj, err := c.Get().Resource("pods").Name("a").Do(context.Background()).Get()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pod, ok := obj.(*v1.Pod); !ok || pod.Name != "a" {
+		t.Errorf("unexpected object: %#v", obj)
+	}
+
+	w, err := c.Get().Resource("pods").Param("watch", "true").Watch(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer w.Stop()
+	for _, name := range []string{"a", "b"} {
+		event, ok := <-w.ResultChan()
+		if !ok {
+			t.Fatalf("unexpected end of the watch")
+		}
+		if pod, ok := event.Object.(*v1.Pod); event.Type != watch.Added || !ok || pod.Name != name {
+			t.Errorf("unexpected event: %#v", event)
+		}
+	}
+}
+
+func TestRequestCBORFallback(t *testing.T) {
+	var contentTypes, accepts []string
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		contentTypes = append(contentTypes, req.Header.Get("Content-Type"))
+		accepts = append(accepts, req.Header.Get("Accept"))
+		if req.Header.Get("Content-Type") == "application/cbor" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.Copy(w, req.Body)
+	}))
+	defer testServer.Close()
+	c := testRESTClientWithConfig(t, testServer, cborContentConfig())
+
+	for i := 0; i < 2; i++ {
+		pod := &v1.Pod{}
+		err := c.Post().Resource("pods").Body(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "a"}}).Do(context.Background()).Into(pod)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if e, a := "a", pod.Name; e != a {
+			t.Errorf("expected %q, got %q", e, a)
+		}
+	}
+
+	// Only the first request is sent with CBOR, and retried with JSON.
+	if e, a := []string{"application/cbor", "application/json", "application/json"}, contentTypes; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected content types %v, got %v", e, a)
+	}
+	if e, a := []string{"application/cbor,application/json", "application/json", "application/json"}, accepts; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected accepted content types %v, got %v", e, a)
+	}
+}
+
+func TestRequestResponseCache(t *testing.T) {
+	var ifNoneMatch []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ifNoneMatch = append(ifNoneMatch, r.Header.Get("If-None-Match"))
+		w.Header().Set("ETag", `"1"`)
+		if r.Header.Get("If-None-Match") == `"1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", runtime.ContentTypeJSON)
+		w.Write([]byte(runtime.EncodeOrDie(scheme.Codecs.LegacyCodec(v1.SchemeGroupVersion), &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo"}})))
+	}))
+	defer srv.Close()
+
+	c, err := RESTClientFor(&Config{
+		Host: srv.URL,
+		ContentConfig: ContentConfig{
+			GroupVersion:         &v1.SchemeGroupVersion,
+			NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		},
+		BearerToken:   "token",
+		ResponseCache: transport.NewMemoryResponseCache(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		configMap := &v1.ConfigMap{}
+		if err := c.Get().Resource("configmaps").Name("foo").Do(context.Background()).Into(configMap); err != nil {
+			t.Fatal(err)
+		}
+		if configMap.Name != "foo" {
+			t.Errorf("expected configmap foo, got %q", configMap.Name)
+		}
+	}
+	if e, a := []string{"", `"1"`}, ifNoneMatch; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected If-None-Match headers %v, got %v", e, a)
+	}
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"context"
+	"net/http"
+)
+
+// Tracer starts the spans tracing the requests of a client. It lets callers
+// plug in the tracing library of their choice without this package depending
+// on it. k8s.io/client-go/rest/tracing implements it with OpenTelemetry.
+//
+// Every call of Do, DoRaw, Watch and Stream is traced by a span, with a child
+// span for every attempt made by the retry logic. The attributes of the spans
+// are listed by the SpanAttribute constants.
+type Tracer interface {
+	// Start starts a span with the given name, as a child of the span of ctx if
+	// there is one, and returns a context carrying the new span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+	// Inject writes the trace context of the span of ctx into the headers of a
+	// request to the server, normally as W3C traceparent and tracestate headers.
+	Inject(ctx context.Context, header http.Header)
+}
+
+// Span is a span started by a Tracer.
+type Span interface {
+	// SetAttributes sets attributes of the span.
+	SetAttributes(attributes ...Attribute)
+	// RecordError records an error which occurred during the span.
+	RecordError(err error)
+	// End ends the span. No other method is called after End.
+	End()
+}
+
+// Attribute is a key value pair describing a span.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// The keys of the attributes of the spans of a Request.
+const (
+	// SpanAttributeVerb is the verb of the request, as a string.
+	SpanAttributeVerb = "http.request.method"
+	// SpanAttributeResource is the resource of the request, as a string. It is
+	// only set if the request has a resource.
+	SpanAttributeResource = "k8s.resource"
+	// SpanAttributeNamespace is the namespace of the request, as a string. It
+	// is only set if the request has a namespace.
+	SpanAttributeNamespace = "k8s.namespace.name"
+	// SpanAttributeStatusCode is the status code of the response, as an int.
+	// It is only set if a response was received.
+	SpanAttributeStatusCode = "http.response.status_code"
+	// SpanAttributeRetryCount is the number of attempts preceding an attempt,
+	// or the number of retries made by a call, as an int.
+	SpanAttributeRetryCount = "http.request.resend_count"
+	// SpanAttributeThrottleWait is the time spent waiting for the client side
+	// rate limiter before an attempt, or before the first attempt of a call, as
+	// a time.Duration. It is only set if the request is rate limited.
+	SpanAttributeThrottleWait = "k8s.client.throttle_wait"
+)
+
+// spanKey is the context key of the Span of a Request.
+type spanKey struct{}
+
+// startSpan starts a span of r with the given name. It returns a nil Span if r
+// is not traced.
+func (r *Request) startSpan(ctx context.Context, name string) (context.Context, Span) {
+	if r.tracer == nil {
+		return ctx, nil
+	}
+	ctx, span := r.tracer.Start(ctx, name)
+	attributes := []Attribute{{Key: SpanAttributeVerb, Value: r.verb}}
+	if len(r.resource) > 0 {
+		attributes = append(attributes, Attribute{Key: SpanAttributeResource, Value: r.resource})
+	}
+	if len(r.namespace) > 0 {
+		attributes = append(attributes, Attribute{Key: SpanAttributeNamespace, Value: r.namespace})
+	}
+	span.SetAttributes(attributes...)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// spanFromContext returns the Span of a Request carried by ctx, if any.
+func spanFromContext(ctx context.Context) (Span, bool) {
+	span, ok := ctx.Value(spanKey{}).(Span)
+	return span, ok
+}
+
+// requestTrace traces a call of Do, DoRaw, Watch or Stream of a Request and its
+// attempts. A nil *requestTrace traces nothing.
+type requestTrace struct {
+	r    *Request
+	span Span
+	// attempt is the span of the current attempt, if it was not ended yet.
+	attempt Span
+	// attempts is the number of attempts started so far.
+	attempts int
+	// statusCode is the status code of the last response, if any.
+	statusCode int
+}
+
+// startTrace starts tracing the call of the given operation of r, if r is traced.
+func (r *Request) startTrace(ctx context.Context, operation string) (context.Context, *requestTrace) {
+	ctx, span := r.startSpan(ctx, "rest."+operation)
+	if span == nil {
+		return ctx, nil
+	}
+	return ctx, &requestTrace{r: r, span: span}
+}
+
+// startAttempt starts the span of the next attempt of the call, as a child of
+// the span of the call carried by ctx.
+func (t *requestTrace) startAttempt(ctx context.Context) context.Context {
+	if t == nil {
+		return ctx
+	}
+	t.endAttempt(nil)
+	ctx, t.attempt = t.r.startSpan(ctx, t.r.verb)
+	t.attempt.SetAttributes(Attribute{Key: SpanAttributeRetryCount, Value: t.attempts})
+	t.attempts++
+	return ctx
+}
+
+// attemptDone ends the span of the current attempt with its outcome.
+func (t *requestTrace) attemptDone(resp *http.Response, err error) {
+	if t == nil || t.attempt == nil {
+		return
+	}
+	if resp != nil {
+		t.statusCode = resp.StatusCode
+		t.attempt.SetAttributes(Attribute{Key: SpanAttributeStatusCode, Value: resp.StatusCode})
+	}
+	t.endAttempt(err)
+}
+
+func (t *requestTrace) endAttempt(err error) {
+	if t.attempt == nil {
+		return
+	}
+	if err != nil {
+		t.attempt.RecordError(err)
+	}
+	t.attempt.End()
+	t.attempt = nil
+}
+
+// end ends the span of the call, and of its current attempt if it was not
+// ended yet, with the error returned by the call.
+func (t *requestTrace) end(err error) {
+	if t == nil {
+		return
+	}
+	t.endAttempt(err)
+	retries := 0
+	if t.attempts > 1 {
+		retries = t.attempts - 1
+	}
+	attributes := []Attribute{{Key: SpanAttributeRetryCount, Value: retries}}
+	if t.statusCode != 0 {
+		attributes = append(attributes, Attribute{Key: SpanAttributeStatusCode, Value: t.statusCode})
+	}
+	t.span.SetAttributes(attributes...)
+	if err != nil {
+		t.span.RecordError(err)
+	}
+	t.span.End()
+}
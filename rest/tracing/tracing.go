@@ -0,0 +1,115 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tracing traces the requests of rest clients with OpenTelemetry, e.g.
+//
+//	config.Tracer = tracing.NewTracer(otel.GetTracerProvider())
+package tracing // import "k8s.io/client-go/rest/tracing"
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"k8s.io/client-go/rest"
+)
+
+// instrumentationName is the name of the OpenTelemetry tracer of the spans.
+const instrumentationName = "k8s.io/client-go/rest"
+
+// NewTracer returns a rest.Tracer which records the spans of the requests of
+// a client with the tracers of tracerProvider, and propagates their trace
+// context to the server in W3C traceparent and tracestate headers.
+func NewTracer(tracerProvider trace.TracerProvider) rest.Tracer {
+	return NewTracerWithPropagator(tracerProvider, propagation.TraceContext{})
+}
+
+// NewTracerWithPropagator is like NewTracer, but propagates the trace context
+// to the server with propagator, e.g. otel.GetTextMapPropagator().
+func NewTracerWithPropagator(tracerProvider trace.TracerProvider, propagator propagation.TextMapPropagator) rest.Tracer {
+	return &tracer{
+		tracer:     tracerProvider.Tracer(instrumentationName),
+		propagator: propagator,
+	}
+}
+
+type tracer struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+func (t *tracer) Start(ctx context.Context, name string) (context.Context, rest.Span) {
+	ctx, s := t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+	return ctx, span{s}
+}
+
+func (t *tracer) Inject(ctx context.Context, header http.Header) {
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+type span struct {
+	span trace.Span
+}
+
+func (s span) SetAttributes(attributes ...rest.Attribute) {
+	kvs := make([]attribute.KeyValue, 0, len(attributes))
+	for _, a := range attributes {
+		kvs = append(kvs, keyValue(a))
+		// Responses with error status codes fail client spans, as per the
+		// OpenTelemetry semantic conventions for HTTP.
+		if code, ok := a.Value.(int); ok && a.Key == rest.SpanAttributeStatusCode && code >= 400 {
+			s.span.SetStatus(codes.Error, http.StatusText(code))
+		}
+	}
+	s.span.SetAttributes(kvs...)
+}
+
+func (s span) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s span) End() {
+	s.span.End()
+}
+
+// keyValue converts a rest.Attribute to an OpenTelemetry attribute. Durations
+// are recorded in seconds.
+func keyValue(a rest.Attribute) attribute.KeyValue {
+	key := attribute.Key(a.Key)
+	switch v := a.Value.(type) {
+	case string:
+		return key.String(v)
+	case int:
+		return key.Int(v)
+	case int64:
+		return key.Int64(v)
+	case bool:
+		return key.Bool(v)
+	case float64:
+		return key.Float64(v)
+	case time.Duration:
+		return key.Float64(v.Seconds())
+	default:
+		return key.String(fmt.Sprint(v))
+	}
+}
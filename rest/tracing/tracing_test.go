@@ -0,0 +1,102 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+)
+
+func TestTracer(t *testing.T) {
+	var traceparents []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	c, err := rest.RESTClientFor(&rest.Config{
+		Host:    srv.URL,
+		APIPath: "/api",
+		ContentConfig: rest.ContentConfig{
+			GroupVersion:         &v1.SchemeGroupVersion,
+			NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		},
+		Tracer: NewTracer(provider),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, caller := provider.Tracer("test").Start(context.Background(), "caller")
+	if err := c.Get().Namespace("ns").Resource("pods").Name("foo").Do(ctx).Error(); err == nil {
+		t.Fatal("expected an error")
+	}
+	caller.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+	attempt, call := spans[0], spans[1]
+	if e, a := "rest.Do", call.Name(); e != a {
+		t.Errorf("expected the call span to be named %q, got %q", e, a)
+	}
+	if call.Parent().SpanID() != caller.SpanContext().SpanID() {
+		t.Errorf("expected the call span to be a child of the caller's span")
+	}
+	if attempt.Parent().SpanID() != call.SpanContext().SpanID() {
+		t.Errorf("expected the attempt span to be a child of the call span")
+	}
+	attributes := map[attribute.Key]attribute.Value{}
+	for _, kv := range attempt.Attributes() {
+		attributes[kv.Key] = kv.Value
+	}
+	for key, expected := range map[string]attribute.Value{
+		rest.SpanAttributeVerb:       attribute.StringValue("GET"),
+		rest.SpanAttributeResource:   attribute.StringValue("pods"),
+		rest.SpanAttributeNamespace:  attribute.StringValue("ns"),
+		rest.SpanAttributeStatusCode: attribute.IntValue(http.StatusNotFound),
+		rest.SpanAttributeRetryCount: attribute.IntValue(0),
+	} {
+		if actual := attributes[attribute.Key(key)]; actual != expected {
+			t.Errorf("expected attribute %s to be %v, got %v", key, expected.Emit(), actual.Emit())
+		}
+	}
+	if e, a := codes.Error, call.Status().Code; e != a {
+		t.Errorf("expected the call span to have status %v, got %v", e, a)
+	}
+
+	// The server sees the trace context of the attempt.
+	expected := "00-" + attempt.SpanContext().TraceID().String() + "-" + attempt.SpanContext().SpanID().String() + "-01"
+	if len(traceparents) != 1 || traceparents[0] != expected {
+		t.Errorf("expected traceparent %q, got %v", expected, traceparents)
+	}
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+type recordingSpan struct {
+	name       string
+	id         int
+	parent     *recordingSpan
+	attributes map[string]interface{}
+	errs       []error
+	ended      bool
+}
+
+func (s *recordingSpan) SetAttributes(attributes ...Attribute) {
+	for _, attribute := range attributes {
+		s.attributes[attribute.Key] = attribute.Value
+	}
+}
+
+func (s *recordingSpan) RecordError(err error) {
+	s.errs = append(s.errs, err)
+}
+
+func (s *recordingSpan) End() {
+	s.ended = true
+}
+
+type recordingSpanKey struct{}
+
+type recordingTracer struct {
+	lock  sync.Mutex
+	spans []*recordingSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	parent, _ := ctx.Value(recordingSpanKey{}).(*recordingSpan)
+	span := &recordingSpan{
+		name:       name,
+		id:         len(t.spans) + 1,
+		parent:     parent,
+		attributes: map[string]interface{}{},
+	}
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, recordingSpanKey{}, span), span
+}
+
+func (t *recordingTracer) Inject(ctx context.Context, header http.Header) {
+	if span, ok := ctx.Value(recordingSpanKey{}).(*recordingSpan); ok {
+		header.Set("traceparent", strconv.Itoa(span.id))
+	}
+}
+
+func TestRequestTracing(t *testing.T) {
+	var lock sync.Mutex
+	var traceparents []string
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		traceparents = append(traceparents, req.Header.Get("traceparent"))
+		if len(traceparents) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+
+	tracer := &recordingTracer{}
+	c := testRESTClient(t, testServer)
+	c.tracer = tracer
+	c.rateLimiter = flowcontrol.NewFakeAlwaysRateLimiter()
+
+	ctx, caller := tracer.Start(context.Background(), "caller")
+	r := c.Get().Namespace("ns").Resource("pods").SetHeader("Foo", "bar")
+	if result := r.Do(ctx); result.Error() != nil {
+		t.Fatalf("unexpected error: %v", result.Error())
+	}
+
+	if len(tracer.spans) != 4 {
+		t.Fatalf("expected 4 spans, got %d", len(tracer.spans))
+	}
+	call, first, second := tracer.spans[1], tracer.spans[2], tracer.spans[3]
+	if call.name != "rest.Do" || call.parent != caller {
+		t.Errorf("expected span rest.Do as child of the caller span, got %s with parent %v", call.name, call.parent)
+	}
+	for i, attempt := range []*recordingSpan{first, second} {
+		if attempt.name != "GET" || attempt.parent != call {
+			t.Errorf("expected attempt %d to be a span GET as child of the call span, got %s with parent %v", i, attempt.name, attempt.parent)
+		}
+	}
+	for _, span := range tracer.spans[1:] {
+		if !span.ended {
+			t.Errorf("expected span %d to be ended", span.id)
+		}
+		for key, value := range map[string]interface{}{
+			SpanAttributeVerb:      "GET",
+			SpanAttributeResource:  "pods",
+			SpanAttributeNamespace: "ns",
+		} {
+			if span.attributes[key] != value {
+				t.Errorf("expected attribute %s of span %d to be %v, got %v", key, span.id, value, span.attributes[key])
+			}
+		}
+		if _, ok := span.attributes[SpanAttributeThrottleWait]; ok == (span == first) {
+			t.Errorf("unexpected throttle wait attribute of span %d: %v", span.id, span.attributes[SpanAttributeThrottleWait])
+		}
+	}
+	for span, expected := range map[*recordingSpan][2]int{
+		call:   {http.StatusOK, 1},
+		first:  {http.StatusTooManyRequests, 0},
+		second: {http.StatusOK, 1},
+	} {
+		if e, a := expected[0], span.attributes[SpanAttributeStatusCode]; e != a {
+			t.Errorf("expected status code %v of span %d, got %v", e, span.id, a)
+		}
+		if e, a := expected[1], span.attributes[SpanAttributeRetryCount]; e != a {
+			t.Errorf("expected retry count %v of span %d, got %v", e, span.id, a)
+		}
+	}
+
+	if e, a := []string{strconv.Itoa(first.id), strconv.Itoa(second.id)}, traceparents; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected trace context headers %v, got %v", e, a)
+	}
+	if _, ok := r.headers["Traceparent"]; ok {
+		t.Errorf("expected the trace context not to be added to the headers of the request")
+	}
+}
+
+func TestRequestTracingWatch(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer testServer.Close()
+
+	tracer := &recordingTracer{}
+	c := testRESTClient(t, testServer)
+	c.tracer = tracer
+
+	if _, err := c.Get().Resource("pods").Watch(context.Background()); err == nil {
+		t.Fatalf("expected an error")
+	}
+
+	if len(tracer.spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(tracer.spans))
+	}
+	call, attempt := tracer.spans[0], tracer.spans[1]
+	if call.name != "rest.Watch" || call.parent != nil || attempt.parent != call {
+		t.Errorf("unexpected spans %s and %s", call.name, attempt.name)
+	}
+	if len(call.errs) != 1 || !call.ended || !attempt.ended {
+		t.Errorf("expected the error to be recorded by the ended call span, got %v", call.errs)
+	}
+	if e, a := http.StatusForbidden, attempt.attributes[SpanAttributeStatusCode]; e != a {
+		t.Errorf("expected status code %v, got %v", e, a)
+	}
+}
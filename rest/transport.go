@@ -60,12 +60,18 @@ func TLSConfigFor(config *Config) (*tls.Config, error) {
 // TransportFor returns an http.RoundTripper that will provide the authentication
 // or transport level security defined by the provided Config. Will return the
 // default http.DefaultTransport if no special case behavior is needed.
+// If the Config lists Endpoints, the http.RoundTripper spreads the requests
+// across them and Host, see EndpointSelection.
 func TransportFor(config *Config) (http.RoundTripper, error) {
 	cfg, err := config.TransportConfig()
 	if err != nil {
 		return nil, err
 	}
-	return transport.New(cfg)
+	rt, err := transport.New(cfg)
+	if err != nil || len(config.Endpoints) == 0 {
+		return rt, err
+	}
+	return newEndpointRoundTripper(config, rt)
 }
 
 // HTTPWrappersForConfig wraps a round tripper with any relevant layered behavior from the
@@ -108,7 +114,8 @@ func (c *Config) TransportConfig() (*transport.Config, error) {
 			Groups:   c.Impersonate.Groups,
 			Extra:    c.Impersonate.Extra,
 		},
-		Proxy: c.Proxy,
+		Proxy:         c.Proxy,
+		ResponseCache: c.ResponseCache,
 	}
 
 	if c.Dial != nil {
@@ -80,18 +80,22 @@ func DefaultVersionedAPIPath(apiPath string, groupVersion schema.GroupVersion) s
 // DefaultServerUrlFor is shared between IsConfigTransportTLS and RESTClientFor. It
 // requires Host and Version to be set prior to being called.
 func DefaultServerUrlFor(config *Config) (*url.URL, string, error) {
-	// TODO: move the default to secure when the apiserver supports TLS by default
-	// config.Insecure is taken to mean "I want HTTPS but don't bother checking the certs against a CA."
-	hasCA := len(config.CAFile) != 0 || len(config.CAData) != 0
-	hasCert := len(config.CertFile) != 0 || len(config.CertData) != 0
-	defaultTLS := hasCA || hasCert || config.Insecure
 	host := config.Host
 	if host == "" {
 		host = "localhost"
 	}
 
 	if config.GroupVersion != nil {
-		return DefaultServerURL(host, config.APIPath, *config.GroupVersion, defaultTLS)
+		return DefaultServerURL(host, config.APIPath, *config.GroupVersion, defaultTLS(config))
 	}
-	return DefaultServerURL(host, config.APIPath, schema.GroupVersion{}, defaultTLS)
+	return DefaultServerURL(host, config.APIPath, schema.GroupVersion{}, defaultTLS(config))
+}
+
+// defaultTLS returns whether hosts of config without a scheme are accessed with TLS.
+func defaultTLS(config *Config) bool {
+	// TODO: move the default to secure when the apiserver supports TLS by default
+	// config.Insecure is taken to mean "I want HTTPS but don't bother checking the certs against a CA."
+	hasCA := len(config.CAFile) != 0 || len(config.CAData) != 0
+	hasCert := len(config.CertFile) != 0 || len(config.CertData) != 0
+	return hasCA || hasCert || config.Insecure
 }
@@ -0,0 +1,189 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/metrics"
+	"k8s.io/utils/clock"
+)
+
+// WarningAggregatorOptions controls the behavior of a WarningAggregator constructed using NewWarningAggregator()
+type WarningAggregatorOptions struct {
+	// Delegate is passed the warnings which are not suppressed by LogInterval.
+	// If nil, warnings are only counted.
+	Delegate WarningHandler
+	// LogInterval is the minimum time between two warnings with the same message, code and resource
+	// being passed to Delegate. Zero passes every warning to Delegate.
+	LogInterval time.Duration
+	// Clock is used to rate limit warnings passed to Delegate. Defaults to the real clock.
+	Clock clock.PassiveClock
+}
+
+// WarningSummary is the number of times a warning was returned for a given resource.
+type WarningSummary struct {
+	// Message is the text of the warning.
+	Message string
+	// Code is the warn code of the warning.
+	Code int
+	// Resource is the group, version and resource of the requests the warning was returned for.
+	// It is empty if the warning was handled without request information.
+	Resource schema.GroupVersionResource
+	// Count is the number of times the warning was returned.
+	Count int
+}
+
+type warningKey struct {
+	message  string
+	code     int
+	resource schema.GroupVersionResource
+}
+
+// WarningAggregator is an implementation of WarningHandler which counts warnings by message, code and
+// resource, optionally rate limits the warnings passed on to another handler, and counts warnings in
+// the metrics.Warnings metric.
+// Every distinct warning is retained, so memory use grows with the number of distinct warnings handled.
+type WarningAggregator struct {
+	opts WarningAggregatorOptions
+
+	// lock guards counts and lastDelegated
+	lock          sync.Mutex
+	counts        map[warningKey]int
+	lastDelegated map[warningKey]time.Time
+}
+
+// NewWarningAggregator returns a WarningAggregator configured with the specified options.
+// A CLI can install it with SetDefaultWarningHandler() and call WriteSummary() on exit.
+func NewWarningAggregator(opts WarningAggregatorOptions) *WarningAggregator {
+	if opts.Clock == nil {
+		opts.Clock = clock.RealClock{}
+	}
+	return &WarningAggregator{
+		opts:          opts,
+		counts:        map[warningKey]int{},
+		lastDelegated: map[warningKey]time.Time{},
+	}
+}
+
+// HandleWarningHeader counts a warning which was returned for an unknown resource.
+func (a *WarningAggregator) HandleWarningHeader(code int, agent string, message string) {
+	a.HandleRequestWarningHeader(schema.GroupVersionResource{}, code, agent, message)
+}
+
+// HandleRequestWarningHeader counts a warning which was returned for the given resource.
+func (a *WarningAggregator) HandleRequestWarningHeader(gvr schema.GroupVersionResource, code int, agent string, message string) {
+	if len(message) == 0 {
+		return
+	}
+	metrics.Warnings.Increment(strconv.Itoa(code), gvr.Group, gvr.Version, gvr.Resource)
+
+	if !a.record(warningKey{message: message, code: code, resource: gvr}) {
+		return
+	}
+	switch delegate := a.opts.Delegate.(type) {
+	case nil:
+	case RequestWarningHandler:
+		delegate.HandleRequestWarningHeader(gvr, code, agent, message)
+	default:
+		delegate.HandleWarningHeader(code, agent, message)
+	}
+}
+
+// record counts the warning and returns whether it should be passed to the delegate.
+func (a *WarningAggregator) record(key warningKey) bool {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.counts[key]++
+	if a.opts.Delegate == nil {
+		return false
+	}
+	if a.opts.LogInterval <= 0 {
+		return true
+	}
+	now := a.opts.Clock.Now()
+	if last, ok := a.lastDelegated[key]; ok && now.Sub(last) < a.opts.LogInterval {
+		return false
+	}
+	a.lastDelegated[key] = now
+	return true
+}
+
+// Summary returns the counts of the warnings handled so far, ordered by
+// decreasing count, then by message and resource.
+func (a *WarningAggregator) Summary() []WarningSummary {
+	a.lock.Lock()
+	summary := make([]WarningSummary, 0, len(a.counts))
+	for key, count := range a.counts {
+		summary = append(summary, WarningSummary{
+			Message:  key.message,
+			Code:     key.code,
+			Resource: key.resource,
+			Count:    count,
+		})
+	}
+	a.lock.Unlock()
+
+	sort.Slice(summary, func(i, j int) bool {
+		if summary[i].Count != summary[j].Count {
+			return summary[i].Count > summary[j].Count
+		}
+		if summary[i].Message != summary[j].Message {
+			return summary[i].Message < summary[j].Message
+		}
+		if summary[i].Code != summary[j].Code {
+			return summary[i].Code < summary[j].Code
+		}
+		return summary[i].Resource.String() < summary[j].Resource.String()
+	})
+	return summary
+}
+
+// WriteSummary writes a report of the code 299 warnings handled so far to
+// the specified writer. Nothing is written if no such warning was handled.
+func (a *WarningAggregator) WriteSummary(out io.Writer) error {
+	var lines []string
+	for _, s := range a.Summary() {
+		if s.Code != 299 {
+			continue
+		}
+		resource := "unknown resource"
+		if len(s.Resource.Resource) > 0 {
+			resource = s.Resource.GroupVersion().String() + " " + s.Resource.Resource
+		}
+		lines = append(lines, fmt.Sprintf("  %d x %s: %s\n", s.Count, resource, s.Message))
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintf(out, "Warning summary:\n"); err != nil {
+		return err
+	}
+	for _, line := range lines {
+		if _, err := io.WriteString(out, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rest
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/metrics"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+type fakeWarningMetric struct {
+	calls [][]string
+}
+
+func (f *fakeWarningMetric) Increment(code, group, version, resource string) {
+	f.calls = append(f.calls, []string{code, group, version, resource})
+}
+
+type recordingWarningHandler struct {
+	messages []string
+}
+
+func (r *recordingWarningHandler) HandleWarningHeader(code int, agent string, message string) {
+	r.messages = append(r.messages, message)
+}
+
+func TestWarningAggregator(t *testing.T) {
+	fakeMetric := &fakeWarningMetric{}
+	originalMetric := metrics.Warnings
+	metrics.Warnings = fakeMetric
+	defer func() { metrics.Warnings = originalMetric }()
+
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	delegate := &recordingWarningHandler{}
+	a := NewWarningAggregator(WarningAggregatorOptions{
+		Delegate:    delegate,
+		LogInterval: time.Minute,
+		Clock:       fakeClock,
+	})
+	deployments := schema.GroupVersionResource{Group: "extensions", Version: "v1beta1", Resource: "deployments"}
+
+	a.HandleRequestWarningHeader(deployments, 299, "", "deprecated")
+	a.HandleRequestWarningHeader(deployments, 299, "", "deprecated")
+	a.HandleWarningHeader(299, "", "deprecated")
+	a.HandleWarningHeader(299, "", "other")
+	a.HandleWarningHeader(299, "", "")
+	fakeClock.Step(time.Minute)
+	a.HandleRequestWarningHeader(deployments, 299, "", "deprecated")
+
+	// The warnings are rate limited per message, code and resource.
+	if e, a := []string{"deprecated", "deprecated", "other", "deprecated"}, delegate.messages; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected delegated warnings %v, got %v", e, a)
+	}
+	expectedSummary := []WarningSummary{
+		{Message: "deprecated", Code: 299, Resource: deployments, Count: 3},
+		{Message: "deprecated", Code: 299, Count: 1},
+		{Message: "other", Code: 299, Count: 1},
+	}
+	if summary := a.Summary(); !reflect.DeepEqual(expectedSummary, summary) {
+		t.Errorf("expected summary %#v, got %#v", expectedSummary, summary)
+	}
+	if len(fakeMetric.calls) != 5 {
+		t.Errorf("expected 5 metric increments, got %v", fakeMetric.calls)
+	}
+	if e, a := []string{"299", "extensions", "v1beta1", "deployments"}, fakeMetric.calls[0]; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected metric labels %v, got %v", e, a)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := a.WriteSummary(buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := "Warning summary:\n" +
+		"  3 x extensions/v1beta1 deployments: deprecated\n" +
+		"  1 x unknown resource: deprecated\n" +
+		"  1 x unknown resource: other\n"
+	if buf.String() != expected {
+		t.Errorf("expected summary output %q, got %q", expected, buf.String())
+	}
+}
+
+func TestWarningAggregatorWithoutDelegate(t *testing.T) {
+	a := NewWarningAggregator(WarningAggregatorOptions{})
+	a.HandleWarningHeader(214, "", "transformed")
+
+	buf := &bytes.Buffer{}
+	if err := a.WriteSummary(buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no summary output for non-299 warnings, got %q", buf.String())
+	}
+	if e, a := []WarningSummary{{Message: "transformed", Code: 214, Count: 1}}, a.Summary(); !reflect.DeepEqual(e, a) {
+		t.Errorf("expected summary %#v, got %#v", e, a)
+	}
+}
+
+func TestRequestWarningAggregator(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Warning", `299 - "configmaps are deprecated"`)
+		w.Header().Set("Content-Type", runtime.ContentTypeJSON)
+		w.Write([]byte(runtime.EncodeOrDie(scheme.Codecs.LegacyCodec(v1.SchemeGroupVersion), &v1.ConfigMap{})))
+	}))
+	defer srv.Close()
+
+	aggregator := NewWarningAggregator(WarningAggregatorOptions{})
+	c, err := RESTClientFor(&Config{
+		// The resource is the one of the request, whatever the base path.
+		Host:    srv.URL + "/apis/proxy",
+		APIPath: "/api",
+		ContentConfig: ContentConfig{
+			GroupVersion:         &v1.SchemeGroupVersion,
+			NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		},
+		WarningHandler: aggregator,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := c.Get().Namespace("ns").Resource("configmaps").Name("foo").Do(context.Background()).Error(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := []WarningSummary{{
+		Message:  "configmaps are deprecated",
+		Code:     299,
+		Resource: schema.GroupVersionResource{Version: "v1", Resource: "configmaps"},
+		Count:    2,
+	}}
+	if summary := aggregator.Summary(); !reflect.DeepEqual(expected, summary) {
+		t.Errorf("expected summary %#v, got %#v", expected, summary)
+	}
+}
@@ -24,6 +24,7 @@ import (
 
 	"k8s.io/klog/v2"
 
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/net"
 )
 
@@ -33,6 +34,18 @@ type WarningHandler interface {
 	HandleWarningHeader(code int, agent string, text string)
 }
 
+// RequestWarningHandler is an optional interface a WarningHandler can implement
+// to be told which resource the warning was returned for. When a handler
+// implements it, HandleRequestWarningHeader is called instead of HandleWarningHeader.
+type RequestWarningHandler interface {
+	WarningHandler
+	// HandleRequestWarningHeader is called with the group, version and resource of the request
+	// and the warn code, agent, and text when a warning header is encountered.
+	// The group and version are those of the client which made the request, and the resource
+	// is empty if the request did not address one, e.g. for AbsPath requests.
+	HandleRequestWarningHeader(gvr schema.GroupVersionResource, code int, agent string, text string)
+}
+
 var (
 	defaultWarningHandler     WarningHandler = WarningLogger{}
 	defaultWarningHandlerLock sync.RWMutex
@@ -134,14 +147,25 @@ func (w *warningWriter) WarningCount() int {
 	return w.writtenCount
 }
 
-func handleWarnings(headers http.Header, handler WarningHandler) []net.WarningHeader {
+func handleWarnings(headers http.Header, handler WarningHandler, groupVersion schema.GroupVersion, resource string) []net.WarningHeader {
 	if handler == nil {
 		handler = getDefaultWarningHandler()
 	}
 
 	warnings, _ := net.ParseWarningHeaders(headers["Warning"])
+	if len(warnings) == 0 {
+		return warnings
+	}
+	requestHandler, ok := handler.(RequestWarningHandler)
+	if !ok {
+		for _, warning := range warnings {
+			handler.HandleWarningHeader(warning.Code, warning.Agent, warning.Text)
+		}
+		return warnings
+	}
+	gvr := groupVersion.WithResource(resource)
 	for _, warning := range warnings {
-		handler.HandleWarningHeader(warning.Code, warning.Agent, warning.Text)
+		requestHandler.HandleRequestWarningHeader(gvr, warning.Code, warning.Agent, warning.Text)
 	}
 	return warnings
 }
@@ -256,6 +256,8 @@ func (r *withRetry) After(ctx context.Context, request *Request, resp *http.Resp
 		updateRequestRetryMetric(ctx, request, resp, err)
 	}
 
+	request.observeServerFeedback(ctx, resp)
+
 	if request.c.base != nil {
 		if err != nil {
 			request.backoff.UpdateBackoff(request.URL(), err, 0)
@@ -0,0 +1,294 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/pager"
+	"k8s.io/klog/v2"
+)
+
+const defaultConsistencyCheckPeriod = 10 * time.Minute
+
+// ConsistencyCheckOptions configures the optional consistency checker of a
+// sharedIndexInformer. See SharedIndexInformerOptions.ConsistencyCheck.
+type ConsistencyCheckOptions struct {
+	// Period is the time between two checks. The first check happens one
+	// period after the informer is started. Defaults to ten minutes.
+	Period time.Duration
+
+	// Equal reports whether an object in the informer's store matches the
+	// object returned by the server. The server's object has already been
+	// passed through the informer's TransformFunc, if any. Defaults to
+	// comparing UID and ResourceVersion.
+	Equal func(stored, listed interface{}) bool
+
+	// OnInconsistency, if set, is called with the result of every check
+	// that found a difference between the store and the server.
+	OnInconsistency func(result ConsistencyCheckResult)
+}
+
+// ConsistencyCheckResult describes how an informer's store differed from
+// a consistent LIST issued at the store's resource version.
+type ConsistencyCheckResult struct {
+	// ResourceVersion is the resource version the LIST was issued at.
+	ResourceVersion string
+	// Missing holds the keys of the objects returned by the server that
+	// were not in the store.
+	Missing []string
+	// Extra holds the keys of the objects in the store that were not
+	// returned by the server.
+	Extra []string
+	// Mismatched holds the keys of the objects for which the store and
+	// the server disagree.
+	Mismatched []string
+}
+
+// Consistent returns true if the store matched the server.
+func (r ConsistencyCheckResult) Consistent() bool {
+	return len(r.Missing) == 0 && len(r.Extra) == 0 && len(r.Mismatched) == 0
+}
+
+// ConsistencyCheckMetricsProvider generates the metrics reported by
+// informer consistency checkers.
+type ConsistencyCheckMetricsProvider interface {
+	NewChecksMetric(name string) CounterMetric
+	NewInconsistentChecksMetric(name string) CounterMetric
+	NewMissingObjectsMetric(name string) GaugeMetric
+	NewExtraObjectsMetric(name string) GaugeMetric
+	NewMismatchedObjectsMetric(name string) GaugeMetric
+}
+
+type noopConsistencyCheckMetricsProvider struct{}
+
+func (noopConsistencyCheckMetricsProvider) NewChecksMetric(name string) CounterMetric {
+	return noopMetric{}
+}
+func (noopConsistencyCheckMetricsProvider) NewInconsistentChecksMetric(name string) CounterMetric {
+	return noopMetric{}
+}
+func (noopConsistencyCheckMetricsProvider) NewMissingObjectsMetric(name string) GaugeMetric {
+	return noopMetric{}
+}
+func (noopConsistencyCheckMetricsProvider) NewExtraObjectsMetric(name string) GaugeMetric {
+	return noopMetric{}
+}
+func (noopConsistencyCheckMetricsProvider) NewMismatchedObjectsMetric(name string) GaugeMetric {
+	return noopMetric{}
+}
+
+var consistencyCheckMetricsFactory = struct {
+	metricsProvider ConsistencyCheckMetricsProvider
+	setProviders    sync.Once
+}{
+	metricsProvider: noopConsistencyCheckMetricsProvider{},
+}
+
+// SetConsistencyCheckMetricsProvider sets the metrics provider of the
+// informer consistency checkers. Only the first call has an effect.
+func SetConsistencyCheckMetricsProvider(metricsProvider ConsistencyCheckMetricsProvider) {
+	consistencyCheckMetricsFactory.setProviders.Do(func() {
+		consistencyCheckMetricsFactory.metricsProvider = metricsProvider
+	})
+}
+
+type consistencyCheckMetrics struct {
+	checks             CounterMetric
+	inconsistentChecks CounterMetric
+	missingObjects     GaugeMetric
+	extraObjects       GaugeMetric
+	mismatchedObjects  GaugeMetric
+}
+
+func newConsistencyCheckMetrics(name string) *consistencyCheckMetrics {
+	provider := consistencyCheckMetricsFactory.metricsProvider
+	return &consistencyCheckMetrics{
+		checks:             provider.NewChecksMetric(name),
+		inconsistentChecks: provider.NewInconsistentChecksMetric(name),
+		missingObjects:     provider.NewMissingObjectsMetric(name),
+		extraObjects:       provider.NewExtraObjectsMetric(name),
+		mismatchedObjects:  provider.NewMismatchedObjectsMetric(name),
+	}
+}
+
+// consistencyChecker periodically compares the store of a
+// sharedIndexInformer with a consistent LIST from the server.
+type consistencyChecker struct {
+	informer *sharedIndexInformer
+	name     string
+	period   time.Duration
+	equal    func(stored, listed interface{}) bool
+	report   func(result ConsistencyCheckResult)
+	metrics  *consistencyCheckMetrics
+}
+
+func newConsistencyChecker(informer *sharedIndexInformer, name string, options ConsistencyCheckOptions) *consistencyChecker {
+	c := &consistencyChecker{
+		informer: informer,
+		name:     name,
+		period:   options.Period,
+		equal:    options.Equal,
+		report:   options.OnInconsistency,
+		metrics:  newConsistencyCheckMetrics(name),
+	}
+	if c.period <= 0 {
+		c.period = defaultConsistencyCheckPeriod
+	}
+	if c.equal == nil {
+		c.equal = equalUIDAndResourceVersion
+	}
+	return c
+}
+
+func equalUIDAndResourceVersion(stored, listed interface{}) bool {
+	storedMeta, err := meta.Accessor(stored)
+	if err != nil {
+		return false
+	}
+	listedMeta, err := meta.Accessor(listed)
+	if err != nil {
+		return false
+	}
+	return storedMeta.GetUID() == listedMeta.GetUID() && storedMeta.GetResourceVersion() == listedMeta.GetResourceVersion()
+}
+
+func (c *consistencyChecker) run(stopCh <-chan struct{}) {
+	ctx := wait.ContextForChannel(stopCh)
+	for {
+		timer := c.informer.clock.NewTimer(c.period)
+		select {
+		case <-stopCh:
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+		if _, err := c.check(ctx); err != nil {
+			klog.V(2).Infof("%s: skipped consistency check: %v", c.name, err)
+		}
+	}
+}
+
+// check runs a single consistency check. It returns an error if the check
+// could not be performed, which is usually transient.
+func (c *consistencyChecker) check(ctx context.Context) (ConsistencyCheckResult, error) {
+	resourceVersion, stored, err := c.informer.quiescentSnapshot()
+	if err != nil {
+		return ConsistencyCheckResult{}, err
+	}
+
+	lw := c.informer.getListerWatcher()
+	p := pager.New(pager.SimplePageFunc(func(opts metav1.ListOptions) (runtime.Object, error) {
+		return lw.List(opts)
+	}))
+	list, _, err := p.List(ctx, metav1.ListOptions{
+		ResourceVersion:      resourceVersion,
+		ResourceVersionMatch: metav1.ResourceVersionMatchExact,
+	})
+	if err != nil {
+		return ConsistencyCheckResult{}, fmt.Errorf("failed to list at resource version %q: %w", resourceVersion, err)
+	}
+	items, err := meta.ExtractListWithAlloc(list)
+	if err != nil {
+		return ConsistencyCheckResult{}, fmt.Errorf("unable to understand list result %#v: %w", list, err)
+	}
+
+	result := ConsistencyCheckResult{ResourceVersion: resourceVersion}
+	listedKeys := make(map[string]bool, len(items))
+	for _, item := range items {
+		var obj interface{} = item
+		if c.informer.transform != nil {
+			if obj, err = c.informer.transform(obj); err != nil {
+				return ConsistencyCheckResult{}, err
+			}
+		}
+		key, err := DeletionHandlingMetaNamespaceKeyFunc(obj)
+		if err != nil {
+			return ConsistencyCheckResult{}, err
+		}
+		listedKeys[key] = true
+
+		storedObj, exists, err := stored.GetByKey(key)
+		switch {
+		case err != nil:
+			return ConsistencyCheckResult{}, err
+		case !exists:
+			result.Missing = append(result.Missing, key)
+		case newerThan(storedObj, resourceVersion):
+			return ConsistencyCheckResult{}, fmt.Errorf("store is ahead of resource version %q", resourceVersion)
+		case !c.equal(storedObj, obj):
+			result.Mismatched = append(result.Mismatched, key)
+		}
+	}
+	for _, key := range stored.ListKeys() {
+		if listedKeys[key] {
+			continue
+		}
+		if storedObj, exists, _ := stored.GetByKey(key); exists && newerThan(storedObj, resourceVersion) {
+			return ConsistencyCheckResult{}, fmt.Errorf("store is ahead of resource version %q", resourceVersion)
+		}
+		result.Extra = append(result.Extra, key)
+	}
+	sort.Strings(result.Missing)
+	sort.Strings(result.Extra)
+	sort.Strings(result.Mismatched)
+
+	c.metrics.checks.Inc()
+	c.metrics.missingObjects.Set(float64(len(result.Missing)))
+	c.metrics.extraObjects.Set(float64(len(result.Extra)))
+	c.metrics.mismatchedObjects.Set(float64(len(result.Mismatched)))
+	if result.Consistent() {
+		klog.V(4).Infof("%s: store is consistent with the server at resource version %q", c.name, resourceVersion)
+		return result, nil
+	}
+
+	c.metrics.inconsistentChecks.Inc()
+	klog.Warningf("%s: store is inconsistent with the server at resource version %q: missing %v, extra %v, mismatched %v",
+		c.name, resourceVersion, result.Missing, result.Extra, result.Mismatched)
+	if c.report != nil {
+		c.report(result)
+	}
+	return result, nil
+}
+
+// newerThan returns true if obj has a resource version that is known to be
+// more recent than resourceVersion. Resource versions are opaque in general,
+// so this only returns true if both can be parsed as integers.
+func newerThan(obj interface{}, resourceVersion string) bool {
+	objMeta, err := meta.Accessor(obj)
+	if err != nil {
+		return false
+	}
+	objRV, err := strconv.ParseUint(objMeta.GetResourceVersion(), 10, 64)
+	if err != nil {
+		return false
+	}
+	rv, err := strconv.ParseUint(resourceVersion, 10, 64)
+	if err != nil {
+		return false
+	}
+	return objRV > rv
+}
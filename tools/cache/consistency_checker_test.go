@@ -0,0 +1,141 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	fcache "k8s.io/client-go/tools/cache/testing"
+)
+
+func TestConsistencyChecker(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns", UID: "uid1"}})
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod2", Namespace: "ns", UID: "uid2"}})
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod3", Namespace: "ns", UID: "uid3"}})
+
+	var reported []ConsistencyCheckResult
+	informer := NewSharedIndexInformerWithOptions(source, &v1.Pod{}, SharedIndexInformerOptions{
+		ConsistencyCheck: &ConsistencyCheckOptions{
+			Period: time.Hour,
+			OnInconsistency: func(result ConsistencyCheckResult) {
+				reported = append(reported, result)
+			},
+		},
+	}).(*sharedIndexInformer)
+
+	if _, err := informer.consistencyChecker.check(context.Background()); err == nil {
+		t.Errorf("expected the check to fail before the informer is synced")
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	if !WaitForCacheSync(stop, informer.HasSynced) {
+		t.Fatal("cache did not sync")
+	}
+
+	result, err := informer.consistencyChecker.check(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Consistent() || result.ResourceVersion != "3" {
+		t.Errorf("expected a consistent result at resource version 3, got %+v", result)
+	}
+	if len(reported) != 0 {
+		t.Errorf("expected no reports, got %v", reported)
+	}
+
+	// Corrupt the store behind the informer's back.
+	indexer := informer.GetIndexer()
+	indexer.Delete(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns"}})
+	indexer.Update(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod3", Namespace: "ns", UID: "other", ResourceVersion: "3"}})
+	indexer.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod4", Namespace: "ns", UID: "uid4", ResourceVersion: "1"}})
+
+	result, err = informer.consistencyChecker.check(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := ConsistencyCheckResult{
+		ResourceVersion: "3",
+		Missing:         []string{"ns/pod1"},
+		Extra:           []string{"ns/pod4"},
+		Mismatched:      []string{"ns/pod3"},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %+v, got %+v", expected, result)
+	}
+	if len(reported) != 1 || !reflect.DeepEqual(reported[0], expected) {
+		t.Errorf("expected a single report of %+v, got %+v", expected, reported)
+	}
+
+	// A store holding objects newer than the listed resource version
+	// cannot be compared.
+	indexer.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod5", Namespace: "ns", UID: "uid5", ResourceVersion: "10"}})
+	if _, err := informer.consistencyChecker.check(context.Background()); err == nil {
+		t.Errorf("expected the check to be skipped when the store is ahead of the server")
+	}
+}
+
+func TestConsistencyCheckerResourceVersionOfAppliedDeltas(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns", UID: "uid1"}})
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod2", Namespace: "ns", UID: "uid2"}})
+
+	informer := NewSharedIndexInformerWithOptions(source, &v1.Pod{}, SharedIndexInformerOptions{
+		ConsistencyCheck: &ConsistencyCheckOptions{Period: time.Hour},
+	}).(*sharedIndexInformer)
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	if !WaitForCacheSync(stop, informer.HasSynced) {
+		t.Fatal("cache did not sync")
+	}
+
+	// Queue a deletion the way the reflector does, without updating the
+	// reflector's resource version yet.
+	fifo := informer.controller.(*controller).config.Queue.(*DeltaFIFO)
+	if err := fifo.Delete(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns", UID: "uid1", ResourceVersion: "3"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		_, exists, err := informer.GetIndexer().GetByKey("ns/pod1")
+		return !exists, err
+	}); err != nil {
+		t.Fatalf("deletion was not applied: %v", err)
+	}
+
+	resourceVersion, stored, err := informer.quiescentSnapshot()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := "2", informer.LastSyncResourceVersion(); e != a {
+		t.Errorf("expected the reflector to still be at resource version %q, got %q", e, a)
+	}
+	if e, a := "3", resourceVersion; e != a {
+		t.Errorf("expected the snapshot to be at resource version %q, got %q", e, a)
+	}
+	if keys := stored.ListKeys(); !reflect.DeepEqual(keys, []string{"ns/pod2"}) {
+		t.Errorf("unexpected keys %v", keys)
+	}
+}
@@ -174,6 +174,16 @@ func (c *controller) LastSyncResourceVersion() string {
 	return c.reflector.LastSyncResourceVersion()
 }
 
+// relist makes the reflector, if any, drop its watch and list again, using lw
+// from then on if it is not nil.
+func (c *controller) relist(lw ListerWatcher) {
+	c.reflectorMutex.RLock()
+	defer c.reflectorMutex.RUnlock()
+	if c.reflector != nil {
+		c.reflector.requestRelist(lw)
+	}
+}
+
 // processLoop drains the work queue.
 // TODO: Consider doing the processing in parallel. This will require a little thought
 // to make sure that we don't end up processing the same object multiple times
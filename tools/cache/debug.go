@@ -0,0 +1,135 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxRecordedWatchErrors is the number of watch errors remembered by a
+// sharedIndexInformer for debugging.
+const maxRecordedWatchErrors = 10
+
+// InformerDebugInfo is a point-in-time description of the state of an
+// informer, intended for debugging running processes.
+type InformerDebugInfo struct {
+	// Synced is true if the informer has synced.
+	Synced bool `json:"synced"`
+	// LastSyncResourceVersion is the resource version the informer last
+	// synced with.
+	LastSyncResourceVersion string `json:"lastSyncResourceVersion"`
+	// StoreSize is the number of objects in the informer's store.
+	StoreSize int `json:"storeSize"`
+	// Indices maps the name of each index of the informer's store to the
+	// number of indexed values in that index.
+	Indices map[string]int `json:"indices,omitempty"`
+	// Handlers describes the registered event handlers.
+	Handlers []HandlerDebugInfo `json:"handlers,omitempty"`
+	// WatchErrors holds the most recent errors which made the informer
+	// drop its watch, oldest first.
+	WatchErrors []WatchErrorRecord `json:"watchErrors,omitempty"`
+}
+
+// HandlerDebugInfo describes an event handler registered with an informer.
+type HandlerDebugInfo struct {
+	// Handler is the type of the handler.
+	Handler string `json:"handler"`
+	// Synced is true if the handler has received all objects of the
+	// informer's initial list.
+	Synced bool `json:"synced"`
+	// PendingNotifications is the number of notifications buffered for
+	// the handler, which have not been delivered to it yet.
+	PendingNotifications int `json:"pendingNotifications"`
+}
+
+// WatchErrorRecord describes an error which made an informer drop its watch.
+type WatchErrorRecord struct {
+	Time  time.Time `json:"time"`
+	Error string    `json:"error"`
+}
+
+// GetInformerDebugInfo describes the current state of the given informer.
+// Handlers and watch errors are only reported for informers created by this
+// package.
+func GetInformerDebugInfo(informer SharedInformer) InformerDebugInfo {
+	info := InformerDebugInfo{
+		Synced:                  informer.HasSynced(),
+		LastSyncResourceVersion: informer.LastSyncResourceVersion(),
+	}
+	if indexer, ok := informer.GetStore().(Indexer); ok {
+		info.StoreSize = len(indexer.ListKeys())
+		for name := range indexer.GetIndexers() {
+			if info.Indices == nil {
+				info.Indices = map[string]int{}
+			}
+			info.Indices[name] = len(indexer.ListIndexFuncValues(name))
+		}
+	} else {
+		info.StoreSize = len(informer.GetStore().ListKeys())
+	}
+
+	s, ok := informer.(*sharedIndexInformer)
+	if !ok {
+		return info
+	}
+	info.Handlers = s.processor.debugInfo()
+	info.WatchErrors = s.watchErrors.list()
+	return info
+}
+
+func (p *sharedProcessor) debugInfo() []HandlerDebugInfo {
+	p.listenersLock.RLock()
+	defer p.listenersLock.RUnlock()
+
+	handlers := make([]HandlerDebugInfo, 0, len(p.listeners))
+	for listener := range p.listeners {
+		handlers = append(handlers, HandlerDebugInfo{
+			Handler:              fmt.Sprintf("%T", listener.handler),
+			Synced:               listener.HasSynced(),
+			PendingNotifications: int(atomic.LoadInt64(&listener.pendingCount)),
+		})
+	}
+	sort.SliceStable(handlers, func(i, j int) bool {
+		return handlers[i].Handler < handlers[j].Handler
+	})
+	return handlers
+}
+
+// watchErrorRecorder remembers the most recent watch errors of an informer.
+type watchErrorRecorder struct {
+	lock   sync.Mutex
+	errors []WatchErrorRecord
+}
+
+func (w *watchErrorRecorder) record(now time.Time, err error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if len(w.errors) == maxRecordedWatchErrors {
+		w.errors = w.errors[1:]
+	}
+	w.errors = append(w.errors, WatchErrorRecord{Time: now, Error: err.Error()})
+}
+
+func (w *watchErrorRecorder) list() []WatchErrorRecord {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return append([]WatchErrorRecord(nil), w.errors...)
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	fcache "k8s.io/client-go/tools/cache/testing"
+)
+
+func TestGetInformerDebugInfo(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns1"}})
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod2", Namespace: "ns2"}})
+	informer := NewSharedIndexInformer(source, &v1.Pod{}, 0, Indexers{NamespaceIndex: MetaNamespaceIndexFunc})
+
+	info := GetInformerDebugInfo(informer)
+	if info.Synced || info.StoreSize != 0 || len(info.Handlers) != 0 {
+		t.Errorf("unexpected debug info before start: %+v", info)
+	}
+
+	block := make(chan struct{})
+	blocked := ResourceEventHandlerFuncs{AddFunc: func(obj interface{}) { <-block }}
+	if _, err := informer.AddEventHandler(blocked); err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	defer close(block)
+	go informer.Run(stop)
+	if !WaitForCacheSync(stop, informer.HasSynced) {
+		t.Fatal("cache did not sync")
+	}
+
+	// The first notification is held by the blocked handler, the second
+	// one is buffered.
+	if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		info = GetInformerDebugInfo(informer)
+		return len(info.Handlers) == 1 && info.Handlers[0].PendingNotifications == 1, nil
+	}); err != nil {
+		t.Fatalf("unexpected handlers: %+v", info.Handlers)
+	}
+	if !info.Synced || info.LastSyncResourceVersion != "2" || info.StoreSize != 2 {
+		t.Errorf("unexpected debug info: %+v", info)
+	}
+	if info.Indices[NamespaceIndex] != 2 {
+		t.Errorf("expected two indexed namespaces, got %v", info.Indices)
+	}
+	if handler := info.Handlers[0]; handler.Handler != "cache.ResourceEventHandlerFuncs" || handler.Synced {
+		t.Errorf("unexpected handler info: %+v", handler)
+	}
+
+	s := informer.(*sharedIndexInformer)
+	for i := 0; i < maxRecordedWatchErrors+2; i++ {
+		s.watchErrors.record(time.Now(), fmt.Errorf("error %d", i))
+	}
+	info = GetInformerDebugInfo(informer)
+	if len(info.WatchErrors) != maxRecordedWatchErrors || info.WatchErrors[0].Error != "error 2" {
+		t.Errorf("unexpected watch errors: %+v", info.WatchErrors)
+	}
+}
+
+func TestSharedInformerRecordsWatchErrors(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.ListError = errors.New("list failed")
+	informer := NewSharedInformer(source, &v1.Pod{}, 0)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+
+	if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		return len(GetInformerDebugInfo(informer).WatchErrors) > 0, nil
+	}); err != nil {
+		t.Fatalf("expected the list error to be recorded")
+	}
+}
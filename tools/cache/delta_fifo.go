@@ -22,6 +22,7 @@ import (
 	"sync"
 	"time"
 
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/util/sets"
 
 	"k8s.io/klog/v2"
@@ -136,6 +137,12 @@ type DeltaFIFO struct {
 
 	// Called with every object if non-nil.
 	transformer TransformFunc
+
+	// queuedResourceVersion is the resource version of the last object
+	// passed to Add, Update or Delete, or of the last list passed to
+	// Replace. Once the queue is empty, the consumer has seen everything up
+	// to it.
+	queuedResourceVersion string
 }
 
 // TransformFunc allows for transforming an object before it will be processed.
@@ -313,6 +320,7 @@ func (f *DeltaFIFO) Add(obj interface{}) error {
 	f.lock.Lock()
 	defer f.lock.Unlock()
 	f.populated = true
+	f.recordResourceVersionLocked(obj)
 	return f.queueActionLocked(Added, obj)
 }
 
@@ -321,6 +329,7 @@ func (f *DeltaFIFO) Update(obj interface{}) error {
 	f.lock.Lock()
 	defer f.lock.Unlock()
 	f.populated = true
+	f.recordResourceVersionLocked(obj)
 	return f.queueActionLocked(Updated, obj)
 }
 
@@ -337,6 +346,7 @@ func (f *DeltaFIFO) Delete(obj interface{}) error {
 	f.lock.Lock()
 	defer f.lock.Unlock()
 	f.populated = true
+	f.recordResourceVersionLocked(obj)
 	if f.knownObjects == nil {
 		if _, exists := f.items[id]; !exists {
 			// Presumably, this was deleted when a relist happened.
@@ -361,6 +371,16 @@ func (f *DeltaFIFO) Delete(obj interface{}) error {
 	return f.queueActionLocked(Deleted, obj)
 }
 
+// recordResourceVersionLocked records the resource version of obj, if it has
+// one, as the queued resource version.
+func (f *DeltaFIFO) recordResourceVersionLocked(obj interface{}) {
+	objMeta, err := meta.Accessor(obj)
+	if err != nil || objMeta.GetResourceVersion() == "" {
+		return
+	}
+	f.queuedResourceVersion = objMeta.GetResourceVersion()
+}
+
 // AddIfNotPresent inserts an item, and puts it in the queue. If the item is already
 // present in the set, it is neither enqueued nor added to the set.
 //
@@ -620,9 +640,10 @@ func (f *DeltaFIFO) Pop(process PopProcessFunc) (interface{}, error) {
 // `f.items` and `f.knownObjects` (if not nil). The last known object for key K is
 // the one present in the last delta in `f.items`. If there is no delta for K
 // in `f.items`, it is the object in `f.knownObjects`
-func (f *DeltaFIFO) Replace(list []interface{}, _ string) error {
+func (f *DeltaFIFO) Replace(list []interface{}, resourceVersion string) error {
 	f.lock.Lock()
 	defer f.lock.Unlock()
+	f.queuedResourceVersion = resourceVersion
 	keys := make(sets.String, len(list))
 
 	// keep backwards compat for old clients
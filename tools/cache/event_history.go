@@ -0,0 +1,157 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+// EventRecord describes a single delta processed by an informer. It does
+// not hold the object itself, only what is needed to identify it.
+type EventRecord struct {
+	// Type is the type of the delta.
+	Type DeltaType `json:"type"`
+	// Key is the store key of the object.
+	Key string `json:"key"`
+	// ResourceVersion is the resource version of the object, or of its
+	// last known state for deletions.
+	ResourceVersion string `json:"resourceVersion"`
+	// Time is when the informer processed the delta.
+	Time time.Time `json:"time"`
+	// Relist is true if the delta was produced by a relist, rather than
+	// by a watch event or a resync.
+	Relist bool `json:"relist"`
+}
+
+// String formats the record as a single line.
+func (r EventRecord) String() string {
+	relist := ""
+	if r.Relist {
+		relist = " (relist)"
+	}
+	return fmt.Sprintf("%s %s %s resourceVersion=%q%s", r.Time.Format(time.RFC3339Nano), r.Type, r.Key, r.ResourceVersion, relist)
+}
+
+// EventHistory is a bounded history of the deltas processed by an informer,
+// oldest first. Once full, each new record replaces the oldest one.
+type EventHistory struct {
+	lock    sync.Mutex
+	records []EventRecord
+	// next is the index in records the next record is written to.
+	next int
+	// full is true once records has wrapped around.
+	full bool
+}
+
+// NewEventHistory returns an EventHistory holding up to size records.
+func NewEventHistory(size int) *EventHistory {
+	return &EventHistory{records: make([]EventRecord, size)}
+}
+
+// Record adds a record to the history.
+func (h *EventHistory) Record(record EventRecord) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if len(h.records) == 0 {
+		return
+	}
+	h.records[h.next] = record
+	h.next++
+	if h.next == len(h.records) {
+		h.next = 0
+		h.full = true
+	}
+}
+
+// List returns all records, oldest first.
+func (h *EventHistory) List() []EventRecord {
+	return h.filter(func(EventRecord) bool { return true })
+}
+
+// ListForKey returns the records for the object with the given key, oldest
+// first.
+func (h *EventHistory) ListForKey(key string) []EventRecord {
+	return h.filter(func(r EventRecord) bool { return r.Key == key })
+}
+
+func (h *EventHistory) filter(include func(EventRecord) bool) []EventRecord {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	var records []EventRecord
+	add := func(rs []EventRecord) {
+		for _, r := range rs {
+			if include(r) {
+				records = append(records, r)
+			}
+		}
+	}
+	if h.full {
+		add(h.records[h.next:])
+	}
+	add(h.records[:h.next])
+	return records
+}
+
+// WriteTo writes all records to w, one per line, oldest first.
+func (h *EventHistory) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	for _, r := range h.List() {
+		n, err := fmt.Fprintln(w, r.String())
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// GetEventHistory returns the event history of the given informer, or nil
+// if the informer does not record one. See
+// SharedIndexInformerOptions.EventHistorySize.
+func GetEventHistory(informer SharedInformer) *EventHistory {
+	if s, ok := informer.(*sharedIndexInformer); ok {
+		return s.eventHistory
+	}
+	return nil
+}
+
+// recordDeltas adds the given deltas to the history.
+func (h *EventHistory) recordDeltas(deltas Deltas, now time.Time) {
+	for _, d := range deltas {
+		record := EventRecord{Type: d.Type, Time: now, Relist: d.Type == Replaced}
+		obj := d.Object
+		if tombstone, ok := obj.(DeletedFinalStateUnknown); ok {
+			// A relist produces tombstones for the objects it no longer
+			// finds.
+			record.Relist = true
+			record.Key = tombstone.Key
+			obj = tombstone.Obj
+		} else if key, err := DeletionHandlingMetaNamespaceKeyFunc(obj); err == nil {
+			record.Key = key
+		}
+		if accessor, err := meta.Accessor(obj); err == nil {
+			record.ResourceVersion = accessor.GetResourceVersion()
+		}
+		h.Record(record)
+	}
+}
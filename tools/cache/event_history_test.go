@@ -0,0 +1,101 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	fcache "k8s.io/client-go/tools/cache/testing"
+)
+
+func TestEventHistoryWrapsAround(t *testing.T) {
+	h := NewEventHistory(3)
+	for _, key := range []string{"a", "b", "a", "c", "a"} {
+		h.Record(EventRecord{Type: Added, Key: key})
+	}
+
+	var keys []string
+	for _, r := range h.List() {
+		keys = append(keys, r.Key)
+	}
+	if got, want := strings.Join(keys, ","), "a,c,a"; got != want {
+		t.Errorf("expected records %s, got %s", want, got)
+	}
+	if got := len(h.ListForKey("a")); got != 2 {
+		t.Errorf("expected 2 records for a, got %d", got)
+	}
+	if got := len(h.ListForKey("b")); got != 0 {
+		t.Errorf("expected no records for b, got %d", got)
+	}
+}
+
+func TestEventHistoryInformer(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns"}})
+	informer := NewSharedIndexInformerWithOptions(source, &v1.Pod{}, SharedIndexInformerOptions{EventHistorySize: 10})
+	if GetEventHistory(NewSharedInformer(source, &v1.Pod{}, 0)) != nil {
+		t.Error("expected no history for an informer without EventHistorySize")
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	if !WaitForCacheSync(stop, informer.HasSynced) {
+		t.Fatal("cache did not sync")
+	}
+
+	source.Modify(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns"}})
+	source.Delete(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns"}})
+
+	history := GetEventHistory(informer)
+	var records []EventRecord
+	if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		records = history.ListForKey("ns/pod1")
+		return len(records) == 3, nil
+	}); err != nil {
+		t.Fatalf("expected 3 records, got %v", records)
+	}
+
+	expected := []EventRecord{
+		{Type: Replaced, Key: "ns/pod1", ResourceVersion: "1", Relist: true},
+		{Type: Updated, Key: "ns/pod1", ResourceVersion: "2"},
+		{Type: Deleted, Key: "ns/pod1", ResourceVersion: "3"},
+	}
+	for i, r := range records {
+		if r.Time.IsZero() {
+			t.Errorf("record %d has no time", i)
+		}
+		r.Time = time.Time{}
+		if r != expected[i] {
+			t.Errorf("record %d: expected %+v, got %+v", i, expected[i], r)
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := history.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 3 || !strings.Contains(lines[0], "Replaced ns/pod1") {
+		t.Errorf("unexpected dump:\n%s", buf.String())
+	}
+}
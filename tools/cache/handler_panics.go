@@ -0,0 +1,189 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// HandlerOptions configures the registration of an event handler with a
+// SharedInformer, see HandlerOptionsAdder.
+type HandlerOptions struct {
+	// ResyncPeriod is the resync period of the handler, see
+	// AddEventHandlerWithResyncPeriod. If unset/unspecified, the default
+	// resync period of the informer is used.
+	ResyncPeriod *time.Duration
+
+	// PanicPolicy, if set, makes the informer recover from panics of the
+	// handler instead of crashing. If unset/unspecified, a panicking handler
+	// crashes the process.
+	PanicPolicy *HandlerPanicPolicy
+}
+
+// HandlerPanicPolicy describes how an informer deals with the panics of an
+// event handler.
+//
+// Every panic is recovered and logged together with the key of the object
+// being handled, and the notification is skipped. After MaxConsecutivePanics
+// notifications in a row panicked, the handler is quarantined: it receives no
+// further notifications and is removed from the informer.
+type HandlerPanicPolicy struct {
+	// Name identifies the handler in logs and metrics. If unset/unspecified,
+	// the type of the handler is used.
+	Name string
+
+	// MaxConsecutivePanics is the number of consecutive panics after which the
+	// handler is quarantined. If unset/unspecified, the handler is never
+	// quarantined.
+	MaxConsecutivePanics int
+
+	// OnPanic, if set, is called after each recovered panic with the key of
+	// the object being handled and the value passed to panic.
+	OnPanic func(key string, recovered interface{})
+
+	// OnQuarantine, if set, is called once the handler is quarantined.
+	OnQuarantine func()
+}
+
+// HandlerPanicMetricsProvider generates the metrics reported for event
+// handlers registered with a HandlerPanicPolicy.
+type HandlerPanicMetricsProvider interface {
+	NewPanicsMetric(name string) CounterMetric
+	NewQuarantinesMetric(name string) CounterMetric
+}
+
+type noopHandlerPanicMetricsProvider struct{}
+
+func (noopHandlerPanicMetricsProvider) NewPanicsMetric(name string) CounterMetric {
+	return noopMetric{}
+}
+func (noopHandlerPanicMetricsProvider) NewQuarantinesMetric(name string) CounterMetric {
+	return noopMetric{}
+}
+
+var handlerPanicMetricsFactory = struct {
+	metricsProvider HandlerPanicMetricsProvider
+	setProviders    sync.Once
+}{
+	metricsProvider: noopHandlerPanicMetricsProvider{},
+}
+
+// SetHandlerPanicMetricsProvider sets the metrics provider of the handlers
+// registered with a HandlerPanicPolicy. Only the first call has an effect.
+func SetHandlerPanicMetricsProvider(metricsProvider HandlerPanicMetricsProvider) {
+	handlerPanicMetricsFactory.setProviders.Do(func() {
+		handlerPanicMetricsFactory.metricsProvider = metricsProvider
+	})
+}
+
+// panicGuard applies a HandlerPanicPolicy to a processorListener. It is only
+// used by the run goroutine of the listener, except for quarantined.
+type panicGuard struct {
+	name   string
+	policy HandlerPanicPolicy
+	// quarantine removes the listener from its informer.
+	quarantine func()
+
+	panics      CounterMetric
+	quarantines CounterMetric
+
+	consecutivePanics int
+	// quarantined is set once the handler is quarantined. The listener
+	// reports itself synced from then on, since the rest of the initial list
+	// will never be delivered to the handler.
+	quarantined atomic.Bool
+}
+
+func newPanicGuard(handler ResourceEventHandler, policy HandlerPanicPolicy, quarantine func()) *panicGuard {
+	name := policy.Name
+	if name == "" {
+		name = fmt.Sprintf("%T", handler)
+	}
+	provider := handlerPanicMetricsFactory.metricsProvider
+	return &panicGuard{
+		name:        name,
+		policy:      policy,
+		quarantine:  quarantine,
+		panics:      provider.NewPanicsMetric(name),
+		quarantines: provider.NewQuarantinesMetric(name),
+	}
+}
+
+// handle calls handle for the notification, unless the listener is
+// quarantined, and recovers from its panics.
+func (g *panicGuard) handle(notification interface{}, handle func(interface{})) {
+	if g.quarantined.Load() {
+		return
+	}
+	panicked := true
+	defer func() {
+		if !panicked {
+			g.consecutivePanics = 0
+			return
+		}
+		g.recovered(notification, recover())
+	}()
+	handle(notification)
+	panicked = false
+}
+
+func (g *panicGuard) recovered(notification interface{}, r interface{}) {
+	key := notificationKey(notification)
+	g.consecutivePanics++
+	g.panics.Inc()
+	klog.Errorf("Event handler %s panicked while handling %q: %v\n%s", g.name, key, r, debug.Stack())
+	if g.policy.OnPanic != nil {
+		g.policy.OnPanic(key, r)
+	}
+
+	if g.policy.MaxConsecutivePanics > 0 && g.consecutivePanics >= g.policy.MaxConsecutivePanics {
+		g.quarantined.Store(true)
+		g.quarantines.Inc()
+		klog.Errorf("Event handler %s panicked %d times in a row, removing it from the informer", g.name, g.consecutivePanics)
+		// The listener cannot be removed synchronously from its own run
+		// goroutine: the processor waits for that goroutine while holding
+		// the lock needed to remove it.
+		go g.quarantine()
+		if g.policy.OnQuarantine != nil {
+			g.policy.OnQuarantine()
+		}
+	}
+}
+
+// notificationKey returns the key of the object of a notification.
+func notificationKey(notification interface{}) string {
+	var obj interface{}
+	switch n := notification.(type) {
+	case addNotification:
+		obj = n.newObj
+	case updateNotification:
+		obj = n.newObj
+	case deleteNotification:
+		obj = n.oldObj
+	}
+	key, err := DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return fmt.Sprintf("<unknown: %v>", err)
+	}
+	return key
+}
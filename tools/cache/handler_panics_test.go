@@ -0,0 +1,138 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	fcache "k8s.io/client-go/tools/cache/testing"
+)
+
+func TestHandlerPanicPolicy(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	informer := NewSharedInformer(source, &v1.Pod{}, 0).(*sharedIndexInformer)
+
+	var lock sync.Mutex
+	var handled, panicked []string
+	quarantined := make(chan struct{})
+	handle, err := informer.AddEventHandlerWithOptions(ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			name := obj.(*v1.Pod).Name
+			if strings.HasPrefix(name, "bad") {
+				panic("bad pod")
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			handled = append(handled, name)
+		},
+	}, HandlerOptions{PanicPolicy: &HandlerPanicPolicy{
+		MaxConsecutivePanics: 3,
+		OnPanic: func(key string, recovered interface{}) {
+			lock.Lock()
+			defer lock.Unlock()
+			panicked = append(panicked, key)
+		},
+		OnQuarantine: func() { close(quarantined) },
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	if !WaitForCacheSync(stop, informer.HasSynced, handle.HasSynced) {
+		t.Fatal("cache did not sync")
+	}
+
+	// Panics which are not consecutive do not quarantine the handler.
+	for _, name := range []string{"bad1", "bad2", "good1", "bad3", "bad4", "good2"} {
+		source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"}})
+	}
+	if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(handled) == 2, nil
+	}); err != nil {
+		t.Fatalf("expected the good pods to be handled, got %v", handled)
+	}
+	lock.Lock()
+	if got := strings.Join(panicked, ","); got != "ns/bad1,ns/bad2,ns/bad3,ns/bad4" {
+		t.Errorf("unexpected panics %s", got)
+	}
+	lock.Unlock()
+
+	for _, name := range []string{"bad5", "bad6", "bad7", "good3"} {
+		source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"}})
+	}
+	select {
+	case <-quarantined:
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("handler was not quarantined")
+	}
+	if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		informer.processor.listenersLock.RLock()
+		defer informer.processor.listenersLock.RUnlock()
+		return len(informer.processor.listeners) == 0, nil
+	}); err != nil {
+		t.Fatal("quarantined handler was not removed")
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if len(handled) != 2 {
+		t.Errorf("expected no notification after the quarantine, got %v", handled)
+	}
+}
+
+func TestHandlerPanicPolicyQuarantineDuringInitialList(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	for _, name := range []string{"pod1", "pod2", "pod3"} {
+		source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"}})
+	}
+	informer := NewSharedInformer(source, &v1.Pod{}, 0).(*sharedIndexInformer)
+
+	quarantined := make(chan struct{})
+	handle, err := informer.AddEventHandlerWithOptions(ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { panic("bad handler") },
+	}, HandlerOptions{PanicPolicy: &HandlerPanicPolicy{
+		MaxConsecutivePanics: 1,
+		OnQuarantine:         func() { close(quarantined) },
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	select {
+	case <-quarantined:
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("handler was not quarantined")
+	}
+	// The rest of the initial list is never delivered to the quarantined
+	// handler, which must not keep its registration from syncing.
+	if !WaitForCacheSync(stop, informer.HasSynced, handle.HasSynced) {
+		t.Fatal("registration did not sync")
+	}
+}
@@ -0,0 +1,603 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+)
+
+// defaultLazyObjectCacheTTL is the default LazyObjectCacheOptions.TTL.
+const defaultLazyObjectCacheTTL = 10 * time.Minute
+
+// LazyObjectCacheOptions configures a LazyObjectCache.
+type LazyObjectCacheOptions struct {
+	// TTL is how long an object stays cached after it was last retrieved
+	// with Get. If unset/unspecified, it defaults to 10 minutes.
+	TTL time.Duration
+
+	// ShareNamespaceWatches makes the objects retrieved from a namespace
+	// share a single reflector which lists and watches the whole namespace,
+	// instead of each object having its own. This uses fewer watches when
+	// many objects of a namespace are retrieved, at the cost of caching every
+	// object of the namespace. Objects of cluster-scoped resources are still
+	// watched one by one.
+	ShareNamespaceWatches bool
+
+	// Clock allows tests to control time. If unset/unspecified, the real
+	// clock is used.
+	Clock clock.Clock
+}
+
+// LazyObjectCache caches individual objects, for controllers which only need
+// a few specific objects of a resource and should not list all of them.
+//
+// An object is fetched on the first call to Get, which blocks until then, and
+// is kept up to date by a reflector which only lists and watches that object,
+// or its namespace if LazyObjectCacheOptions.ShareNamespaceWatches is set.
+// Objects which do not exist are cached too, so that their creation is
+// observed. An object which has not been retrieved with Get for the TTL is
+// dropped from the cache, and its reflector is stopped once no other object
+// uses it; no notification is delivered for that.
+//
+// LazyObjectCache implements GenericLister. List only returns the objects
+// which are currently cached and were retrieved with Get.
+type LazyObjectCache struct {
+	newListWatch          func(namespace, name string) ListerWatcher
+	exampleObject         runtime.Object
+	resource              schema.GroupResource
+	ttl                   time.Duration
+	shareNamespaceWatches bool
+	clock                 clock.Clock
+
+	// processor delivers notifications to the handlers, each from its own
+	// goroutine, so that reflectors never wait for handlers.
+	processor *sharedProcessor
+
+	// lock guards the fields below. It is acquired before the lock of any
+	// watch.
+	lock sync.Mutex
+	// entries maps the keys of the retrieved objects to their entry.
+	entries map[string]*lazyEntry
+	// watches maps the keys of the running watches to them, see
+	// lazyWatch.key.
+	watches map[string]*lazyWatch
+	// reflectors tracks the goroutines running reflectors.
+	reflectors wait.Group
+	// stopCh is the channel passed to Run, nil until then.
+	stopCh  <-chan struct{}
+	stopped bool
+}
+
+var _ GenericLister = &LazyObjectCache{}
+
+// NewLazyObjectCache returns a LazyObjectCache for objects of the type of
+// exampleObject, which belong to the given resource. newListWatch is called
+// for every object when it is first retrieved, and must return a
+// ListerWatcher which lists and watches only that object, usually through a
+// metadata.name field selector; NewObjectListWatchFromClient does that. If
+// LazyObjectCacheOptions.ShareNamespaceWatches is set, it is called with an
+// empty name when an object of a namespace is first retrieved, and must
+// then return a ListerWatcher for the whole namespace.
+func NewLazyObjectCache(newListWatch func(namespace, name string) ListerWatcher, exampleObject runtime.Object, resource schema.GroupResource, options LazyObjectCacheOptions) *LazyObjectCache {
+	c := &LazyObjectCache{
+		newListWatch:          newListWatch,
+		exampleObject:         exampleObject,
+		resource:              resource,
+		ttl:                   options.TTL,
+		shareNamespaceWatches: options.ShareNamespaceWatches,
+		clock:                 options.Clock,
+		entries:               map[string]*lazyEntry{},
+		watches:               map[string]*lazyWatch{},
+	}
+	if c.ttl <= 0 {
+		c.ttl = defaultLazyObjectCacheTTL
+	}
+	if c.clock == nil {
+		c.clock = clock.RealClock{}
+	}
+	c.processor = &sharedProcessor{clock: c.clock}
+	return c
+}
+
+// NewObjectListWatchFromClient creates a new ListWatch from the specified
+// client, for the object with the given name in the given namespace, or for
+// every object of the namespace if name is empty.
+func NewObjectListWatchFromClient(c Getter, resource string, namespace string, name string) *ListWatch {
+	selector := fields.Everything()
+	if name != "" {
+		selector = fields.OneTermEqualSelector("metadata.name", name)
+	}
+	return NewListWatchFromClient(c, resource, namespace, selector)
+}
+
+// AddEventHandler adds a handler which is notified of the changes to the
+// cached objects. When an object is first retrieved, an add notification is
+// delivered for it, unless it does not exist. Like the handlers of a
+// SharedInformer, each handler is called from its own goroutine, never
+// concurrently with itself.
+func (c *LazyObjectCache) AddEventHandler(handler ResourceEventHandler) {
+	listener := newProcessListener(handler, 0, 0, c.clock.Now(), initialBufferSize, func() bool { return true })
+	c.processor.addListener(listener)
+}
+
+// Run expires the objects which have not been retrieved for the TTL, and
+// delivers notifications to the handlers, until stopCh is closed. Get may
+// only be used while Run runs.
+func (c *LazyObjectCache) Run(stopCh <-chan struct{}) {
+	c.lock.Lock()
+	if c.stopCh != nil {
+		c.lock.Unlock()
+		klog.Warningf("The LazyObjectCache has started, run more than once is not allowed")
+		return
+	}
+	c.stopCh = stopCh
+	c.lock.Unlock()
+
+	var wg wait.Group
+	processorStopCh := make(chan struct{})
+	wg.StartWithChannel(processorStopCh, c.processor.run)
+	defer func() {
+		c.lock.Lock()
+		c.stopped = true
+		for key, w := range c.watches {
+			w.stop()
+			delete(c.watches, key)
+		}
+		c.entries = map[string]*lazyEntry{}
+		c.lock.Unlock()
+
+		// The processor stops once no reflector can deliver notifications
+		// anymore.
+		c.reflectors.Wait()
+		close(processorStopCh)
+		wg.Wait()
+	}()
+
+	for {
+		timer := c.clock.NewTimer(c.ttl / 2)
+		select {
+		case <-stopCh:
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+		c.expire()
+	}
+}
+
+// expire drops the entries which have not been retrieved for the TTL, and
+// stops the watches no entry uses anymore.
+func (c *LazyObjectCache) expire() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	now := c.clock.Now()
+	for key, entry := range c.entries {
+		if now.Sub(entry.lastAccess) < c.ttl {
+			continue
+		}
+		klog.V(4).Infof("Expiring %s %q from lazy object cache", c.resource, key)
+		delete(c.entries, key)
+		if entry.watch.removeKey(key) && c.watches[entry.watch.key] == entry.watch {
+			delete(c.watches, entry.watch.key)
+			entry.watch.stop()
+		}
+	}
+}
+
+// List returns the cached objects which match the selector.
+func (c *LazyObjectCache) List(selector labels.Selector) ([]runtime.Object, error) {
+	return c.list(metav1.NamespaceAll, selector)
+}
+
+// Get returns the object with the given name, for cluster-scoped resources.
+func (c *LazyObjectCache) Get(name string) (runtime.Object, error) {
+	return c.get("", name)
+}
+
+// ByNamespace returns a GenericNamespaceLister for the given namespace.
+func (c *LazyObjectCache) ByNamespace(namespace string) GenericNamespaceLister {
+	return &lazyNamespaceLister{cache: c, namespace: namespace}
+}
+
+type lazyNamespaceLister struct {
+	cache     *LazyObjectCache
+	namespace string
+}
+
+// List returns the cached objects of the namespace which match the selector.
+func (l *lazyNamespaceLister) List(selector labels.Selector) ([]runtime.Object, error) {
+	return l.cache.list(l.namespace, selector)
+}
+
+// Get returns the object with the given name in the namespace.
+func (l *lazyNamespaceLister) Get(name string) (runtime.Object, error) {
+	return l.cache.get(l.namespace, name)
+}
+
+func (c *LazyObjectCache) list(namespace string, selector labels.Selector) ([]runtime.Object, error) {
+	type cachedKey struct {
+		key   string
+		store Store
+	}
+	c.lock.Lock()
+	var keys []cachedKey
+	for key, entry := range c.entries {
+		if namespace == metav1.NamespaceAll || entry.namespace == namespace {
+			keys = append(keys, cachedKey{key: key, store: entry.watch.store})
+		}
+	}
+	c.lock.Unlock()
+
+	var ret []runtime.Object
+	for _, k := range keys {
+		obj, exists, err := k.store.GetByKey(k.key)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			continue
+		}
+		objMeta, err := meta.Accessor(obj)
+		if err != nil {
+			return nil, err
+		}
+		if selector.Matches(labels.Set(objMeta.GetLabels())) {
+			ret = append(ret, obj.(runtime.Object))
+		}
+	}
+	return ret, nil
+}
+
+func (c *LazyObjectCache) get(namespace, name string) (runtime.Object, error) {
+	key := name
+	if namespace != "" {
+		key = namespace + "/" + name
+	}
+
+	w, err := c.watchFor(key, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case <-w.ready:
+	case <-w.stopCh:
+		return nil, fmt.Errorf("%s %q expired before it could be retrieved", c.resource, key)
+	case <-c.stopCh:
+		return nil, fmt.Errorf("lazy object cache has stopped")
+	}
+	if w.err != nil {
+		return nil, w.err
+	}
+
+	obj, exists, err := w.store.GetByKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(c.resource, name)
+	}
+	return obj.(runtime.Object), nil
+}
+
+// watchFor returns the watch of the given key, creating its entry and watch
+// if needed, and records the access.
+func (c *LazyObjectCache) watchFor(key, namespace, name string) (*lazyWatch, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.stopCh == nil || c.stopped {
+		return nil, fmt.Errorf("lazy object cache is not running")
+	}
+	entry, exists := c.entries[key]
+	if !exists {
+		watchKey, watchName := key, name
+		if c.shareNamespaceWatches && namespace != "" {
+			watchKey, watchName = namespace+"/", ""
+		}
+		w, exists := c.watches[watchKey]
+		if !exists {
+			w = c.newWatch(watchKey, namespace, watchName)
+			c.watches[watchKey] = w
+		}
+		w.addKey(key)
+		entry = &lazyEntry{namespace: namespace, watch: w}
+		c.entries[key] = entry
+	}
+	entry.lastAccess = c.clock.Now()
+	return entry.watch, nil
+}
+
+// newWatch creates a watch of the object with the given name, or of the
+// namespace if name is empty, and starts its reflector. c.lock must be held.
+func (c *LazyObjectCache) newWatch(key, namespace, name string) *lazyWatch {
+	w := &lazyWatch{
+		cache:  c,
+		key:    key,
+		store:  NewStore(MetaNamespaceKeyFunc),
+		keys:   sets.NewString(),
+		stopCh: make(chan struct{}),
+		ready:  make(chan struct{}),
+	}
+	if name != "" {
+		w.object = key
+	}
+	description := fmt.Sprintf("%s %q", c.resource, key)
+	if name == "" {
+		description = fmt.Sprintf("%s in namespace %q", c.resource, namespace)
+	}
+	r := NewReflectorWithOptions(c.newListWatch(namespace, name), c.exampleObject, &lazyWatchStore{watch: w}, ReflectorOptions{
+		Name:  "lazy object cache for " + description,
+		Clock: c.clock,
+	})
+	r.watchErrorHandler = func(r *Reflector, err error) {
+		if w.setReady(err) {
+			// The objects could not be retrieved. Drop the watch and its
+			// entries, so that the next Get tries again.
+			c.lock.Lock()
+			if c.watches[key] == w {
+				delete(c.watches, key)
+			}
+			for entryKey, entry := range c.entries {
+				if entry.watch == w {
+					delete(c.entries, entryKey)
+				}
+			}
+			c.lock.Unlock()
+			w.stop()
+			return
+		}
+		DefaultWatchErrorHandler(r, err)
+	}
+
+	stopCh := make(chan struct{})
+	go func() {
+		defer close(stopCh)
+		select {
+		case <-c.stopCh:
+		case <-w.stopCh:
+		}
+	}()
+	c.reflectors.Start(func() { r.Run(stopCh) })
+	return w
+}
+
+// lazyEntry is an object retrieved with Get.
+type lazyEntry struct {
+	namespace string
+	// watch is the watch which keeps the object up to date.
+	watch *lazyWatch
+	// lastAccess is when the object was last retrieved, guarded by
+	// cache.lock.
+	lastAccess time.Time
+}
+
+// lazyWatch is a reflector which keeps either a single object or the objects
+// of a namespace up to date.
+type lazyWatch struct {
+	cache *LazyObjectCache
+	// key identifies the watch in cache.watches: it is the key of the
+	// watched object, or the namespace followed by a slash for a namespace
+	// watch.
+	key string
+	// object is the key of the watched object, empty for a namespace watch.
+	object string
+	// store holds the watched objects which exist.
+	store Store
+
+	// lock serializes the changes to the store with the notifications they
+	// cause, and guards the fields below.
+	lock sync.Mutex
+	// keys holds the keys of the objects retrieved with Get, which the
+	// handlers are notified about.
+	keys sets.String
+	// listed is true once the objects were first listed.
+	listed bool
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+
+	readyOnce sync.Once
+	// ready is closed once the objects were first listed, or could not be.
+	ready chan struct{}
+	// err is why the objects could not be listed, set before ready is closed.
+	err error
+}
+
+// addKey makes the handlers be notified about the object with the given key.
+// If the objects were already listed and it exists, an add notification is
+// delivered for it right away.
+func (w *lazyWatch) addKey(key string) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.keys.Insert(key)
+	if !w.listed {
+		return
+	}
+	if obj, exists, err := w.store.GetByKey(key); err == nil && exists {
+		w.cache.processor.distribute(addNotification{newObj: obj, isInInitialList: true}, false)
+	}
+}
+
+// removeKey stops notifications about the object with the given key, and
+// returns true if no key is left.
+func (w *lazyWatch) removeKey(key string) bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.keys.Delete(key)
+	return w.keys.Len() == 0
+}
+
+// setReady marks the watch as ready, with the given error, and returns true
+// if it was not ready yet.
+func (w *lazyWatch) setReady(err error) bool {
+	done := false
+	w.readyOnce.Do(func() {
+		w.err = err
+		close(w.ready)
+		done = true
+	})
+	return done
+}
+
+func (w *lazyWatch) stop() {
+	w.stopOnce.Do(func() { close(w.stopCh) })
+}
+
+// lazyWatchStore is the Store of the reflector of a watch. It keeps the
+// objects of the watch, ignoring any other object the ListerWatcher returns,
+// and notifies the handlers of the cache of the changes to the objects
+// retrieved with Get.
+type lazyWatchStore struct {
+	watch *lazyWatch
+}
+
+var _ Store = &lazyWatchStore{}
+
+// key returns the key of obj, and whether the watch keeps it.
+func (s *lazyWatchStore) key(obj interface{}) (string, bool) {
+	key, err := DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return "", false
+	}
+	return key, s.watch.object == "" || key == s.watch.object
+}
+
+func (s *lazyWatchStore) notify(key string, notification interface{}) {
+	if s.watch.keys.Has(key) {
+		s.watch.cache.processor.distribute(notification, false)
+	}
+}
+
+func (s *lazyWatchStore) Add(obj interface{}) error {
+	return s.Update(obj)
+}
+
+func (s *lazyWatchStore) Update(obj interface{}) error {
+	key, ok := s.key(obj)
+	if !ok {
+		return nil
+	}
+	s.watch.lock.Lock()
+	defer s.watch.lock.Unlock()
+	old, exists, err := s.watch.store.GetByKey(key)
+	if err != nil {
+		return err
+	}
+	if err := s.watch.store.Update(obj); err != nil {
+		return err
+	}
+	if exists {
+		s.notify(key, updateNotification{oldObj: old, newObj: obj})
+	} else {
+		s.notify(key, addNotification{newObj: obj})
+	}
+	return nil
+}
+
+func (s *lazyWatchStore) Delete(obj interface{}) error {
+	key, ok := s.key(obj)
+	if !ok {
+		return nil
+	}
+	s.watch.lock.Lock()
+	defer s.watch.lock.Unlock()
+	_, exists, err := s.watch.store.GetByKey(key)
+	if err != nil || !exists {
+		return err
+	}
+	if err := s.watch.store.Delete(obj); err != nil {
+		return err
+	}
+	s.notify(key, deleteNotification{oldObj: obj})
+	return nil
+}
+
+func (s *lazyWatchStore) Replace(list []interface{}, resourceVersion string) error {
+	items := map[string]interface{}{}
+	for _, item := range list {
+		if key, ok := s.key(item); ok {
+			items[key] = item
+		}
+	}
+
+	s.watch.lock.Lock()
+	defer s.watch.lock.Unlock()
+	old := map[string]interface{}{}
+	for _, obj := range s.watch.store.List() {
+		if key, err := MetaNamespaceKeyFunc(obj); err == nil {
+			old[key] = obj
+		}
+	}
+	replaced := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		replaced = append(replaced, item)
+	}
+	if err := s.watch.store.Replace(replaced, resourceVersion); err != nil {
+		return err
+	}
+	initial := !s.watch.listed
+	s.watch.listed = true
+	s.watch.setReady(nil)
+
+	for key, obj := range items {
+		if oldObj, exists := old[key]; exists {
+			s.notify(key, updateNotification{oldObj: oldObj, newObj: obj})
+		} else {
+			s.notify(key, addNotification{newObj: obj, isInInitialList: initial})
+		}
+	}
+	for key, oldObj := range old {
+		if _, exists := items[key]; !exists {
+			s.notify(key, deleteNotification{oldObj: DeletedFinalStateUnknown{Key: key, Obj: oldObj}})
+		}
+	}
+	return nil
+}
+
+func (s *lazyWatchStore) List() []interface{} {
+	return s.watch.store.List()
+}
+
+func (s *lazyWatchStore) ListKeys() []string {
+	return s.watch.store.ListKeys()
+}
+
+func (s *lazyWatchStore) Get(obj interface{}) (item interface{}, exists bool, err error) {
+	return s.watch.store.Get(obj)
+}
+
+func (s *lazyWatchStore) GetByKey(key string) (item interface{}, exists bool, err error) {
+	return s.watch.store.GetByKey(key)
+}
+
+func (s *lazyWatchStore) Resync() error {
+	return nil
+}
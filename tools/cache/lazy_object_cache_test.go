@@ -0,0 +1,277 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	fcache "k8s.io/client-go/tools/cache/testing"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestLazyObjectCache(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm1", Namespace: "ns"}})
+	source.Add(&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "ns"}})
+
+	var lock sync.Mutex
+	listWatches := map[string]int{}
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	c := NewLazyObjectCache(func(namespace, name string) ListerWatcher {
+		lock.Lock()
+		defer lock.Unlock()
+		listWatches[namespace+"/"+name]++
+		// The fake source ignores field selectors, the cache must ignore
+		// the other objects.
+		return source
+	}, &v1.ConfigMap{}, v1.Resource("configmaps"), LazyObjectCacheOptions{TTL: time.Minute, Clock: fakeClock})
+
+	var events []string
+	c.AddEventHandler(ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			lock.Lock()
+			events = append(events, "add "+obj.(*v1.ConfigMap).Name)
+			lock.Unlock()
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			lock.Lock()
+			events = append(events, "update "+obj.(*v1.ConfigMap).Name)
+			lock.Unlock()
+		},
+		DeleteFunc: func(obj interface{}) {
+			lock.Lock()
+			events = append(events, "delete "+obj.(*v1.ConfigMap).Name)
+			lock.Unlock()
+		},
+	})
+	waitForEvents := func(expected ...string) {
+		t.Helper()
+		err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			return len(events) >= len(expected), nil
+		})
+		lock.Lock()
+		defer lock.Unlock()
+		if err != nil || len(events) != len(expected) {
+			t.Fatalf("expected events %v, got %v", expected, events)
+		}
+		for i := range expected {
+			if events[i] != expected[i] {
+				t.Fatalf("expected events %v, got %v", expected, events)
+			}
+		}
+		events = nil
+	}
+
+	if _, err := c.ByNamespace("ns").Get("cm1"); err == nil {
+		t.Error("expected an error before Run")
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go c.Run(stop)
+	if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		_, err := c.ByNamespace("ns").Get("cm1")
+		return err == nil, nil
+	}); err != nil {
+		t.Fatalf("cm1 was never retrieved: %v", err)
+	}
+	waitForEvents("add cm1")
+
+	if _, err := c.ByNamespace("ns").Get("cm2"); !apierrors.IsNotFound(err) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+	source.Add(&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm2", Namespace: "ns"}})
+	waitForEvents("add cm2")
+	if _, err := c.ByNamespace("ns").Get("cm2"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	source.Modify(&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm1", Namespace: "ns"}})
+	waitForEvents("update cm1")
+	source.Delete(&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm2", Namespace: "ns"}})
+	waitForEvents("delete cm2")
+
+	if objs, err := c.List(labels.Everything()); err != nil || len(objs) != 1 {
+		t.Errorf("expected only cm1 to be listed, got %v, %v", objs, err)
+	}
+
+	// Keep cm1 in use, let cm2 expire.
+	fakeClock.Step(40 * time.Second)
+	if _, err := c.ByNamespace("ns").Get("cm1"); err != nil {
+		t.Fatal(err)
+	}
+	fakeClock.Step(40 * time.Second)
+	if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		_, cm1 := c.entries["ns/cm1"]
+		_, cm2 := c.entries["ns/cm2"]
+		return cm1 && !cm2, nil
+	}); err != nil {
+		t.Fatal("expected only cm2 to expire")
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if listWatches["ns/cm1"] != 1 || listWatches["ns/cm2"] != 1 {
+		t.Errorf("expected one ListerWatcher per object, got %v", listWatches)
+	}
+}
+
+func TestLazyObjectCacheListError(t *testing.T) {
+	listErr := errors.New("forbidden")
+	c := NewLazyObjectCache(func(namespace, name string) ListerWatcher {
+		return &ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return nil, listErr
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return nil, listErr
+			},
+		}
+	}, &v1.ConfigMap{}, v1.Resource("configmaps"), LazyObjectCacheOptions{})
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go c.Run(stop)
+	if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		return c.stopCh != nil, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ByNamespace("ns").Get("cm"); !errors.Is(err, listErr) {
+		t.Errorf("expected list error, got %v", err)
+	}
+}
+
+func TestLazyObjectCacheShareNamespaceWatches(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm1", Namespace: "ns"}})
+	source.Add(&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm2", Namespace: "ns"}})
+	source.Add(&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "ns"}})
+
+	var lock sync.Mutex
+	listWatches := map[string]int{}
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	c := NewLazyObjectCache(func(namespace, name string) ListerWatcher {
+		lock.Lock()
+		defer lock.Unlock()
+		listWatches[namespace+"/"+name]++
+		return source
+	}, &v1.ConfigMap{}, v1.Resource("configmaps"), LazyObjectCacheOptions{TTL: time.Minute, ShareNamespaceWatches: true, Clock: fakeClock})
+
+	var events []string
+	c.AddEventHandler(ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			lock.Lock()
+			events = append(events, "add "+obj.(*v1.ConfigMap).Name)
+			lock.Unlock()
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			lock.Lock()
+			events = append(events, "update "+obj.(*v1.ConfigMap).Name)
+			lock.Unlock()
+		},
+	})
+	waitForEvents := func(expected ...string) {
+		t.Helper()
+		err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			return len(events) >= len(expected), nil
+		})
+		lock.Lock()
+		defer lock.Unlock()
+		if err != nil || len(events) != len(expected) {
+			t.Fatalf("expected events %v, got %v", expected, events)
+		}
+		for i := range expected {
+			if events[i] != expected[i] {
+				t.Fatalf("expected events %v, got %v", expected, events)
+			}
+		}
+		events = nil
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go c.Run(stop)
+	if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		_, err := c.ByNamespace("ns").Get("cm1")
+		return err == nil, nil
+	}); err != nil {
+		t.Fatalf("cm1 was never retrieved: %v", err)
+	}
+	waitForEvents("add cm1")
+	if _, err := c.ByNamespace("ns").Get("cm2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	waitForEvents("add cm2")
+
+	// Objects which were not retrieved are neither notified nor listed.
+	source.Modify(&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "ns"}})
+	source.Modify(&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm1", Namespace: "ns"}})
+	waitForEvents("update cm1")
+	if objs, err := c.List(labels.Everything()); err != nil || len(objs) != 2 {
+		t.Errorf("expected cm1 and cm2 to be listed, got %v, %v", objs, err)
+	}
+
+	// The watch is kept while cm1 is in use.
+	fakeClock.Step(40 * time.Second)
+	if _, err := c.ByNamespace("ns").Get("cm1"); err != nil {
+		t.Fatal(err)
+	}
+	fakeClock.Step(40 * time.Second)
+	if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		_, cm2 := c.entries["ns/cm2"]
+		_, watch := c.watches["ns/"]
+		return !cm2 && watch, nil
+	}); err != nil {
+		t.Fatal("expected only cm2 to expire")
+	}
+
+	fakeClock.Step(time.Minute)
+	if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		return len(c.entries) == 0 && len(c.watches) == 0, nil
+	}); err != nil {
+		t.Fatal("expected the namespace watch to stop once every object expired")
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(listWatches) != 1 || listWatches["ns/"] != 1 {
+		t.Errorf("expected one ListerWatcher for the namespace, got %v", listWatches)
+	}
+}
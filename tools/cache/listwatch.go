@@ -18,7 +18,10 @@ package cache
 
 import (
 	"context"
+	"fmt"
+	"sync"
 
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -39,6 +42,16 @@ type Watcher interface {
 	Watch(options metav1.ListOptions) (watch.Interface, error)
 }
 
+// ItemsLister is any object that knows how to perform an initial list item by
+// item, without buffering the response or decoding it into a list object. The
+// Reflector prefers it over Lister, but still collects the items to replace
+// its store with.
+type ItemsLister interface {
+	// ListItems should call fn with every item of the list, and return the
+	// metadata of the list.
+	ListItems(options metav1.ListOptions, fn func(obj runtime.Object) error) (*metav1.ListMeta, error)
+}
+
 // ListerWatcher is any object that knows how to perform an initial list and start a watch on a resource.
 type ListerWatcher interface {
 	Lister
@@ -48,6 +61,9 @@ type ListerWatcher interface {
 // ListFunc knows how to list resources
 type ListFunc func(options metav1.ListOptions) (runtime.Object, error)
 
+// ListItemsFunc knows how to list resources item by item
+type ListItemsFunc func(options metav1.ListOptions, fn func(obj runtime.Object) error) (*metav1.ListMeta, error)
+
 // WatchFunc knows how to watch resources
 type WatchFunc func(options metav1.ListOptions) (watch.Interface, error)
 
@@ -57,6 +73,9 @@ type WatchFunc func(options metav1.ListOptions) (watch.Interface, error)
 type ListWatch struct {
 	ListFunc  ListFunc
 	WatchFunc WatchFunc
+	// ListItemsFunc optionally lists resources item by item. If nil,
+	// ListItems uses ListFunc, and the Reflector lists with ListFunc.
+	ListItemsFunc ListItemsFunc
 	// DisableChunking requests no chunking for this list watcher.
 	DisableChunking bool
 }
@@ -96,7 +115,15 @@ func NewFilteredListWatchFromClient(c Getter, resource string, namespace string,
 			VersionedParams(&options, metav1.ParameterCodec).
 			Watch(context.TODO())
 	}
-	return &ListWatch{ListFunc: listFunc, WatchFunc: watchFunc}
+	listItemsFunc := func(options metav1.ListOptions, fn func(obj runtime.Object) error) (*metav1.ListMeta, error) {
+		optionsModifier(&options)
+		return c.Get().
+			Namespace(namespace).
+			Resource(resource).
+			VersionedParams(&options, metav1.ParameterCodec).
+			EachListItem(context.TODO(), fn)
+	}
+	return &ListWatch{ListFunc: listFunc, WatchFunc: watchFunc, ListItemsFunc: listItemsFunc}
 }
 
 // List a set of apiserver resources
@@ -106,7 +133,99 @@ func (lw *ListWatch) List(options metav1.ListOptions) (runtime.Object, error) {
 	return lw.ListFunc(options)
 }
 
+// ListItems lists a set of apiserver resources item by item
+func (lw *ListWatch) ListItems(options metav1.ListOptions, fn func(obj runtime.Object) error) (*metav1.ListMeta, error) {
+	if lw.ListItemsFunc == nil {
+		return listItems(lw, options, fn)
+	}
+	return lw.ListItemsFunc(options, fn)
+}
+
+// itemsLister returns lw as an ItemsLister if it lists item by item, rather
+// than only implementing ListItems on top of List.
+func itemsLister(lw ListerWatcher) (ItemsLister, bool) {
+	switch lw := lw.(type) {
+	case *ListWatch:
+		if lw.ListItemsFunc == nil {
+			return nil, false
+		}
+	case *tweakedListerWatcher:
+		if _, ok := itemsLister(lw.ListerWatcher); !ok {
+			return nil, false
+		}
+	}
+	il, ok := lw.(ItemsLister)
+	return il, ok
+}
+
+// listItems lists by means of lister and calls fn with every item of the list.
+func listItems(lister Lister, options metav1.ListOptions, fn func(obj runtime.Object) error) (*metav1.ListMeta, error) {
+	list, err := lister.List(options)
+	if err != nil {
+		return nil, err
+	}
+	m, err := meta.ListAccessor(list)
+	if err != nil {
+		return nil, fmt.Errorf("returned object must be a list: %v", err)
+	}
+	if err := meta.EachListItemWithAlloc(list, fn); err != nil {
+		return nil, err
+	}
+	return &metav1.ListMeta{
+		SelfLink:           m.GetSelfLink(),
+		ResourceVersion:    m.GetResourceVersion(),
+		Continue:           m.GetContinue(),
+		RemainingItemCount: m.GetRemainingItemCount(),
+	}, nil
+}
+
 // Watch a set of apiserver resources
 func (lw *ListWatch) Watch(options metav1.ListOptions) (watch.Interface, error) {
 	return lw.WatchFunc(options)
 }
+
+// tweakedListerWatcher applies a list options tweak, which can be replaced at
+// any time, before delegating to another ListerWatcher.
+type tweakedListerWatcher struct {
+	ListerWatcher
+
+	lock  sync.RWMutex
+	tweak func(*metav1.ListOptions)
+}
+
+func (lw *tweakedListerWatcher) setTweak(tweak func(*metav1.ListOptions)) {
+	lw.lock.Lock()
+	defer lw.lock.Unlock()
+	lw.tweak = tweak
+}
+
+func (lw *tweakedListerWatcher) apply(options *metav1.ListOptions) {
+	lw.lock.RLock()
+	tweak := lw.tweak
+	lw.lock.RUnlock()
+	if tweak != nil {
+		tweak(options)
+	}
+}
+
+// List applies the current tweak and delegates to the wrapped ListerWatcher.
+func (lw *tweakedListerWatcher) List(options metav1.ListOptions) (runtime.Object, error) {
+	lw.apply(&options)
+	return lw.ListerWatcher.List(options)
+}
+
+// ListItems applies the current tweak and delegates to the wrapped
+// ListerWatcher, item by item if it is an ItemsLister.
+func (lw *tweakedListerWatcher) ListItems(options metav1.ListOptions, fn func(obj runtime.Object) error) (*metav1.ListMeta, error) {
+	lw.apply(&options)
+	if il, ok := itemsLister(lw.ListerWatcher); ok {
+		return il.ListItems(options, fn)
+	}
+	return listItems(lw.ListerWatcher, options, fn)
+}
+
+// Watch applies the current tweak and delegates to the wrapped ListerWatcher.
+func (lw *tweakedListerWatcher) Watch(options metav1.ListOptions) (watch.Interface, error) {
+	lw.apply(&options)
+	return lw.ListerWatcher.Watch(options)
+}
@@ -0,0 +1,744 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+)
+
+// MultiNamespaceInformer is a SharedIndexInformer for the objects of a set of
+// namespaces. It runs one informer, and thus one reflector, per namespace, and
+// exposes their stores as a single Indexer and their notifications as a single
+// stream of events per handler.
+//
+// The Indexer returned by GetIndexer and GetStore is a view of the stores of
+// the per-namespace informers, so listers built on it see the objects of all
+// namespaces. Event handlers are never called concurrently, even though the
+// notifications originate from several informers.
+//
+// LastSyncResourceVersion always returns an empty string, since no single
+// resource version describes the state of several reflectors.
+type MultiNamespaceInformer interface {
+	SharedIndexInformer
+
+	// AddNamespace starts watching the given namespace. Its objects are
+	// delivered to the event handlers as additions. Adding a namespace
+	// which is already watched does nothing.
+	AddNamespace(namespace string) error
+	// RemoveNamespace stops watching the given namespace. Its objects are
+	// removed from the store and delivered to the event handlers as
+	// deletions, with a DeletedFinalStateUnknown tombstone. Removing a
+	// namespace which is not watched does nothing.
+	RemoveNamespace(namespace string) error
+	// Namespaces returns the watched namespaces, sorted.
+	Namespaces() []string
+}
+
+// NewMultiNamespaceInformer returns a MultiNamespaceInformer for the given
+// namespaces. newInformer is called to create the informer of each namespace,
+// both initially and when a namespace is added; it must return a new informer
+// which only lists and watches that namespace.
+func NewMultiNamespaceInformer(namespaces []string, newInformer func(namespace string) SharedIndexInformer) MultiNamespaceInformer {
+	m := &multiNamespaceInformer{
+		newInformer:   newInformer,
+		informers:     map[string]*namespaceInformer{},
+		registrations: map[*multiNamespaceRegistration]bool{},
+	}
+	for _, namespace := range namespaces {
+		if _, exists := m.informers[namespace]; exists {
+			continue
+		}
+		// Nothing was configured yet which could make this fail.
+		m.informers[namespace], _ = m.newNamespaceInformer(namespace)
+	}
+	return m
+}
+
+type multiNamespaceInformer struct {
+	newInformer func(namespace string) SharedIndexInformer
+
+	lock sync.Mutex
+	// informers maps each watched namespace to its informer.
+	informers map[string]*namespaceInformer
+	// registrations holds the event handlers added to this informer, which
+	// are added to the informer of every namespace.
+	registrations map[*multiNamespaceRegistration]bool
+
+	// The following configure the informers of namespaces added later on.
+	indexers          Indexers
+	transform         TransformFunc
+	watchErrorHandler WatchErrorHandler
+	tweak             func(*metav1.ListOptions)
+	tweakSet          bool
+
+	// stopCh is the channel passed to Run.
+	stopCh           <-chan struct{}
+	started, stopped bool
+}
+
+var _ MultiNamespaceInformer = &multiNamespaceInformer{}
+var _ ListOptionsUpdater = &multiNamespaceInformer{}
+var _ HandlerOptionsAdder = &multiNamespaceInformer{}
+
+// namespaceInformer is the informer of a single namespace.
+type namespaceInformer struct {
+	informer SharedIndexInformer
+	// stopCh is closed when the namespace is removed.
+	stopCh chan struct{}
+	// done is closed once the informer has stopped running.
+	done chan struct{}
+	// registrations maps the registrations of the multiNamespaceInformer to
+	// the handlers registered with informer.
+	registrations map[*multiNamespaceRegistration]*namespaceHandler
+}
+
+// multiNamespaceRegistration is the registration of an event handler with a
+// multiNamespaceInformer.
+type multiNamespaceRegistration struct {
+	informer *multiNamespaceInformer
+	handler  *serializedHandler
+	options  HandlerOptions
+}
+
+// HasSynced returns true once the handler has received the initial list of
+// every watched namespace.
+func (r *multiNamespaceRegistration) HasSynced() bool {
+	m := r.informer
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if !m.started || !m.registrations[r] {
+		return false
+	}
+	for _, ni := range m.informers {
+		if !ni.registrations[r].handle.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+// serializedHandler delivers the notifications of several informers to a
+// handler one at a time.
+type serializedHandler struct {
+	lock    sync.Mutex
+	handler ResourceEventHandler
+}
+
+func (h *serializedHandler) OnAdd(obj interface{}, isInInitialList bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.handler.OnAdd(obj, isInInitialList)
+}
+
+func (h *serializedHandler) OnUpdate(oldObj, newObj interface{}) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.handler.OnUpdate(oldObj, newObj)
+}
+
+func (h *serializedHandler) OnDelete(obj interface{}) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.handler.OnDelete(obj)
+}
+
+// namespaceHandler passes the notifications of the informer of a namespace
+// to the handler of a registration, and remembers the objects it delivered,
+// so that removing the namespace only delivers the deletion of objects the
+// handler has seen.
+type namespaceHandler struct {
+	handler *serializedHandler
+	handle  ResourceEventHandlerRegistration
+	// delivered maps the keys of the objects delivered to the handler, and
+	// not deleted since, to their last state. It is only used by the
+	// goroutine delivering the notifications of the informer, and once the
+	// informer has stopped.
+	delivered map[string]interface{}
+}
+
+func (h *namespaceHandler) OnAdd(obj interface{}, isInInitialList bool) {
+	h.record(obj)
+	h.handler.OnAdd(obj, isInInitialList)
+}
+
+func (h *namespaceHandler) OnUpdate(oldObj, newObj interface{}) {
+	h.record(newObj)
+	h.handler.OnUpdate(oldObj, newObj)
+}
+
+func (h *namespaceHandler) OnDelete(obj interface{}) {
+	if key, err := DeletionHandlingMetaNamespaceKeyFunc(obj); err == nil {
+		delete(h.delivered, key)
+	}
+	h.handler.OnDelete(obj)
+}
+
+func (h *namespaceHandler) record(obj interface{}) {
+	if key, err := DeletionHandlingMetaNamespaceKeyFunc(obj); err == nil {
+		h.delivered[key] = obj
+	}
+}
+
+// newNamespaceInformer creates and configures the informer of the given
+// namespace. m.lock must be held.
+func (m *multiNamespaceInformer) newNamespaceInformer(namespace string) (*namespaceInformer, error) {
+	ni := &namespaceInformer{
+		informer:      m.newInformer(namespace),
+		stopCh:        make(chan struct{}),
+		done:          make(chan struct{}),
+		registrations: map[*multiNamespaceRegistration]*namespaceHandler{},
+	}
+	if len(m.indexers) > 0 {
+		if err := ni.informer.AddIndexers(m.indexers); err != nil {
+			return nil, err
+		}
+	}
+	if m.transform != nil {
+		if err := ni.informer.SetTransform(m.transform); err != nil {
+			return nil, err
+		}
+	}
+	if m.watchErrorHandler != nil {
+		if err := ni.informer.SetWatchErrorHandler(m.watchErrorHandler); err != nil {
+			return nil, err
+		}
+	}
+	if m.tweakSet {
+		if err := updateListOptions(ni.informer, m.tweak); err != nil {
+			return nil, err
+		}
+	}
+	for r := range m.registrations {
+		if err := ni.addEventHandler(r); err != nil {
+			return nil, err
+		}
+	}
+	return ni, nil
+}
+
+func (ni *namespaceInformer) addEventHandler(r *multiNamespaceRegistration) error {
+	h := &namespaceHandler{handler: r.handler, delivered: map[string]interface{}{}}
+	handle, err := addEventHandlerWithOptions(ni.informer, h, r.options)
+	if err != nil {
+		return err
+	}
+	h.handle = handle
+	ni.registrations[r] = h
+	return nil
+}
+
+// run runs the informer of a namespace until either the namespace is removed
+// or m is stopped. m.lock must be held.
+func (m *multiNamespaceInformer) run(ni *namespaceInformer) {
+	stopCh := make(chan struct{})
+	go func() {
+		defer close(stopCh)
+		select {
+		case <-m.stopCh:
+		case <-ni.stopCh:
+		}
+	}()
+	go func() {
+		defer close(ni.done)
+		ni.informer.Run(stopCh)
+	}()
+}
+
+func (m *multiNamespaceInformer) Run(stopCh <-chan struct{}) {
+	m.lock.Lock()
+	if m.started {
+		m.lock.Unlock()
+		klog.Warningf("The multiNamespaceInformer has started, run more than once is not allowed")
+		return
+	}
+	m.started = true
+	m.stopCh = stopCh
+	for _, ni := range m.informers {
+		m.run(ni)
+	}
+	m.lock.Unlock()
+
+	<-stopCh
+
+	m.lock.Lock()
+	m.stopped = true
+	var running []*namespaceInformer
+	for _, ni := range m.informers {
+		running = append(running, ni)
+	}
+	m.lock.Unlock()
+	for _, ni := range running {
+		<-ni.done
+	}
+}
+
+func (m *multiNamespaceInformer) AddNamespace(namespace string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.stopped {
+		return fmt.Errorf("informer has already stopped")
+	}
+	if _, exists := m.informers[namespace]; exists {
+		return nil
+	}
+	ni, err := m.newNamespaceInformer(namespace)
+	if err != nil {
+		return fmt.Errorf("failed to add namespace %q: %w", namespace, err)
+	}
+	m.informers[namespace] = ni
+	if m.started {
+		m.run(ni)
+	}
+	return nil
+}
+
+func (m *multiNamespaceInformer) RemoveNamespace(namespace string) error {
+	m.lock.Lock()
+	ni, exists := m.informers[namespace]
+	if !exists {
+		m.lock.Unlock()
+		return nil
+	}
+	delete(m.informers, namespace)
+	started := m.started
+	m.lock.Unlock()
+
+	if !started {
+		// Nothing was delivered to the handlers yet.
+		return nil
+	}
+	close(ni.stopCh)
+	<-ni.done
+
+	// Each handler is told about the deletion of the objects it has seen,
+	// which may be fewer than those in the store if the informer stopped
+	// before delivering every notification.
+	for _, h := range ni.registrations {
+		for key, obj := range h.delivered {
+			h.handler.OnDelete(DeletedFinalStateUnknown{Key: key, Obj: obj})
+		}
+	}
+	return nil
+}
+
+func (m *multiNamespaceInformer) Namespaces() []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	namespaces := make([]string, 0, len(m.informers))
+	for namespace := range m.informers {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+func (m *multiNamespaceInformer) AddEventHandler(handler ResourceEventHandler) (ResourceEventHandlerRegistration, error) {
+	return m.AddEventHandlerWithOptions(handler, HandlerOptions{})
+}
+
+func (m *multiNamespaceInformer) AddEventHandlerWithResyncPeriod(handler ResourceEventHandler, resyncPeriod time.Duration) (ResourceEventHandlerRegistration, error) {
+	return m.AddEventHandlerWithOptions(handler, HandlerOptions{ResyncPeriod: &resyncPeriod})
+}
+
+// AddEventHandlerWithOptions adds the handler to the informer of every
+// namespace with the given options. A PanicPolicy applies to each namespace
+// separately.
+func (m *multiNamespaceInformer) AddEventHandlerWithOptions(handler ResourceEventHandler, options HandlerOptions) (ResourceEventHandlerRegistration, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.stopped {
+		return nil, fmt.Errorf("handler %v was not added to shared informer because it has stopped already", handler)
+	}
+	r := &multiNamespaceRegistration{informer: m, handler: &serializedHandler{handler: handler}, options: options}
+	for _, ni := range m.informers {
+		if err := ni.addEventHandler(r); err != nil {
+			m.removeEventHandler(r)
+			return nil, err
+		}
+	}
+	m.registrations[r] = true
+	return r, nil
+}
+
+func (m *multiNamespaceInformer) RemoveEventHandler(handle ResourceEventHandlerRegistration) error {
+	r, ok := handle.(*multiNamespaceRegistration)
+	if !ok || r.informer != m {
+		return fmt.Errorf("handle %v was not returned by this informer", handle)
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.removeEventHandler(r)
+}
+
+// removeEventHandler removes r from m and from the informer of every
+// namespace. m.lock must be held.
+func (m *multiNamespaceInformer) removeEventHandler(r *multiNamespaceRegistration) error {
+	delete(m.registrations, r)
+	var errs []error
+	for _, ni := range m.informers {
+		h, ok := ni.registrations[r]
+		if !ok {
+			continue
+		}
+		delete(ni.registrations, r)
+		if err := ni.informer.RemoveEventHandler(h.handle); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to remove handler: %v", errs)
+	}
+	return nil
+}
+
+func (m *multiNamespaceInformer) GetStore() Store {
+	return &multiNamespaceIndexer{informer: m}
+}
+
+func (m *multiNamespaceInformer) GetIndexer() Indexer {
+	return &multiNamespaceIndexer{informer: m}
+}
+
+func (m *multiNamespaceInformer) GetController() Controller {
+	return &multiNamespaceController{informer: m}
+}
+
+func (m *multiNamespaceInformer) HasSynced() bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if !m.started {
+		return false
+	}
+	for _, ni := range m.informers {
+		if !ni.informer.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *multiNamespaceInformer) LastSyncResourceVersion() string {
+	return ""
+}
+
+func (m *multiNamespaceInformer) SetWatchErrorHandler(handler WatchErrorHandler) error {
+	return m.configure(func(informer SharedIndexInformer) error {
+		return informer.SetWatchErrorHandler(handler)
+	}, func() {
+		m.watchErrorHandler = handler
+	})
+}
+
+func (m *multiNamespaceInformer) SetTransform(handler TransformFunc) error {
+	return m.configure(func(informer SharedIndexInformer) error {
+		return informer.SetTransform(handler)
+	}, func() {
+		m.transform = handler
+	})
+}
+
+func (m *multiNamespaceInformer) AddIndexers(indexers Indexers) error {
+	return m.configure(func(informer SharedIndexInformer) error {
+		return informer.AddIndexers(indexers)
+	}, func() {
+		if m.indexers == nil {
+			m.indexers = Indexers{}
+		}
+		for name, indexFunc := range indexers {
+			m.indexers[name] = indexFunc
+		}
+	})
+}
+
+// configure applies a setting which may only be changed before the informer
+// starts to the informer of every namespace, and records it for namespaces
+// added later on.
+func (m *multiNamespaceInformer) configure(apply func(SharedIndexInformer) error, record func()) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.started {
+		return fmt.Errorf("informer has already started")
+	}
+	for _, ni := range m.informers {
+		if err := apply(ni.informer); err != nil {
+			return err
+		}
+	}
+	record()
+	return nil
+}
+
+func (m *multiNamespaceInformer) UpdateListOptions(tweak func(options *metav1.ListOptions)) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.stopped {
+		return fmt.Errorf("informer has already stopped")
+	}
+	for _, ni := range m.informers {
+		if err := updateListOptions(ni.informer, tweak); err != nil {
+			return err
+		}
+	}
+	m.tweak = tweak
+	m.tweakSet = true
+	return nil
+}
+
+// addEventHandlerWithOptions adds handler to informer with the given options.
+// Informers which do not implement HandlerOptionsAdder only support a
+// ResyncPeriod.
+func addEventHandlerWithOptions(informer SharedIndexInformer, handler ResourceEventHandler, options HandlerOptions) (ResourceEventHandlerRegistration, error) {
+	if adder, ok := informer.(HandlerOptionsAdder); ok {
+		return adder.AddEventHandlerWithOptions(handler, options)
+	}
+	if options.PanicPolicy != nil {
+		return nil, fmt.Errorf("informer of type %T does not support handler panic policies", informer)
+	}
+	if options.ResyncPeriod != nil {
+		return informer.AddEventHandlerWithResyncPeriod(handler, *options.ResyncPeriod)
+	}
+	return informer.AddEventHandler(handler)
+}
+
+func updateListOptions(informer SharedIndexInformer, tweak func(options *metav1.ListOptions)) error {
+	updater, ok := informer.(ListOptionsUpdater)
+	if !ok {
+		return fmt.Errorf("informer of type %T does not support updating list options", informer)
+	}
+	return updater.UpdateListOptions(tweak)
+}
+
+func (m *multiNamespaceInformer) IsStopped() bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.stopped
+}
+
+// namespaceIndexers returns the indexers of the watched namespaces, sorted by
+// namespace.
+func (m *multiNamespaceInformer) namespaceIndexers() []Indexer {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	namespaces := make([]string, 0, len(m.informers))
+	for namespace := range m.informers {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	indexers := make([]Indexer, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		indexers = append(indexers, m.informers[namespace].informer.GetIndexer())
+	}
+	return indexers
+}
+
+// indexerFor returns the indexer of the given namespace, or nil if the
+// namespace is not watched.
+func (m *multiNamespaceInformer) indexerFor(namespace string) Indexer {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if ni, ok := m.informers[namespace]; ok {
+		return ni.informer.GetIndexer()
+	}
+	return nil
+}
+
+// multiNamespaceController hides the per-namespace controllers, like
+// dummyController.
+type multiNamespaceController struct {
+	informer *multiNamespaceInformer
+}
+
+func (c *multiNamespaceController) Run(stopCh <-chan struct{}) {
+}
+
+func (c *multiNamespaceController) HasSynced() bool {
+	return c.informer.HasSynced()
+}
+
+func (c *multiNamespaceController) LastSyncResourceVersion() string {
+	return ""
+}
+
+// multiNamespaceIndexer is the Indexer of a multiNamespaceInformer. It
+// combines the indexers of the watched namespaces.
+type multiNamespaceIndexer struct {
+	informer *multiNamespaceInformer
+}
+
+var _ Indexer = &multiNamespaceIndexer{}
+
+// indexerForObject returns the indexer of the namespace of obj.
+func (i *multiNamespaceIndexer) indexerForObject(obj interface{}) (Indexer, error) {
+	key, err := DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return nil, err
+	}
+	namespace, _, err := SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil, err
+	}
+	indexer := i.informer.indexerFor(namespace)
+	if indexer == nil {
+		return nil, fmt.Errorf("namespace %q is not watched by this informer", namespace)
+	}
+	return indexer, nil
+}
+
+func (i *multiNamespaceIndexer) Add(obj interface{}) error {
+	indexer, err := i.indexerForObject(obj)
+	if err != nil {
+		return err
+	}
+	return indexer.Add(obj)
+}
+
+func (i *multiNamespaceIndexer) Update(obj interface{}) error {
+	indexer, err := i.indexerForObject(obj)
+	if err != nil {
+		return err
+	}
+	return indexer.Update(obj)
+}
+
+func (i *multiNamespaceIndexer) Delete(obj interface{}) error {
+	indexer, err := i.indexerForObject(obj)
+	if err != nil {
+		return err
+	}
+	return indexer.Delete(obj)
+}
+
+func (i *multiNamespaceIndexer) List() []interface{} {
+	var items []interface{}
+	for _, indexer := range i.informer.namespaceIndexers() {
+		items = append(items, indexer.List()...)
+	}
+	return items
+}
+
+func (i *multiNamespaceIndexer) ListKeys() []string {
+	var keys []string
+	for _, indexer := range i.informer.namespaceIndexers() {
+		keys = append(keys, indexer.ListKeys()...)
+	}
+	return keys
+}
+
+func (i *multiNamespaceIndexer) Get(obj interface{}) (item interface{}, exists bool, err error) {
+	key, err := DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return nil, false, KeyError{obj, err}
+	}
+	return i.GetByKey(key)
+}
+
+func (i *multiNamespaceIndexer) GetByKey(key string) (item interface{}, exists bool, err error) {
+	namespace, _, err := SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil, false, err
+	}
+	indexer := i.informer.indexerFor(namespace)
+	if indexer == nil {
+		return nil, false, nil
+	}
+	return indexer.GetByKey(key)
+}
+
+func (i *multiNamespaceIndexer) Replace(list []interface{}, resourceVersion string) error {
+	return fmt.Errorf("the store of a multi-namespace informer cannot be replaced")
+}
+
+func (i *multiNamespaceIndexer) Resync() error {
+	return nil
+}
+
+func (i *multiNamespaceIndexer) Index(indexName string, obj interface{}) ([]interface{}, error) {
+	var items []interface{}
+	for _, indexer := range i.informer.namespaceIndexers() {
+		indexed, err := indexer.Index(indexName, obj)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, indexed...)
+	}
+	return items, nil
+}
+
+func (i *multiNamespaceIndexer) IndexKeys(indexName, indexedValue string) ([]string, error) {
+	var keys []string
+	for _, indexer := range i.informer.namespaceIndexers() {
+		indexed, err := indexer.IndexKeys(indexName, indexedValue)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, indexed...)
+	}
+	return keys, nil
+}
+
+func (i *multiNamespaceIndexer) ListIndexFuncValues(indexName string) []string {
+	values := sets.NewString()
+	for _, indexer := range i.informer.namespaceIndexers() {
+		values.Insert(indexer.ListIndexFuncValues(indexName)...)
+	}
+	return values.UnsortedList()
+}
+
+func (i *multiNamespaceIndexer) ByIndex(indexName, indexedValue string) ([]interface{}, error) {
+	var items []interface{}
+	for _, indexer := range i.informer.namespaceIndexers() {
+		indexed, err := indexer.ByIndex(indexName, indexedValue)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, indexed...)
+	}
+	return items, nil
+}
+
+func (i *multiNamespaceIndexer) GetIndexers() Indexers {
+	indexers := Indexers{}
+	for _, indexer := range i.informer.namespaceIndexers() {
+		for name, indexFunc := range indexer.GetIndexers() {
+			indexers[name] = indexFunc
+		}
+	}
+	i.informer.lock.Lock()
+	defer i.informer.lock.Unlock()
+	for name, indexFunc := range i.informer.indexers {
+		indexers[name] = indexFunc
+	}
+	return indexers
+}
+
+func (i *multiNamespaceIndexer) AddIndexers(newIndexers Indexers) error {
+	return i.informer.AddIndexers(newIndexers)
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	fcache "k8s.io/client-go/tools/cache/testing"
+)
+
+func TestMultiNamespaceInformer(t *testing.T) {
+	sources := map[string]*fcache.FakeControllerSource{}
+	for _, namespace := range []string{"ns1", "ns2", "ns3"} {
+		sources[namespace] = fcache.NewFakeControllerSource()
+		sources[namespace].Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: namespace}})
+	}
+	informer := NewMultiNamespaceInformer([]string{"ns1", "ns2"}, func(namespace string) SharedIndexInformer {
+		return NewSharedIndexInformer(sources[namespace], &v1.Pod{}, 0, Indexers{NamespaceIndex: MetaNamespaceIndexFunc})
+	})
+
+	var lock sync.Mutex
+	var events []string
+	active := 0
+	record := func(event string, obj interface{}) {
+		key, _ := DeletionHandlingMetaNamespaceKeyFunc(obj)
+		lock.Lock()
+		active++
+		if active > 1 {
+			t.Errorf("handler called concurrently")
+		}
+		events = append(events, event+" "+key)
+		lock.Unlock()
+		time.Sleep(time.Millisecond)
+		lock.Lock()
+		active--
+		lock.Unlock()
+	}
+	handle, err := informer.AddEventHandler(ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { record("add", obj) },
+		UpdateFunc: func(_, obj interface{}) { record("update", obj) },
+		DeleteFunc: func(obj interface{}) { record("delete", obj) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForEvents := func(expected ...string) {
+		t.Helper()
+		err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			return len(events) == len(expected), nil
+		})
+		lock.Lock()
+		defer lock.Unlock()
+		if err != nil || !sets.NewString(events...).Equal(sets.NewString(expected...)) {
+			t.Fatalf("expected events %v, got %v", expected, events)
+		}
+		events = nil
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	if !WaitForCacheSync(stop, informer.HasSynced, handle.HasSynced) {
+		t.Fatal("cache did not sync")
+	}
+	waitForEvents("add ns1/pod", "add ns2/pod")
+
+	keys := informer.GetStore().ListKeys()
+	if !sets.NewString(keys...).Equal(sets.NewString("ns1/pod", "ns2/pod")) {
+		t.Errorf("unexpected keys %v", keys)
+	}
+	if _, exists, _ := informer.GetIndexer().GetByKey("ns2/pod"); !exists {
+		t.Error("expected ns2/pod to exist")
+	}
+	if pods, err := informer.GetIndexer().ByIndex(NamespaceIndex, "ns1"); err != nil || len(pods) != 1 {
+		t.Errorf("expected one pod in ns1, got %v, %v", pods, err)
+	}
+
+	sources["ns2"].Modify(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns2"}})
+	waitForEvents("update ns2/pod")
+
+	if err := informer.AddNamespace("ns3"); err != nil {
+		t.Fatal(err)
+	}
+	waitForEvents("add ns3/pod")
+	if err := informer.RemoveNamespace("ns1"); err != nil {
+		t.Fatal(err)
+	}
+	waitForEvents("delete ns1/pod")
+
+	if got := informer.Namespaces(); !sets.NewString(got...).Equal(sets.NewString("ns2", "ns3")) {
+		t.Errorf("unexpected namespaces %v", got)
+	}
+	if _, exists, _ := informer.GetIndexer().GetByKey("ns1/pod"); exists {
+		t.Error("expected ns1/pod to be gone")
+	}
+	if !handle.HasSynced() {
+		t.Error("expected handler to be synced")
+	}
+}
+
+func TestMultiNamespaceInformerRemoveNamespaceOnlyDeletesDelivered(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	for i := 0; i < 20; i++ {
+		source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("pod%d", i), Namespace: "ns"}})
+	}
+	informer := NewMultiNamespaceInformer([]string{"ns"}, func(namespace string) SharedIndexInformer {
+		return NewSharedIndexInformer(source, &v1.Pod{}, 0, Indexers{})
+	})
+
+	var lock sync.Mutex
+	added := sets.NewString()
+	deleted := sets.NewString()
+	blocked := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	if _, err := informer.AddEventHandler(ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			once.Do(func() {
+				close(blocked)
+				<-release
+			})
+			key, _ := MetaNamespaceKeyFunc(obj)
+			lock.Lock()
+			defer lock.Unlock()
+			added.Insert(key)
+		},
+		DeleteFunc: func(obj interface{}) {
+			key, _ := DeletionHandlingMetaNamespaceKeyFunc(obj)
+			lock.Lock()
+			defer lock.Unlock()
+			deleted.Insert(key)
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	<-blocked
+
+	// The namespace is removed while the handler is still busy with the
+	// first addition, so it never receives most of the others.
+	removed := make(chan error)
+	go func() { removed <- informer.RemoveNamespace("ns") }()
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	if err := <-removed; err != nil {
+		t.Fatal(err)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if !added.Equal(deleted) {
+		t.Errorf("expected deletions %v to match additions %v", deleted.List(), added.List())
+	}
+	if added.Len() == 0 {
+		t.Errorf("expected the blocked addition to be delivered")
+	}
+}
@@ -0,0 +1,229 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+// This file implements "read your writes" on top of a shared informer: a
+// caller that has just written an object can wait until the informer's
+// store reflects that write before acting on the store's contents again.
+//
+// Resource versions are compared numerically. They are opaque in general,
+// but every apiserver backed by etcd uses integers. A resource version that
+// cannot be parsed only matches the exact same string.
+
+// WaitForObservedResourceVersion blocks until the informer's store holds the
+// object with the given key at the given resource version or a later one, or
+// until ctx is done. It also returns if the informer observes the deletion
+// of the object in the meantime, since the write has been superseded then.
+//
+// The informer must have been created by this package. Waiters are released
+// by the informer's delta processing, right after the store is updated.
+func WaitForObservedResourceVersion(ctx context.Context, informer SharedInformer, key, resourceVersion string) error {
+	return waitForObservation(ctx, informer, key, resourceVersion)
+}
+
+// WaitForObservedDeletion blocks until the informer's store no longer holds
+// the object with the given key, or until ctx is done.
+//
+// The informer must have been created by this package.
+func WaitForObservedDeletion(ctx context.Context, informer SharedInformer, key string) error {
+	return waitForObservation(ctx, informer, key, "")
+}
+
+func waitForObservation(ctx context.Context, informer SharedInformer, key, resourceVersion string) error {
+	s, ok := informer.(*sharedIndexInformer)
+	if !ok {
+		return fmt.Errorf("informer of type %T does not support waiting for observations", informer)
+	}
+	done := make(chan struct{})
+	cancel := s.observations.wait(s.indexer, key, resourceVersion, func() { close(done) })
+	defer cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ItemAdder is the part of a work queue used by AddAfterObserved. It is
+// satisfied by workqueue.Interface.
+type ItemAdder interface {
+	Add(item interface{})
+}
+
+// AddAfterObserved adds item to queue once the informer's store holds the
+// object with the given key at the given resource version or a later one,
+// or has observed its deletion. An empty resourceVersion waits for the
+// deletion of the object only. If that has not happened within timeout, as
+// measured by the informer's clock, the item is added anyway, so that a
+// write that is never observed, for example because the informer was
+// stopped, does not leave the item behind.
+//
+// This allows a worker that has just written an object to defer the next
+// reconciliation of the corresponding key until its cache has caught up,
+// instead of immediately working from stale data.
+func AddAfterObserved(queue ItemAdder, item interface{}, informer SharedInformer, key, resourceVersion string, timeout time.Duration) {
+	s, ok := informer.(*sharedIndexInformer)
+	if !ok {
+		queue.Add(item)
+		return
+	}
+	timer := s.clock.NewTimer(timeout)
+	observed := make(chan struct{})
+	cancel := s.observations.wait(s.indexer, key, resourceVersion, func() { close(observed) })
+	go func() {
+		select {
+		case <-observed:
+			timer.Stop()
+		case <-timer.C():
+			cancel()
+		}
+		queue.Add(item)
+	}()
+}
+
+// HasObservedResourceVersion returns true if the store holds the object with
+// the given key at the given resource version or a later one. If
+// resourceVersion is empty, it returns true if the store does not hold the
+// object.
+func HasObservedResourceVersion(store Store, key, resourceVersion string) (bool, error) {
+	obj, exists, err := store.GetByKey(key)
+	if err != nil {
+		return false, err
+	}
+	if resourceVersion == "" {
+		return !exists, nil
+	}
+	return exists && resourceVersionAtLeast(obj, resourceVersion), nil
+}
+
+func resourceVersionAtLeast(obj interface{}, resourceVersion string) bool {
+	objMeta, err := meta.Accessor(obj)
+	if err != nil {
+		return false
+	}
+	objRV := objMeta.GetResourceVersion()
+	if objRV == resourceVersion {
+		return true
+	}
+	have, err := strconv.ParseUint(objRV, 10, 64)
+	if err != nil {
+		return false
+	}
+	want, err := strconv.ParseUint(resourceVersion, 10, 64)
+	if err != nil {
+		return false
+	}
+	return have >= want
+}
+
+// observationWaiters tracks the callers waiting for the store of an informer
+// to observe a write.
+type observationWaiters struct {
+	lock    sync.Mutex
+	waiters map[string]map[*observationWaiter]struct{}
+}
+
+type observationWaiter struct {
+	// resourceVersion is the minimum resource version to wait for. An
+	// empty resourceVersion only waits for the deletion of the object.
+	resourceVersion string
+	notify          func()
+}
+
+// wait registers notify to be called once store satisfies the condition
+// described in HasObservedResourceVersion for key and resourceVersion, or
+// once the deletion of the object is observed. notify may be called before
+// wait returns. The returned function releases the waiter if it is no longer
+// of interest.
+func (o *observationWaiters) wait(store Store, key, resourceVersion string, notify func()) func() {
+	w := &observationWaiter{resourceVersion: resourceVersion, notify: notify}
+
+	// The waiter is registered before the store is checked: a concurrent
+	// store update either happened before the check or notifies the waiter.
+	o.lock.Lock()
+	if o.waiters == nil {
+		o.waiters = map[string]map[*observationWaiter]struct{}{}
+	}
+	if o.waiters[key] == nil {
+		o.waiters[key] = map[*observationWaiter]struct{}{}
+	}
+	o.waiters[key][w] = struct{}{}
+	o.lock.Unlock()
+
+	cancel := func() { o.remove(key, w) }
+	if observed, err := HasObservedResourceVersion(store, key, resourceVersion); err == nil && observed {
+		if o.remove(key, w) {
+			notify()
+		}
+	}
+	return cancel
+}
+
+// remove unregisters w and returns true if it was still registered.
+func (o *observationWaiters) remove(key string, w *observationWaiter) bool {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if _, ok := o.waiters[key][w]; !ok {
+		return false
+	}
+	delete(o.waiters[key], w)
+	if len(o.waiters[key]) == 0 {
+		delete(o.waiters, key)
+	}
+	return true
+}
+
+// observe releases the waiters satisfied by the store now holding obj, or
+// no longer holding it if deleted is true.
+func (o *observationWaiters) observe(obj interface{}, deleted bool) {
+	o.lock.Lock()
+	if len(o.waiters) == 0 {
+		o.lock.Unlock()
+		return
+	}
+	key, err := DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		o.lock.Unlock()
+		return
+	}
+	var notify []func()
+	for w := range o.waiters[key] {
+		if deleted || (w.resourceVersion != "" && resourceVersionAtLeast(obj, w.resourceVersion)) {
+			notify = append(notify, w.notify)
+			delete(o.waiters[key], w)
+		}
+	}
+	if len(o.waiters[key]) == 0 {
+		delete(o.waiters, key)
+	}
+	o.lock.Unlock()
+
+	for _, f := range notify {
+		f()
+	}
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	fcache "k8s.io/client-go/tools/cache/testing"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestHasObservedResourceVersion(t *testing.T) {
+	store := NewStore(MetaNamespaceKeyFunc)
+	store.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns", ResourceVersion: "10"}})
+	store.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "opaque", Namespace: "ns", ResourceVersion: "abc"}})
+
+	for _, tc := range []struct {
+		key, resourceVersion string
+		expected             bool
+	}{
+		{"ns/pod", "9", true},
+		{"ns/pod", "10", true},
+		{"ns/pod", "11", false},
+		{"ns/pod", "", false},
+		{"ns/missing", "1", false},
+		{"ns/missing", "", true},
+		{"ns/opaque", "abc", true},
+		{"ns/opaque", "1", false},
+	} {
+		observed, err := HasObservedResourceVersion(store, tc.key, tc.resourceVersion)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if observed != tc.expected {
+			t.Errorf("%s at %q: expected %v, got %v", tc.key, tc.resourceVersion, tc.expected, observed)
+		}
+	}
+}
+
+func TestWaitForObservedResourceVersion(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns"}})
+	informer := NewSharedInformer(source, &v1.Pod{}, 0)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	if !WaitForCacheSync(stop, informer.HasSynced) {
+		t.Fatal("cache did not sync")
+	}
+
+	// Already observed.
+	if err := WaitForObservedResourceVersion(context.Background(), informer, "ns/pod1", "1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// Not observed before the context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := WaitForObservedResourceVersion(ctx, informer, "ns/pod1", "2"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+
+	// Observed while waiting.
+	updated := make(chan error)
+	go func() {
+		updated <- WaitForObservedResourceVersion(context.Background(), informer, "ns/pod1", "2")
+	}()
+	deleted := make(chan error)
+	go func() {
+		deleted <- WaitForObservedDeletion(context.Background(), informer, "ns/pod1")
+	}()
+
+	source.Modify(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns"}})
+	select {
+	case err := <-updated:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("update was not observed")
+	}
+
+	source.Delete(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns"}})
+	select {
+	case err := <-deleted:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("deletion was not observed")
+	}
+
+	if err := WaitForObservedDeletion(context.Background(), informer, "ns/pod1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(informer.(*sharedIndexInformer).observations.waiters) != 0 {
+		t.Errorf("expected all waiters to be released")
+	}
+}
+
+type chanItemAdder chan interface{}
+
+func (c chanItemAdder) Add(item interface{}) {
+	c <- item
+}
+
+func TestAddAfterObserved(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	informer := NewSharedInformer(source, &v1.Pod{}, 0)
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	informer.(*sharedIndexInformer).clock = fakeClock
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	if !WaitForCacheSync(stop, informer.HasSynced) {
+		t.Fatal("cache did not sync")
+	}
+
+	queue := make(chanItemAdder, 2)
+	AddAfterObserved(queue, "ns/pod1", informer, "ns/pod1", "1", time.Hour)
+	AddAfterObserved(queue, "timeout", informer, "ns/pod2", "1", time.Minute)
+	select {
+	case item := <-queue:
+		t.Fatalf("expected no item before the write is observed or the timeout expires, got %v", item)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	fakeClock.Step(time.Minute)
+	select {
+	case item := <-queue:
+		if item != "timeout" {
+			t.Errorf("expected the item to be added after the timeout, got %v", item)
+		}
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("expected the item to be added after the timeout")
+	}
+
+	source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns"}})
+	select {
+	case item := <-queue:
+		if item != "ns/pod1" {
+			t.Errorf("expected the item to be added once observed, got %v", item)
+		}
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("expected the item to be added once observed")
+	}
+
+	fakeClock.Step(time.Hour)
+	select {
+	case item := <-queue:
+		t.Errorf("expected the item to be added exactly once, got %v", item)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
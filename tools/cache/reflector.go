@@ -108,6 +108,14 @@ type Reflector struct {
 	//
 	// See https://github.com/kubernetes/enhancements/tree/master/keps/sig-api-machinery/3157-watch-list#design-details
 	UseWatchList bool
+	// relistCh receives a value when the reflector should drop its watch and relist,
+	// see requestRelist.
+	relistCh chan struct{}
+	// relistLock guards nextListerWatcher
+	relistLock sync.Mutex
+	// nextListerWatcher, if set, replaces listerWatcher when the reflector next
+	// lists, see requestRelist.
+	nextListerWatcher ListerWatcher
 }
 
 // ResourceVersionUpdater is an interface that allows store implementation to
@@ -223,6 +231,7 @@ func NewReflectorWithOptions(lw ListerWatcher, expectedType interface{}, store S
 		clock:             reflectorClock,
 		watchErrorHandler: WatchErrorHandler(DefaultWatchErrorHandler),
 		expectedType:      reflect.TypeOf(expectedType),
+		relistCh:          make(chan struct{}, 1),
 	}
 
 	if r.name == "" {
@@ -302,6 +311,9 @@ var (
 	// Used to indicate that watching stopped because of a signal from the stop
 	// channel passed in from a client of the reflector.
 	errorStopRequested = errors.New("stop requested")
+
+	// Used to indicate that watching stopped because a relist was requested.
+	errorRelistRequested = errors.New("relist requested")
 )
 
 // resyncChan returns a channel which will receive something when a resync is
@@ -323,6 +335,12 @@ func (r *Reflector) resyncChan() (<-chan time.Time, func() bool) {
 // It returns error if ListAndWatch didn't even try to initialize watch.
 func (r *Reflector) ListAndWatch(stopCh <-chan struct{}) error {
 	klog.V(3).Infof("Listing and watching %v from %s", r.typeDescription, r.name)
+	r.relistLock.Lock()
+	if r.nextListerWatcher != nil {
+		r.listerWatcher = r.nextListerWatcher
+		r.nextListerWatcher = nil
+	}
+	r.relistLock.Unlock()
 	var err error
 	var w watch.Interface
 	fallbackToList := !r.UseWatchList
@@ -353,13 +371,45 @@ func (r *Reflector) ListAndWatch(stopCh <-chan struct{}) error {
 
 	klog.V(2).Infof("Caches populated for %v from %s", r.typeDescription, r.name)
 
-	resyncerrc := make(chan error, 1)
+	// Both startResync and watchForRelist send at most one error.
+	resyncerrc := make(chan error, 2)
 	cancelCh := make(chan struct{})
 	defer close(cancelCh)
 	go r.startResync(stopCh, cancelCh, resyncerrc)
+	go r.watchForRelist(stopCh, cancelCh, resyncerrc)
 	return r.watch(w, stopCh, resyncerrc)
 }
 
+// requestRelist makes the reflector drop its current watch and list again, for
+// example because the options used by its ListerWatcher changed. If the
+// reflector is listing, it lists once more after that. If lw is not nil, it
+// replaces the reflector's ListerWatcher from that list on.
+func (r *Reflector) requestRelist(lw ListerWatcher) {
+	if lw != nil {
+		r.relistLock.Lock()
+		r.nextListerWatcher = lw
+		r.relistLock.Unlock()
+	}
+	select {
+	case r.relistCh <- struct{}{}:
+	default:
+		// A relist is already pending.
+	}
+}
+
+// watchForRelist ends the current watch through errc once a relist is
+// requested.
+// Note that this method is blocking and should be
+// called in a separate goroutine.
+func (r *Reflector) watchForRelist(stopCh <-chan struct{}, cancelCh <-chan struct{}, errc chan error) {
+	select {
+	case <-r.relistCh:
+		errc <- errorRelistRequested
+	case <-stopCh:
+	case <-cancelCh:
+	}
+}
+
 // startResync periodically calls r.store.Resync() method.
 // Note that this method is blocking and should be
 // called in a separate goroutine.
@@ -440,6 +490,8 @@ func (r *Reflector) watch(w watch.Interface, stopCh <-chan struct{}, resyncerrc
 		if err != nil {
 			if err != errorStopRequested {
 				switch {
+				case err == errorRelistRequested:
+					klog.V(2).Infof("%s: relisting %v as requested", r.name, r.typeDescription)
 				case isExpiredError(err):
 					// Don't set LastSyncResourceVersionUnavailable - LIST call with ResourceVersion=RV already
 					// has a semantic that it returns data at least as fresh as provided RV.
@@ -489,6 +541,14 @@ func (r *Reflector) list(stopCh <-chan struct{}) error {
 		pager := pager.New(pager.SimplePageFunc(func(opts metav1.ListOptions) (runtime.Object, error) {
 			return r.listerWatcher.List(opts)
 		}))
+		if il, ok := itemsLister(r.listerWatcher); ok {
+			// Decode the items one at a time rather than buffering every
+			// page and decoding it into a list. The items themselves are
+			// still collected, to replace the store with.
+			pager.PageItemsFn = func(ctx context.Context, opts metav1.ListOptions, fn func(obj runtime.Object) error) (*metav1.ListMeta, error) {
+				return il.ListItems(opts, fn)
+			}
+		}
 		switch {
 		case r.WatchListPageSize != 0:
 			pager.PageSize = r.WatchListPageSize
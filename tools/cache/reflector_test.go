@@ -1614,3 +1614,74 @@ func BenchmarkReflectorList(b *testing.B) {
 		})
 	}
 }
+
+func TestReflectorListItems(t *testing.T) {
+	pages := map[string][]string{"": {"a", "b"}, "next": {"c"}}
+	lw := &ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			t.Errorf("unexpected call to ListFunc")
+			return nil, fmt.Errorf("unexpected call")
+		},
+		ListItemsFunc: func(options metav1.ListOptions, fn func(obj runtime.Object) error) (*metav1.ListMeta, error) {
+			for _, name := range pages[options.Continue] {
+				if err := fn(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, ResourceVersion: "1"}}); err != nil {
+					return nil, err
+				}
+			}
+			if options.Continue == "" {
+				return &metav1.ListMeta{ResourceVersion: "10", Continue: "next"}, nil
+			}
+			return &metav1.ListMeta{ResourceVersion: "10"}, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return watch.NewFake(), nil
+		},
+	}
+	store := NewStore(MetaNamespaceKeyFunc)
+	r := NewReflector(lw, &v1.Pod{}, store, 0)
+	r.WatchListPageSize = 2
+
+	if err := r.list(wait.NeverStop); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := []string{"a", "b", "c"}, store.ListKeys(); !sets.NewString(e...).Equal(sets.NewString(a...)) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if e, a := "10", r.LastSyncResourceVersion(); e != a {
+		t.Errorf("expected resource version %q, got %q", e, a)
+	}
+}
+
+func TestReflectorListWithoutListItemsFunc(t *testing.T) {
+	listCalls := 0
+	lw := &ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			listCalls++
+			return &v1.PodList{
+				ListMeta: metav1.ListMeta{ResourceVersion: "10"},
+				Items:    []v1.Pod{{ObjectMeta: metav1.ObjectMeta{Name: "a", ResourceVersion: "1"}}},
+			}, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return watch.NewFake(), nil
+		},
+	}
+	if _, ok := itemsLister(lw); ok {
+		t.Errorf("expected a ListWatch without ListItemsFunc not to list item by item")
+	}
+	if _, ok := itemsLister(&tweakedListerWatcher{ListerWatcher: lw}); ok {
+		t.Errorf("expected a tweaked ListWatch without ListItemsFunc not to list item by item")
+	}
+	store := NewStore(MetaNamespaceKeyFunc)
+	r := NewReflector(lw, &v1.Pod{}, store, 0)
+
+	if err := r.list(wait.NeverStop); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if listCalls != 1 {
+		t.Errorf("expected ListFunc to be called once, got %d", listCalls)
+	}
+	if e, a := []string{"a"}, store.ListKeys(); !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}
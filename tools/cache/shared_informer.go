@@ -20,9 +20,11 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
@@ -234,6 +236,34 @@ type SharedIndexInformer interface {
 	GetIndexer() Indexer
 }
 
+// ListOptionsUpdater is implemented by the informers of this package, which
+// support changing their list options while they run.
+type ListOptionsUpdater interface {
+	// UpdateListOptions changes the options of the informer's list and watch
+	// requests, for example their label or field selector, while it runs.
+	// tweak is applied to the options of every subsequent request, after the
+	// informer sets them and before they are passed to its ListerWatcher; it
+	// replaces the tweak of any previous call, and nil removes it. A
+	// ListerWatcher which itself sets the same fields takes precedence.
+	//
+	// If the informer is running, it drops its watch and lists again with the
+	// new options. Objects which are no longer listed are delivered to the
+	// event handlers as deletions, with a DeletedFinalStateUnknown tombstone,
+	// new ones as additions and the others as updates. Event handler
+	// registrations are kept.
+	//
+	// Calling this after the informer has stopped returns an error.
+	UpdateListOptions(tweak func(options *metav1.ListOptions)) error
+}
+
+// HandlerOptionsAdder is implemented by the informers of this package, which
+// support configuring the registration of an event handler.
+type HandlerOptionsAdder interface {
+	// AddEventHandlerWithOptions is a variant of AddEventHandler which
+	// configures the registration with the given options, see HandlerOptions.
+	AddEventHandlerWithOptions(handler ResourceEventHandler, options HandlerOptions) (ResourceEventHandlerRegistration, error)
+}
+
 // NewSharedInformer creates a new instance for the ListerWatcher. See NewSharedIndexInformerWithOptions for full details.
 func NewSharedInformer(lw ListerWatcher, exampleObject runtime.Object, defaultEventHandlerResyncPeriod time.Duration) SharedInformer {
 	return NewSharedIndexInformer(lw, exampleObject, defaultEventHandlerResyncPeriod, Indexers{})
@@ -266,7 +296,7 @@ func NewSharedIndexInformer(lw ListerWatcher, exampleObject runtime.Object, defa
 func NewSharedIndexInformerWithOptions(lw ListerWatcher, exampleObject runtime.Object, options SharedIndexInformerOptions) SharedIndexInformer {
 	realClock := &clock.RealClock{}
 
-	return &sharedIndexInformer{
+	s := &sharedIndexInformer{
 		indexer:                         NewIndexer(DeletionHandlingMetaNamespaceKeyFunc, options.Indexers),
 		processor:                       &sharedProcessor{clock: realClock},
 		listerWatcher:                   lw,
@@ -277,6 +307,17 @@ func NewSharedIndexInformerWithOptions(lw ListerWatcher, exampleObject runtime.O
 		clock:                           realClock,
 		cacheMutationDetector:           NewCacheMutationDetector(fmt.Sprintf("%T", exampleObject)),
 	}
+	if options.EventHistorySize > 0 {
+		s.eventHistory = NewEventHistory(options.EventHistorySize)
+	}
+	if options.ConsistencyCheck != nil {
+		name := options.ObjectDescription
+		if name == "" {
+			name = fmt.Sprintf("%T", exampleObject)
+		}
+		s.consistencyChecker = newConsistencyChecker(s, name, *options.ConsistencyCheck)
+	}
+	return s
 }
 
 // SharedIndexInformerOptions configures a sharedIndexInformer.
@@ -291,6 +332,16 @@ type SharedIndexInformerOptions struct {
 	// ObjectDescription is the sharedIndexInformer's object description. This is passed through to the
 	// underlying Reflector's type description.
 	ObjectDescription string
+
+	// ConsistencyCheck, if set, enables a checker that periodically issues a consistent LIST at the
+	// resource version the informer's store reflects and reports any difference with the store.
+	// This is intended for detecting bugs in transforms, watch caches, or the informer itself, and
+	// puts additional load on the apiserver.
+	ConsistencyCheck *ConsistencyCheckOptions
+
+	// EventHistorySize is the number of processed deltas the informer remembers for debugging,
+	// see GetEventHistory. If unset/unspecified, no history is recorded.
+	EventHistorySize int
 }
 
 // InformerSynced is a function that can be used to determine if an informer has synced.  This is useful for determining if caches have synced.
@@ -359,8 +410,18 @@ type sharedIndexInformer struct {
 
 	processor             *sharedProcessor
 	cacheMutationDetector MutationDetector
+	consistencyChecker    *consistencyChecker
+
+	// observations holds the callers waiting for the indexer to observe a write
+	observations observationWaiters
+
+	// eventHistory, if set, records the deltas processed by HandleDeltas
+	eventHistory *EventHistory
 
 	listerWatcher ListerWatcher
+	// listOptions wraps the ListerWatcher passed to the informer once
+	// UpdateListOptions is called, and replaces it as listerWatcher
+	listOptions *tweakedListerWatcher
 
 	// objectType is an example object of the type this informer is expected to handle. If set, an event
 	// with an object with a mismatching type is dropped instead of being delivered to listeners.
@@ -388,6 +449,8 @@ type sharedIndexInformer struct {
 
 	// Called whenever the ListAndWatch drops the connection with an error.
 	watchErrorHandler WatchErrorHandler
+	// watchErrors remembers the most recent errors passed to watchErrorHandler
+	watchErrors watchErrorRecorder
 
 	transform TransformFunc
 }
@@ -450,6 +513,32 @@ func (s *sharedIndexInformer) SetTransform(handler TransformFunc) error {
 	return nil
 }
 
+// getListerWatcher returns the ListerWatcher the informer currently lists with.
+func (s *sharedIndexInformer) getListerWatcher() ListerWatcher {
+	s.startedLock.Lock()
+	defer s.startedLock.Unlock()
+	return s.listerWatcher
+}
+
+func (s *sharedIndexInformer) UpdateListOptions(tweak func(options *metav1.ListOptions)) error {
+	s.startedLock.Lock()
+	defer s.startedLock.Unlock()
+
+	if s.stopped {
+		return fmt.Errorf("informer has already stopped")
+	}
+
+	if s.listOptions == nil {
+		s.listOptions = &tweakedListerWatcher{ListerWatcher: s.listerWatcher}
+		s.listerWatcher = s.listOptions
+	}
+	s.listOptions.setTweak(tweak)
+	if c, ok := s.controller.(*controller); ok {
+		c.relist(s.listerWatcher)
+	}
+	return nil
+}
+
 func (s *sharedIndexInformer) Run(stopCh <-chan struct{}) {
 	defer utilruntime.HandleCrash()
 
@@ -468,6 +557,11 @@ func (s *sharedIndexInformer) Run(stopCh <-chan struct{}) {
 			Transformer:           s.transform,
 		})
 
+		watchErrorHandler := s.watchErrorHandler
+		if watchErrorHandler == nil {
+			watchErrorHandler = DefaultWatchErrorHandler
+		}
+
 		cfg := &Config{
 			Queue:             fifo,
 			ListerWatcher:     s.listerWatcher,
@@ -477,8 +571,11 @@ func (s *sharedIndexInformer) Run(stopCh <-chan struct{}) {
 			RetryOnError:      false,
 			ShouldResync:      s.processor.shouldResync,
 
-			Process:           s.HandleDeltas,
-			WatchErrorHandler: s.watchErrorHandler,
+			Process: s.HandleDeltas,
+			WatchErrorHandler: func(r *Reflector, err error) {
+				s.watchErrors.record(s.clock.Now(), err)
+				watchErrorHandler(r, err)
+			},
 		}
 
 		s.controller = New(cfg)
@@ -493,6 +590,9 @@ func (s *sharedIndexInformer) Run(stopCh <-chan struct{}) {
 	defer close(processorStopCh) // Tell Processor to stop
 	wg.StartWithChannel(processorStopCh, s.cacheMutationDetector.Run)
 	wg.StartWithChannel(processorStopCh, s.processor.run)
+	if s.consistencyChecker != nil {
+		wg.StartWithChannel(processorStopCh, s.consistencyChecker.run)
+	}
 
 	defer func() {
 		s.startedLock.Lock()
@@ -528,6 +628,39 @@ func (s *sharedIndexInformer) LastSyncResourceVersion() string {
 	return s.controller.LastSyncResourceVersion()
 }
 
+// quiescentSnapshot returns a snapshot of the informer's indexer together
+// with the resource version it reflects. It fails if the informer has not
+// synced yet or has deltas which it has not processed.
+func (s *sharedIndexInformer) quiescentSnapshot() (string, Indexer, error) {
+	s.startedLock.Lock()
+	ctrl, _ := s.controller.(*controller)
+	s.startedLock.Unlock()
+	if ctrl == nil || !ctrl.HasSynced() {
+		return "", nil, fmt.Errorf("informer has not synced")
+	}
+	fifo, ok := ctrl.config.Queue.(*DeltaFIFO)
+	if !ok {
+		return "", nil, fmt.Errorf("unexpected queue type %T", ctrl.config.Queue)
+	}
+
+	// The reflector only updates its resource version after queueing the
+	// corresponding deltas, which may be popped and applied in between, so
+	// the resource version to compare with is the one the fifo recorded when
+	// queueing them. Holding the fifo lock guarantees that no delta is being
+	// queued or processed, so if nothing is queued, the indexer reflects
+	// exactly that resource version.
+	fifo.lock.RLock()
+	defer fifo.lock.RUnlock()
+	resourceVersion := fifo.queuedResourceVersion
+	if resourceVersion == "" {
+		return "", nil, fmt.Errorf("informer has no resource version")
+	}
+	if len(fifo.queue) > 0 {
+		return "", nil, fmt.Errorf("informer has %d unprocessed items", len(fifo.queue))
+	}
+	return resourceVersion, SnapshotIndexer(s.indexer), nil
+}
+
 func (s *sharedIndexInformer) GetStore() Store {
 	return s.indexer
 }
@@ -552,7 +685,7 @@ func (s *sharedIndexInformer) GetController() Controller {
 }
 
 func (s *sharedIndexInformer) AddEventHandler(handler ResourceEventHandler) (ResourceEventHandlerRegistration, error) {
-	return s.AddEventHandlerWithResyncPeriod(handler, s.defaultEventHandlerResyncPeriod)
+	return s.AddEventHandlerWithOptions(handler, HandlerOptions{})
 }
 
 func determineResyncPeriod(desired, check time.Duration) time.Duration {
@@ -573,6 +706,10 @@ func determineResyncPeriod(desired, check time.Duration) time.Duration {
 const minimumResyncPeriod = 1 * time.Second
 
 func (s *sharedIndexInformer) AddEventHandlerWithResyncPeriod(handler ResourceEventHandler, resyncPeriod time.Duration) (ResourceEventHandlerRegistration, error) {
+	return s.AddEventHandlerWithOptions(handler, HandlerOptions{ResyncPeriod: &resyncPeriod})
+}
+
+func (s *sharedIndexInformer) AddEventHandlerWithOptions(handler ResourceEventHandler, options HandlerOptions) (ResourceEventHandlerRegistration, error) {
 	s.startedLock.Lock()
 	defer s.startedLock.Unlock()
 
@@ -580,6 +717,11 @@ func (s *sharedIndexInformer) AddEventHandlerWithResyncPeriod(handler ResourceEv
 		return nil, fmt.Errorf("handler %v was not added to shared informer because it has stopped already", handler)
 	}
 
+	resyncPeriod := s.defaultEventHandlerResyncPeriod
+	if options.ResyncPeriod != nil {
+		resyncPeriod = *options.ResyncPeriod
+	}
+
 	if resyncPeriod > 0 {
 		if resyncPeriod < minimumResyncPeriod {
 			klog.Warningf("resyncPeriod %v is too small. Changing it to the minimum allowed value of %v", resyncPeriod, minimumResyncPeriod)
@@ -601,6 +743,13 @@ func (s *sharedIndexInformer) AddEventHandlerWithResyncPeriod(handler ResourceEv
 	}
 
 	listener := newProcessListener(handler, resyncPeriod, determineResyncPeriod(resyncPeriod, s.resyncCheckPeriod), s.clock.Now(), initialBufferSize, s.HasSynced)
+	if options.PanicPolicy != nil {
+		listener.panicGuard = newPanicGuard(handler, *options.PanicPolicy, func() {
+			if err := s.RemoveEventHandler(listener); err != nil {
+				klog.Errorf("Failed to remove quarantined event handler: %v", err)
+			}
+		})
+	}
 
 	if !s.started {
 		return s.processor.addListener(listener), nil
@@ -634,6 +783,9 @@ func (s *sharedIndexInformer) HandleDeltas(obj interface{}, isInInitialList bool
 	defer s.blockDeltas.Unlock()
 
 	if deltas, ok := obj.(Deltas); ok {
+		if s.eventHistory != nil {
+			s.eventHistory.recordDeltas(deltas, s.clock.Now())
+		}
 		return processDeltas(s, s.indexer, deltas, isInInitialList)
 	}
 	return errors.New("object given as Process argument is not Deltas")
@@ -644,6 +796,7 @@ func (s *sharedIndexInformer) OnAdd(obj interface{}, isInInitialList bool) {
 	// Invocation of this function is locked under s.blockDeltas, so it is
 	// save to distribute the notification
 	s.cacheMutationDetector.AddObject(obj)
+	s.observations.observe(obj, false)
 	s.processor.distribute(addNotification{newObj: obj, isInInitialList: isInInitialList}, false)
 }
 
@@ -666,6 +819,9 @@ func (s *sharedIndexInformer) OnUpdate(old, new interface{}) {
 	// Invocation of this function is locked under s.blockDeltas, so it is
 	// save to distribute the notification
 	s.cacheMutationDetector.AddObject(new)
+	if !isSync {
+		s.observations.observe(new, false)
+	}
 	s.processor.distribute(updateNotification{oldObj: old, newObj: new}, isSync)
 }
 
@@ -673,6 +829,7 @@ func (s *sharedIndexInformer) OnUpdate(old, new interface{}) {
 func (s *sharedIndexInformer) OnDelete(old interface{}) {
 	// Invocation of this function is locked under s.blockDeltas, so it is
 	// save to distribute the notification
+	s.observations.observe(old, true)
 	s.processor.distribute(deleteNotification{oldObj: old}, false)
 }
 
@@ -896,11 +1053,21 @@ type processorListener struct {
 	nextResync time.Time
 	// resyncLock guards access to resyncPeriod and nextResync
 	resyncLock sync.Mutex
+
+	// pendingCount is the number of notifications added to the listener
+	// that have not been handed to run() yet. It is only used for debugging.
+	pendingCount int64
+
+	// panicGuard, if set, recovers from the panics of handler.
+	panicGuard *panicGuard
 }
 
 // HasSynced returns true if the source informer has synced, and all
-// corresponding events have been delivered.
+// corresponding events have been delivered, or the handler was quarantined.
 func (p *processorListener) HasSynced() bool {
+	if p.panicGuard != nil && p.panicGuard.quarantined.Load() {
+		return p.syncTracker.UpstreamHasSynced()
+	}
 	return p.syncTracker.HasSynced()
 }
 
@@ -937,6 +1104,7 @@ func (p *processorListener) pop() {
 		select {
 		case nextCh <- notification:
 			// Notification dispatched
+			atomic.AddInt64(&p.pendingCount, -1)
 			var ok bool
 			notification, ok = p.pendingNotifications.ReadOne()
 			if !ok { // Nothing to pop
@@ -946,6 +1114,7 @@ func (p *processorListener) pop() {
 			if !ok {
 				return
 			}
+			atomic.AddInt64(&p.pendingCount, 1)
 			if notification == nil { // No notification to pop (and pendingNotifications is empty)
 				// Optimize the case - skip adding to pendingNotifications
 				notification = notificationToAdd
@@ -965,18 +1134,10 @@ func (p *processorListener) run() {
 	stopCh := make(chan struct{})
 	wait.Until(func() {
 		for next := range p.nextCh {
-			switch notification := next.(type) {
-			case updateNotification:
-				p.handler.OnUpdate(notification.oldObj, notification.newObj)
-			case addNotification:
-				p.handler.OnAdd(notification.newObj, notification.isInInitialList)
-				if notification.isInInitialList {
-					p.syncTracker.Finished()
-				}
-			case deleteNotification:
-				p.handler.OnDelete(notification.oldObj)
-			default:
-				utilruntime.HandleError(fmt.Errorf("unrecognized notification: %T", next))
+			if p.panicGuard != nil {
+				p.panicGuard.handle(next, p.handle)
+			} else {
+				p.handle(next)
 			}
 		}
 		// the only way to get here is if the p.nextCh is empty and closed
@@ -984,6 +1145,24 @@ func (p *processorListener) run() {
 	}, 1*time.Second, stopCh)
 }
 
+// handle delivers a notification to the handler.
+func (p *processorListener) handle(next interface{}) {
+	switch notification := next.(type) {
+	case updateNotification:
+		p.handler.OnUpdate(notification.oldObj, notification.newObj)
+	case addNotification:
+		if notification.isInInitialList {
+			// Even if the handler panics, the notification was delivered.
+			defer p.syncTracker.Finished()
+		}
+		p.handler.OnAdd(notification.newObj, notification.isInInitialList)
+	case deleteNotification:
+		p.handler.OnDelete(notification.oldObj)
+	default:
+		utilruntime.HandleError(fmt.Errorf("unrecognized notification: %T", next))
+	}
+}
+
 // shouldResync deterimines if the listener needs a resync. If the listener's resyncPeriod is 0,
 // this always returns false.
 func (p *processorListener) shouldResync(now time.Time) bool {
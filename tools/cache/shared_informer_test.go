@@ -29,8 +29,11 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
 	fcache "k8s.io/client-go/tools/cache/testing"
 	testingclock "k8s.io/utils/clock/testing"
 )
@@ -994,3 +997,92 @@ func TestAddWhileActive(t *testing.T) {
 		return
 	}
 }
+
+func TestSharedInformerUpdateListOptions(t *testing.T) {
+	pods := []v1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns", ResourceVersion: "1", Labels: map[string]string{"team": "a"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod2", Namespace: "ns", ResourceVersion: "1", Labels: map[string]string{"team": "a"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod3", Namespace: "ns", ResourceVersion: "1", Labels: map[string]string{"team": "b"}}},
+	}
+	lw := &ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			selector, err := labels.Parse(options.LabelSelector)
+			if err != nil {
+				return nil, err
+			}
+			list := &v1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "1"}}
+			for _, pod := range pods {
+				if selector.Matches(labels.Set(pod.Labels)) {
+					list.Items = append(list.Items, pod)
+				}
+			}
+			return list, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return watch.NewFake(), nil
+		},
+	}
+	informer := NewSharedInformer(lw, &v1.Pod{}, 0)
+	updater := informer.(ListOptionsUpdater)
+
+	var lock sync.Mutex
+	var events []string
+	record := func(event string, obj interface{}) {
+		if tombstone, ok := obj.(DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		events = append(events, event+" "+obj.(*v1.Pod).Name)
+	}
+	if _, err := informer.AddEventHandler(ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { record("add", obj) },
+		UpdateFunc: func(_, obj interface{}) { record("update", obj) },
+		DeleteFunc: func(obj interface{}) { record("delete", obj) },
+	}); err != nil {
+		t.Fatal(err)
+	}
+	waitForEvents := func(expected ...string) {
+		t.Helper()
+		err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			return len(events) == len(expected), nil
+		})
+		lock.Lock()
+		defer lock.Unlock()
+		if err != nil || !sets.NewString(events...).Equal(sets.NewString(expected...)) {
+			t.Fatalf("expected events %v, got %v", expected, events)
+		}
+		events = nil
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go informer.Run(stop)
+	waitForEvents("add pod1", "add pod2", "add pod3")
+	if informer.(*sharedIndexInformer).listOptions != nil {
+		t.Errorf("expected the ListerWatcher not to be wrapped before list options are updated")
+	}
+
+	if err := updater.UpdateListOptions(func(options *metav1.ListOptions) { options.LabelSelector = "team=a" }); err != nil {
+		t.Fatal(err)
+	}
+	waitForEvents("update pod1", "update pod2", "delete pod3")
+
+	// pod1 no longer matches, pod3 now does.
+	pods[0].Labels["team"] = "c"
+	pods[2].Labels["team"] = "a"
+	if err := updater.UpdateListOptions(func(options *metav1.ListOptions) { options.LabelSelector = "team in (a,c)" }); err != nil {
+		t.Fatal(err)
+	}
+	waitForEvents("update pod1", "update pod2", "add pod3")
+
+	if err := updater.UpdateListOptions(func(options *metav1.ListOptions) { options.LabelSelector = "team=a" }); err != nil {
+		t.Fatal(err)
+	}
+	waitForEvents("delete pod1", "update pod2", "update pod3")
+	if keys := informer.GetStore().ListKeys(); !sets.NewString(keys...).Equal(sets.NewString("ns/pod2", "ns/pod3")) {
+		t.Errorf("unexpected store contents %v", keys)
+	}
+}
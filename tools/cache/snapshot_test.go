@@ -0,0 +1,148 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	fcache "k8s.io/client-go/tools/cache/testing"
+)
+
+func testSnapshotPod(name, namespace, rv string) *v1.Pod {
+	return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, ResourceVersion: rv}}
+}
+
+func TestSnapshotIndexerCopyOnWrite(t *testing.T) {
+	indexer := NewIndexer(MetaNamespaceKeyFunc, Indexers{NamespaceIndex: MetaNamespaceIndexFunc})
+	indexer.Add(testSnapshotPod("a", "ns1", "1"))
+	indexer.Add(testSnapshotPod("b", "ns1", "2"))
+
+	snapshot := SnapshotIndexer(indexer)
+
+	indexer.Update(testSnapshotPod("a", "ns1", "3"))
+	indexer.Delete(testSnapshotPod("b", "ns1", "2"))
+	indexer.Add(testSnapshotPod("c", "ns2", "4"))
+
+	if keys := sets.NewString(snapshot.ListKeys()...); !keys.Equal(sets.NewString("ns1/a", "ns1/b")) {
+		t.Errorf("unexpected snapshot keys: %v", keys.List())
+	}
+	obj, exists, err := snapshot.GetByKey("ns1/a")
+	if err != nil || !exists {
+		t.Fatalf("expected ns1/a in snapshot, exists=%v err=%v", exists, err)
+	}
+	if rv := obj.(*v1.Pod).ResourceVersion; rv != "1" {
+		t.Errorf("expected snapshot to hold resourceVersion 1, got %q", rv)
+	}
+	keys, err := snapshot.IndexKeys(NamespaceIndex, "ns1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sets.NewString(keys...).Equal(sets.NewString("ns1/a", "ns1/b")) {
+		t.Errorf("unexpected snapshot index keys: %v", keys)
+	}
+	if values := snapshot.ListIndexFuncValues(NamespaceIndex); !sets.NewString(values...).Equal(sets.NewString("ns1")) {
+		t.Errorf("unexpected snapshot index values: %v", values)
+	}
+
+	// The original indexer must have been detached from the snapshot.
+	if keys := sets.NewString(indexer.ListKeys()...); !keys.Equal(sets.NewString("ns1/a", "ns2/c")) {
+		t.Errorf("unexpected indexer keys: %v", keys.List())
+	}
+	keys, err = indexer.IndexKeys(NamespaceIndex, "ns1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sets.NewString(keys...).Equal(sets.NewString("ns1/a")) {
+		t.Errorf("unexpected indexer index keys: %v", keys)
+	}
+}
+
+func TestSnapshotIndexerReadOnly(t *testing.T) {
+	snapshot := SnapshotIndexer(NewIndexer(MetaNamespaceKeyFunc, Indexers{}))
+	pod := testSnapshotPod("a", "ns1", "1")
+
+	for name, f := range map[string]func() error{
+		"Add":         func() error { return snapshot.Add(pod) },
+		"Update":      func() error { return snapshot.Update(pod) },
+		"Delete":      func() error { return snapshot.Delete(pod) },
+		"Replace":     func() error { return snapshot.Replace([]interface{}{pod}, "1") },
+		"AddIndexers": func() error { return snapshot.AddIndexers(Indexers{"foo": MetaNamespaceIndexFunc}) },
+	} {
+		if err := f(); !errors.Is(err, ErrSnapshotReadOnly) {
+			t.Errorf("%s: expected ErrSnapshotReadOnly, got %v", name, err)
+		}
+	}
+	if len(snapshot.ListKeys()) != 0 {
+		t.Errorf("expected snapshot to stay empty, got %v", snapshot.ListKeys())
+	}
+}
+
+func TestTakeSnapshot(t *testing.T) {
+	podSource := fcache.NewFakeControllerSource()
+	podSource.Add(testSnapshotPod("pod1", "ns1", ""))
+	podSource.Add(testSnapshotPod("pod2", "ns1", ""))
+	pods := NewSharedIndexInformer(podSource, &v1.Pod{}, 0, Indexers{})
+
+	cmSource := fcache.NewFakeControllerSource()
+	cmSource.Add(&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm1", Namespace: "ns1"}})
+	configMaps := NewSharedIndexInformer(cmSource, &v1.ConfigMap{}, 0, Indexers{})
+
+	unstarted := NewSharedIndexInformer(fcache.NewFakeControllerSource(), &v1.Secret{}, 0, Indexers{})
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go pods.Run(stop)
+	go configMaps.Run(stop)
+	if !WaitForCacheSync(stop, pods.HasSynced, configMaps.HasSynced) {
+		t.Fatal("caches did not sync")
+	}
+
+	snapshot := TakeSnapshot(pods, configMaps, pods, unstarted)
+
+	if versions := snapshot.ResourceVersions(); len(versions) != 3 || versions[0] != "2" || versions[1] != "1" || versions[2] != "" {
+		t.Errorf("unexpected resource versions: %v", versions)
+	}
+	if rv := snapshot.ResourceVersion(pods); rv != "2" {
+		t.Errorf("expected pods resourceVersion 2, got %q", rv)
+	}
+
+	podSource.Delete(testSnapshotPod("pod1", "ns1", ""))
+	if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		return len(pods.GetIndexer().ListKeys()) == 1, nil
+	}); err != nil {
+		t.Fatalf("informer did not observe delete: %v", err)
+	}
+
+	if keys := snapshot.Indexer(pods).ListKeys(); len(keys) != 2 {
+		t.Errorf("expected the snapshot to still hold both pods, got %v", keys)
+	}
+	if keys := snapshot.Indexer(configMaps).ListKeys(); len(keys) != 1 || keys[0] != "ns1/cm1" {
+		t.Errorf("unexpected config map keys: %v", keys)
+	}
+	if keys := snapshot.Indexer(unstarted).ListKeys(); len(keys) != 0 {
+		t.Errorf("unexpected secret keys: %v", keys)
+	}
+	if snapshot.Indexer(NewSharedIndexInformer(podSource, &v1.Pod{}, 0, Indexers{})) != nil {
+		t.Errorf("expected no indexer for an informer outside of the snapshot")
+	}
+}
@@ -212,6 +212,10 @@ type threadSafeMap struct {
 
 	// index implements the indexing functionality
 	index *storeIndex
+
+	// shared is true while items and index.indices are referenced by a
+	// snapshot. They are copied before the next mutation.
+	shared bool
 }
 
 func (c *threadSafeMap) Add(key string, obj interface{}) {
@@ -221,6 +225,7 @@ func (c *threadSafeMap) Add(key string, obj interface{}) {
 func (c *threadSafeMap) Update(key string, obj interface{}) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
+	c.copyOnWrite()
 	oldObject := c.items[key]
 	c.items[key] = obj
 	c.index.updateIndices(oldObject, obj, key)
@@ -230,6 +235,7 @@ func (c *threadSafeMap) Delete(key string) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 	if obj, exists := c.items[key]; exists {
+		c.copyOnWrite()
 		c.index.updateIndices(obj, nil, key)
 		delete(c.items, key)
 	}
@@ -268,6 +274,8 @@ func (c *threadSafeMap) Replace(items map[string]interface{}, resourceVersion st
 	c.lock.Lock()
 	defer c.lock.Unlock()
 	c.items = items
+	// the index is rebuilt from scratch below, so nothing needs to be copied
+	c.shared = false
 
 	// rebuild any index
 	c.index.reset()
@@ -346,6 +354,49 @@ func (c *threadSafeMap) AddIndexers(newIndexers Indexers) error {
 	return c.index.addIndexers(newIndexers)
 }
 
+// snapshot returns a read-only copy of c that shares its items and indices.
+// The shared state is only copied when c is next mutated.
+func (c *threadSafeMap) snapshot() *threadSafeMap {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	indexers := make(Indexers, len(c.index.indexers))
+	for name, indexFunc := range c.index.indexers {
+		indexers[name] = indexFunc
+	}
+	c.shared = true
+	return &threadSafeMap{
+		items: c.items,
+		index: &storeIndex{
+			indexers: indexers,
+			indices:  c.index.indices,
+		},
+	}
+}
+
+// copyOnWrite detaches c from any snapshot still referencing its items and
+// indices. It must be called with the lock held, before any mutation.
+func (c *threadSafeMap) copyOnWrite() {
+	if !c.shared {
+		return
+	}
+	items := make(map[string]interface{}, len(c.items))
+	for key, item := range c.items {
+		items[key] = item
+	}
+	indices := make(Indices, len(c.index.indices))
+	for name, index := range c.index.indices {
+		newIndex := make(Index, len(index))
+		for indexedValue, keys := range index {
+			newIndex[indexedValue] = sets.NewString(keys.UnsortedList()...)
+		}
+		indices[name] = newIndex
+	}
+	c.items = items
+	c.index.indices = indices
+	c.shared = false
+}
+
 func (c *threadSafeMap) Resync() error {
 	// Nothing to do
 	return nil
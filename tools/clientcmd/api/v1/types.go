@@ -63,6 +63,10 @@ type Preferences struct {
 type Cluster struct {
 	// Server is the address of the kubernetes cluster (https://hostname:port).
 	Server string `json:"server"`
+	// Endpoints optionally lists further addresses of the kubernetes cluster, e.g. of its other apiservers,
+	// which clients spread their requests across and fail over to if Server is unavailable.
+	// +optional
+	Endpoints []string `json:"endpoints,omitempty"`
 	// TLSServerName is used to check server certificate. If TLSServerName is empty, the hostname used to contact the server is used.
 	// +optional
 	TLSServerName string `json:"tls-server-name,omitempty"`
@@ -156,6 +156,7 @@ func (config *DirectClientConfig) ClientConfig() (*restclient.Config, error) {
 
 	clientConfig := &restclient.Config{}
 	clientConfig.Host = configClusterInfo.Server
+	clientConfig.Endpoints = configClusterInfo.Endpoints
 	if configClusterInfo.ProxyURL != "" {
 		u, err := parseProxyURL(configClusterInfo.ProxyURL)
 		if err != nil {
@@ -532,6 +533,12 @@ func (config *DirectClientConfig) getCluster() (clientcmdapi.Cluster, error) {
 		if config.overrides.ClusterInfo.TLSServerName != "" || config.overrides.ClusterInfo.Server != "" {
 			mergedClusterInfo.TLSServerName = config.overrides.ClusterInfo.TLSServerName
 		}
+
+		// if the --server has been set in overrides, then the KUBECONFIG value of endpoints, which are the further addresses of the
+		// overridden server, are cleared as well.
+		if config.overrides.ClusterInfo.Server != "" {
+			mergedClusterInfo.Endpoints = config.overrides.ClusterInfo.Endpoints
+		}
 	}
 
 	return *mergedClusterInfo, nil
@@ -234,6 +234,34 @@ func TestTLSServerNameClearsWhenServerNameSet(t *testing.T) {
 	matchStringArg("", actualCfg.ServerName, t)
 }
 
+func TestEndpoints(t *testing.T) {
+	config := createValidTestConfig()
+	config.Clusters["clean"].Endpoints = []string{"https://localhost:8444", "https://localhost:8445"}
+
+	clientBuilder := NewNonInteractiveClientConfig(*config, "clean", &ConfigOverrides{}, nil)
+	actualCfg, err := clientBuilder.ClientConfig()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if e, a := config.Clusters["clean"].Endpoints, actualCfg.Endpoints; !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected endpoints %v, got %v", e, a)
+	}
+
+	// Overriding the server clears the endpoints of the kubeconfig.
+	clientBuilder = NewNonInteractiveClientConfig(*config, "clean", &ConfigOverrides{
+		ClusterInfo: clientcmdapi.Cluster{
+			Server: "http://something",
+		},
+	}, nil)
+	actualCfg, err = clientBuilder.ClientConfig()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(actualCfg.Endpoints) != 0 {
+		t.Errorf("Expected no endpoints, got %v", actualCfg.Endpoints)
+	}
+}
+
 func TestFullImpersonateConfig(t *testing.T) {
 	config := createValidTestConfig()
 	config.Clusters["clean"] = &clientcmdapi.Cluster{
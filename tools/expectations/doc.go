@@ -0,0 +1,30 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package expectations helps controllers avoid acting on their own
+// in-flight creates and deletes.
+//
+// A controller that creates or deletes the objects it manages (its
+// controllees) cannot tell from its informer cache whether those writes
+// have been observed yet. Before issuing N creates, it records that it
+// expects N creations for its key; the informer event handlers lower that
+// count as the creations are observed. Until the expectations are
+// satisfied, or have expired, the controller skips acting on the key, so
+// that it does not create the same controllees again.
+//
+// This is the same mechanism that is used by the controllers of
+// kube-controller-manager.
+package expectations // import "k8s.io/client-go/tools/expectations"
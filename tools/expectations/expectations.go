@@ -0,0 +1,391 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package expectations
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+)
+
+// DefaultTimeout is the time after which unfulfilled expectations are
+// considered satisfied. If a controller does not observe the events it
+// expects within this period, for example because a watch event was lost,
+// it syncs again instead of waiting forever. Expired expectations are
+// removed, so that the keys of deleted controllers do not accumulate.
+const DefaultTimeout = 5 * time.Minute
+
+// ControllerExpectationsInterface is an interface that allows users to set
+// and wait on expectations. Only abstracted out for testing.
+//
+// Warning: it is not safe to use a single ControllerExpectationsInterface
+// with different types of controllers, because the keys might conflict
+// across types.
+type ControllerExpectationsInterface interface {
+	GetExpectations(controllerKey string) (*ControlleeExpectations, bool, error)
+	SatisfiedExpectations(controllerKey string) bool
+	DeleteExpectations(controllerKey string)
+	SetExpectations(controllerKey string, add, del int) error
+	ExpectCreations(controllerKey string, adds int) error
+	ExpectDeletions(controllerKey string, dels int) error
+	CreationObserved(controllerKey string)
+	DeletionObserved(controllerKey string)
+	RaiseExpectations(controllerKey string, add, del int)
+	LowerExpectations(controllerKey string, add, del int)
+}
+
+// ControllerExpectations is a cache mapping controllers to what they
+// expect to see before being woken up for a sync.
+type ControllerExpectations struct {
+	cache.Store
+
+	timeout time.Duration
+	clock   clock.PassiveClock
+
+	// lock serializes replacing expectations with removing expired ones,
+	// so that fresh expectations are never removed in place of expired
+	// ones. It also guards lastCollected.
+	lock          sync.Mutex
+	lastCollected time.Time
+}
+
+var _ ControllerExpectationsInterface = &ControllerExpectations{}
+
+// NewControllerExpectations returns a store for ControllerExpectations
+// which expire after DefaultTimeout.
+func NewControllerExpectations() *ControllerExpectations {
+	return NewControllerExpectationsWithTimeout(DefaultTimeout, clock.RealClock{})
+}
+
+// NewControllerExpectationsWithTimeout returns a store for
+// ControllerExpectations which expire after timeout, as measured by the
+// given clock.
+func NewControllerExpectationsWithTimeout(timeout time.Duration, clock clock.PassiveClock) *ControllerExpectations {
+	return &ControllerExpectations{
+		Store:         cache.NewStore(KeyFunc),
+		timeout:       timeout,
+		clock:         clock,
+		lastCollected: clock.Now(),
+	}
+}
+
+// GetExpectations returns the ControlleeExpectations of the given controller.
+func (r *ControllerExpectations) GetExpectations(controllerKey string) (*ControlleeExpectations, bool, error) {
+	exp, exists, err := r.GetByKey(controllerKey)
+	if err == nil && exists {
+		return exp.(*ControlleeExpectations), true, nil
+	}
+	return nil, false, err
+}
+
+// DeleteExpectations deletes the expectations of the given controller.
+func (r *ControllerExpectations) DeleteExpectations(controllerKey string) {
+	if exp, exists, err := r.GetByKey(controllerKey); err == nil && exists {
+		if err := r.Delete(exp); err != nil {
+			klog.V(2).Infof("Error deleting expectations for controller %v: %v", controllerKey, err)
+		}
+	}
+}
+
+// SatisfiedExpectations returns true if the required adds/dels for the
+// given controller have been observed. Add/del counts are established by
+// the controller at sync time, and updated as controllees are observed by
+// the controller manager.
+func (r *ControllerExpectations) SatisfiedExpectations(controllerKey string) bool {
+	if exp, exists, err := r.GetExpectations(controllerKey); exists {
+		if exp.Fulfilled() {
+			klog.V(4).Infof("Controller expectations fulfilled %#v", exp)
+			return true
+		} else if r.isExpired(exp) {
+			klog.V(4).Infof("Controller expectations expired %#v", exp)
+			r.deleteIfExpired(exp)
+			return true
+		} else {
+			klog.V(4).Infof("Controller still waiting on expectations %#v", exp)
+			return false
+		}
+	} else if err != nil {
+		klog.V(2).Infof("Error encountered while checking expectations %#v, forcing sync", err)
+	} else {
+		// When a new controller is created, it doesn't have expectations.
+		// When it doesn't see expected watch events for > TTL, the expectations expire.
+		//	- In this case it wakes up, creates/deletes controllees, and sets expectations again.
+		// When it has satisfied expectations and no controllees need to be created/destroyed > TTL, the expectations expire.
+		//	- In this case it continues without setting expectations till it needs to create/delete controllees.
+		klog.V(4).Infof("Controller %v either never recorded expectations, or the ttl expired.", controllerKey)
+	}
+	// Trigger a sync if we either encountered and error (which shouldn't happen since we're
+	// getting from local store) or this controller hasn't established expectations.
+	return true
+}
+
+func (r *ControllerExpectations) isExpired(exp *ControlleeExpectations) bool {
+	return r.clock.Since(exp.timestamp) > r.timeout
+}
+
+// deleteIfExpired deletes exp if it expired and has not been replaced.
+func (r *ControllerExpectations) deleteIfExpired(exp *ControlleeExpectations) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.deleteIfExpiredLocked(exp)
+}
+
+func (r *ControllerExpectations) deleteIfExpiredLocked(exp *ControlleeExpectations) {
+	current, exists, err := r.GetByKey(exp.key)
+	if err != nil || !exists || current != exp || !r.isExpired(exp) {
+		return
+	}
+	if err := r.Delete(exp); err != nil {
+		klog.V(2).Infof("Error deleting expired expectations for controller %v: %v", exp.key, err)
+	}
+}
+
+// collectExpiredLocked deletes all expired expectations, at most once per timeout.
+// This removes the expectations of controllers which were deleted without
+// their expectations being deleted.
+func (r *ControllerExpectations) collectExpiredLocked() {
+	if r.clock.Since(r.lastCollected) < r.timeout {
+		return
+	}
+	r.lastCollected = r.clock.Now()
+	for _, obj := range r.List() {
+		r.deleteIfExpiredLocked(obj.(*ControlleeExpectations))
+	}
+}
+
+// SetExpectations registers new expectations for the given controller.
+// Forgets existing expectations.
+func (r *ControllerExpectations) SetExpectations(controllerKey string, add, del int) error {
+	exp := &ControlleeExpectations{add: int64(add), del: int64(del), key: controllerKey, timestamp: r.clock.Now()}
+	klog.V(4).Infof("Setting expectations %#v", exp)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.collectExpiredLocked()
+	return r.Add(exp)
+}
+
+// ExpectCreations records that the given controller is about to create
+// adds controllees.
+func (r *ControllerExpectations) ExpectCreations(controllerKey string, adds int) error {
+	return r.SetExpectations(controllerKey, adds, 0)
+}
+
+// ExpectDeletions records that the given controller is about to delete
+// dels controllees.
+func (r *ControllerExpectations) ExpectDeletions(controllerKey string, dels int) error {
+	return r.SetExpectations(controllerKey, 0, dels)
+}
+
+// LowerExpectations decrements the expectation counts of the given controller.
+func (r *ControllerExpectations) LowerExpectations(controllerKey string, add, del int) {
+	if exp, exists, err := r.GetExpectations(controllerKey); err == nil && exists {
+		exp.Add(int64(-add), int64(-del))
+		// The expectations might've been modified since the update on the previous line.
+		klog.V(4).Infof("Lowered expectations %#v", exp)
+	}
+}
+
+// RaiseExpectations increments the expectation counts of the given controller.
+func (r *ControllerExpectations) RaiseExpectations(controllerKey string, add, del int) {
+	if exp, exists, err := r.GetExpectations(controllerKey); err == nil && exists {
+		exp.Add(int64(add), int64(del))
+		// The expectations might've been modified since the update on the previous line.
+		klog.V(4).Infof("Raised expectations %#v", exp)
+	}
+}
+
+// CreationObserved atomically decrements the `add` expectation count of the
+// given controller.
+func (r *ControllerExpectations) CreationObserved(controllerKey string) {
+	r.LowerExpectations(controllerKey, 1, 0)
+}
+
+// DeletionObserved atomically decrements the `del` expectation count of the
+// given controller.
+func (r *ControllerExpectations) DeletionObserved(controllerKey string) {
+	r.LowerExpectations(controllerKey, 0, 1)
+}
+
+// Expectations are either fulfilled, or expire naturally.
+type Expectations interface {
+	Fulfilled() bool
+}
+
+// ControlleeExpectations track controllee creates/deletes.
+type ControlleeExpectations struct {
+	// Important: Since these two int64 fields are using sync/atomic, they have to be at the top of the struct due to a bug on 32-bit platforms
+	// See: https://golang.org/pkg/sync/atomic/ for more information
+	add       int64
+	del       int64
+	key       string
+	timestamp time.Time
+}
+
+// Add increments the add and del counters.
+func (e *ControlleeExpectations) Add(add, del int64) {
+	atomic.AddInt64(&e.add, add)
+	atomic.AddInt64(&e.del, del)
+}
+
+// Fulfilled returns true if this expectation has been fulfilled.
+func (e *ControlleeExpectations) Fulfilled() bool {
+	return atomic.LoadInt64(&e.add) <= 0 && atomic.LoadInt64(&e.del) <= 0
+}
+
+// GetExpectations returns the add and del expectations of the controllee.
+func (e *ControlleeExpectations) GetExpectations() (int64, int64) {
+	return atomic.LoadInt64(&e.add), atomic.LoadInt64(&e.del)
+}
+
+// KeyFunc is the key function of the ControllerExpectations store.
+func KeyFunc(obj interface{}) (string, error) {
+	if e, ok := obj.(*ControlleeExpectations); ok {
+		return e.key, nil
+	}
+	return "", fmt.Errorf("could not find key for obj %#v", obj)
+}
+
+// UIDSetKeyFunc is the key function of the UIDTrackingControllerExpectations
+// store.
+func UIDSetKeyFunc(obj interface{}) (string, error) {
+	if u, ok := obj.(*UIDSet); ok {
+		return u.key, nil
+	}
+	return "", fmt.Errorf("could not find key for obj %#v", obj)
+}
+
+// UIDSet holds a key and a set of UIDs. Used by the
+// UIDTrackingControllerExpectations to remember which UID it has seen/still
+// waiting for.
+type UIDSet struct {
+	sets.String
+	key string
+}
+
+// UIDTrackingControllerExpectations tracks the UIDs of the controllees a
+// controller expects to be deleted, instead of just counting them. A
+// deletion that is observed more than once, for example through an update
+// that sets the deletion timestamp followed by the actual delete, is only
+// counted once.
+type UIDTrackingControllerExpectations struct {
+	ControllerExpectationsInterface
+	uidStoreLock sync.Mutex
+	// Store used for the UIDs associated with any expectation tracked via the
+	// ControllerExpectationsInterface.
+	uidStore cache.Store
+	// The UID sets of controllers without expectations are removed once
+	// uidSetsAdded reaches collectAt.
+	uidSetsAdded int
+	collectAt    int
+}
+
+// NewUIDTrackingControllerExpectations returns a wrapper around
+// ControllerExpectations that tracks the UIDs of the expected deletions.
+func NewUIDTrackingControllerExpectations(ce ControllerExpectationsInterface) *UIDTrackingControllerExpectations {
+	return &UIDTrackingControllerExpectations{ControllerExpectationsInterface: ce, uidStore: cache.NewStore(UIDSetKeyFunc), collectAt: minUIDCollectSize}
+}
+
+// GetUIDs is a convenience method to avoid exposing the set of expected
+// uids. The returned set is not thread safe, all modifications must be made
+// holding the uidStoreLock.
+func (u *UIDTrackingControllerExpectations) GetUIDs(controllerKey string) sets.String {
+	if uid, exists, err := u.uidStore.GetByKey(controllerKey); err == nil && exists {
+		return uid.(*UIDSet).String
+	}
+	return nil
+}
+
+// ExpectDeletions records expectations for the objects with the given UIDs,
+// before the given controller deletes them.
+func (u *UIDTrackingControllerExpectations) ExpectDeletions(controllerKey string, deletedUIDs []string) error {
+	expectedUIDs := sets.NewString(deletedUIDs...)
+	klog.V(4).Infof("Controller %v waiting on deletions for: %+v", controllerKey, deletedUIDs)
+	u.uidStoreLock.Lock()
+	defer u.uidStoreLock.Unlock()
+
+	if existing := u.GetUIDs(controllerKey); existing != nil && existing.Len() != 0 {
+		klog.Errorf("Clobbering existing delete UIDs: %+v", existing)
+	}
+	u.collectLocked()
+	if err := u.uidStore.Add(&UIDSet{expectedUIDs, controllerKey}); err != nil {
+		return err
+	}
+	return u.ControllerExpectationsInterface.ExpectDeletions(controllerKey, expectedUIDs.Len())
+}
+
+// DeletionObserved records the deletion of the object with the given UID,
+// for the given controller.
+func (u *UIDTrackingControllerExpectations) DeletionObserved(controllerKey, deletedUID string) {
+	u.uidStoreLock.Lock()
+	defer u.uidStoreLock.Unlock()
+
+	uids := u.GetUIDs(controllerKey)
+	if uids != nil && uids.Has(deletedUID) {
+		klog.V(4).Infof("Controller %v received delete for %v", controllerKey, deletedUID)
+		u.ControllerExpectationsInterface.DeletionObserved(controllerKey)
+		uids.Delete(deletedUID)
+	}
+}
+
+// DeleteExpectations deletes the UID set and invokes DeleteExpectations on
+// the underlying ControllerExpectationsInterface.
+func (u *UIDTrackingControllerExpectations) DeleteExpectations(controllerKey string) {
+	u.uidStoreLock.Lock()
+	defer u.uidStoreLock.Unlock()
+
+	u.ControllerExpectationsInterface.DeleteExpectations(controllerKey)
+	if uidExp, exists, err := u.uidStore.GetByKey(controllerKey); err == nil && exists {
+		if err := u.uidStore.Delete(uidExp); err != nil {
+			klog.V(2).Infof("Error deleting uid expectations for controller %v: %v", controllerKey, err)
+		}
+	}
+}
+
+// minUIDCollectSize is the minimum number of UID sets added between two
+// collections.
+const minUIDCollectSize = 16
+
+// collectLocked removes the UID sets of controllers whose expectations were
+// deleted or expired. It runs once as many UID sets have been added as the
+// store held after the previous collection, so that its cost is amortized
+// over the calls to ExpectDeletions.
+func (u *UIDTrackingControllerExpectations) collectLocked() {
+	u.uidSetsAdded++
+	if u.uidSetsAdded < u.collectAt {
+		return
+	}
+	u.uidSetsAdded = 0
+	for _, obj := range u.uidStore.List() {
+		uidSet := obj.(*UIDSet)
+		if _, exists, err := u.GetExpectations(uidSet.key); err == nil && !exists {
+			if err := u.uidStore.Delete(uidSet); err != nil {
+				klog.V(2).Infof("Error deleting uid expectations for controller %v: %v", uidSet.key, err)
+			}
+		}
+	}
+	u.collectAt = 2 * len(u.uidStore.ListKeys())
+	if u.collectAt < minUIDCollectSize {
+		u.collectAt = minUIDCollectSize
+	}
+}
@@ -0,0 +1,244 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package expectations
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestControllerExpectations(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	e := NewControllerExpectationsWithTimeout(time.Minute, fakeClock)
+	key := "ns/rs"
+
+	if !e.SatisfiedExpectations(key) {
+		t.Errorf("expected a controller without expectations to be satisfied")
+	}
+
+	adds, dels := 10, 30
+	if err := e.SetExpectations(key, adds, dels); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < adds+1; i++ {
+		wg.Add(1)
+		go func() {
+			// In prod this can happen either because of a failed create by the controller
+			// or after having observed a create via informer
+			e.CreationObserved(key)
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+
+	// There are still delete expectations
+	if e.SatisfiedExpectations(key) {
+		t.Errorf("expected expectations to be unsatisfied")
+	}
+	for i := 0; i < dels+1; i++ {
+		wg.Add(1)
+		go func() {
+			e.DeletionObserved(key)
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+
+	// Expectations have been surpassed
+	if podExp, exists, err := e.GetExpectations(key); err == nil && exists {
+		add, del := podExp.GetExpectations()
+		if add != -1 || del != -1 {
+			t.Errorf("unexpected expectations %#v", podExp)
+		}
+	} else {
+		t.Errorf("could not get expectations, exists %v and err %v", exists, err)
+	}
+	if !e.SatisfiedExpectations(key) {
+		t.Errorf("expected expectations to be satisfied")
+	}
+
+	// Next round of sync, the controller expects more creations
+	e.ExpectCreations(key, 1)
+	if e.SatisfiedExpectations(key) {
+		t.Errorf("expected expectations to be unsatisfied")
+	}
+	e.RaiseExpectations(key, 1, 0)
+	e.LowerExpectations(key, 1, 0)
+	if e.SatisfiedExpectations(key) {
+		t.Errorf("expected expectations to be unsatisfied")
+	}
+
+	// Expectations have expired because of the ttl
+	fakeClock.Step(time.Minute + time.Second)
+	if !e.SatisfiedExpectations(key) {
+		t.Errorf("expected expectations to be satisfied after the ttl")
+	}
+
+	e.DeleteExpectations(key)
+	if _, exists, _ := e.GetExpectations(key); exists {
+		t.Errorf("expected expectations to be deleted")
+	}
+}
+
+func TestUIDExpectations(t *testing.T) {
+	uidExp := NewUIDTrackingControllerExpectations(NewControllerExpectations())
+	rcKeys := []string{"ns/rc1", "ns/rc2", "ns/rc3"}
+	uids := map[string][]string{
+		"ns/rc1": {"a", "b"},
+		"ns/rc2": {"c"},
+		"ns/rc3": {"d", "e", "f"},
+	}
+
+	for _, key := range rcKeys {
+		if err := uidExp.ExpectDeletions(key, uids[key]); err != nil {
+			t.Fatal(err)
+		}
+		if uidExp.GetUIDs(key).Len() != len(uids[key]) {
+			t.Errorf("unexpected uids for %v: %v", key, uidExp.GetUIDs(key))
+		}
+		if uidExp.SatisfiedExpectations(key) {
+			t.Errorf("controller %v satisfied expectations before deletion", key)
+		}
+	}
+
+	for _, key := range rcKeys {
+		for _, uid := range uids[key] {
+			uidExp.DeletionObserved(key, uid)
+			// A repeated observation must not be counted twice.
+			uidExp.DeletionObserved(key, uid)
+		}
+		if podExp, _, _ := uidExp.GetExpectations(key); podExp != nil {
+			if _, del := podExp.GetExpectations(); del != 0 {
+				t.Errorf("expected deletions of %v to be exactly satisfied, got %d", key, del)
+			}
+		}
+		if !uidExp.SatisfiedExpectations(key) {
+			t.Errorf("controller %v didn't satisfy expectations after deletion", key)
+		}
+
+		uidExp.DeleteExpectations(key)
+		if uidExp.GetUIDs(key) != nil {
+			t.Errorf("failed to delete uid expectations for %v", key)
+		}
+	}
+}
+
+func TestExpiredExpectationsAreCollected(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	e := NewControllerExpectationsWithTimeout(time.Minute, fakeClock)
+
+	for _, key := range []string{"ns/deleted", "ns/checked"} {
+		if err := e.ExpectCreations(key, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fakeClock.Step(2 * time.Minute)
+
+	// Expired expectations are deleted when they are checked.
+	if !e.SatisfiedExpectations("ns/checked") {
+		t.Errorf("expected expired expectations to be satisfied")
+	}
+	if _, exists, _ := e.GetExpectations("ns/checked"); exists {
+		t.Errorf("expected checked expired expectations to be deleted")
+	}
+
+	// Expired expectations which are never checked again are deleted by the
+	// next write.
+	if err := e.ExpectCreations("ns/live", 1); err != nil {
+		t.Fatal(err)
+	}
+	if keys := e.ListKeys(); len(keys) != 1 || keys[0] != "ns/live" {
+		t.Errorf("expected only the live expectations to remain, got %v", keys)
+	}
+}
+
+func TestExpiredUIDExpectationsAreCollected(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	uidExp := NewUIDTrackingControllerExpectations(NewControllerExpectationsWithTimeout(time.Minute, fakeClock))
+
+	if err := uidExp.ExpectDeletions("ns/deleted", []string{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	fakeClock.Step(2 * time.Minute)
+	for i := 0; i < minUIDCollectSize; i++ {
+		if err := uidExp.ExpectDeletions("ns/live", []string{"b"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if uidExp.GetUIDs("ns/deleted") != nil {
+		t.Errorf("expected the uids of expired expectations to be deleted")
+	}
+	if uidExp.GetUIDs("ns/live").Len() != 1 {
+		t.Errorf("expected the uids of live expectations to remain, got %v", uidExp.GetUIDs("ns/live"))
+	}
+}
+
+func TestObservingEventHandler(t *testing.T) {
+	e := NewUIDTrackingControllerExpectations(NewControllerExpectations())
+	handler := NewObservingEventHandler(e, func(obj interface{}) (string, bool) {
+		pod := obj.(*v1.Pod)
+		if ref := metav1.GetControllerOf(pod); ref != nil {
+			return pod.Namespace + "/" + ref.Name, true
+		}
+		return "", false
+	})
+
+	isController := true
+	newPod := func(name string) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       "ns",
+			UID:             types.UID(name),
+			OwnerReferences: []metav1.OwnerReference{{Name: "rs", Controller: &isController}},
+		}}
+	}
+	key := "ns/rs"
+
+	e.ExpectCreations(key, 2)
+	handler.OnAdd(newPod("a"), false)
+	handler.OnAdd(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "orphan", Namespace: "ns"}}, false)
+	if e.SatisfiedExpectations(key) {
+		t.Errorf("expected expectations to be unsatisfied")
+	}
+	handler.OnAdd(newPod("b"), false)
+	if !e.SatisfiedExpectations(key) {
+		t.Errorf("expected expectations to be satisfied")
+	}
+
+	e.ExpectDeletions(key, []string{"a", "b"})
+	deleting := newPod("a")
+	now := metav1.Now()
+	deleting.DeletionTimestamp = &now
+	handler.OnUpdate(newPod("a"), deleting)
+	// The final delete of a gracefully deleted pod must not be counted again.
+	handler.OnDelete(deleting)
+	if e.SatisfiedExpectations(key) {
+		t.Errorf("expected expectations to be unsatisfied")
+	}
+	handler.OnDelete(cache.DeletedFinalStateUnknown{Key: "ns/b", Obj: newPod("b")})
+	if !e.SatisfiedExpectations(key) {
+		t.Errorf("expected expectations to be satisfied")
+	}
+}
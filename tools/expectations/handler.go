@@ -0,0 +1,98 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package expectations
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ControllerKeyFunc returns the key of the controller owning the given
+// controllee, and false if the controllee has no controller of interest.
+type ControllerKeyFunc func(controllee interface{}) (string, bool)
+
+// NewObservingEventHandler returns an event handler for the informer of the
+// controllees that feeds their creations and deletions into expectations.
+//
+// A controllee that is added counts as a creation, unless it is already
+// being deleted. A controllee whose deletion timestamp gets set, or that is
+// deleted, counts as the deletion of its UID. The UID tracking makes sure
+// that a controllee is not counted twice when both happen.
+//
+// The returned handler only updates expectations. Controllers typically
+// register it alongside the handler that enqueues their keys, or call its
+// methods from that handler before enqueueing.
+func NewObservingEventHandler(expectations *UIDTrackingControllerExpectations, controllerKey ControllerKeyFunc) cache.ResourceEventHandler {
+	return &observingEventHandler{expectations: expectations, controllerKey: controllerKey}
+}
+
+type observingEventHandler struct {
+	expectations  *UIDTrackingControllerExpectations
+	controllerKey ControllerKeyFunc
+}
+
+func (h *observingEventHandler) OnAdd(obj interface{}, isInInitialList bool) {
+	key, ok := h.controllerKey(obj)
+	if !ok {
+		return
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+	if accessor.GetDeletionTimestamp() != nil {
+		// On a restart of the controller, it's possible a new controllee
+		// shows up in a state that is already pending deletion.
+		h.expectations.DeletionObserved(key, string(accessor.GetUID()))
+		return
+	}
+	h.expectations.CreationObserved(key)
+}
+
+func (h *observingEventHandler) OnUpdate(oldObj, newObj interface{}) {
+	oldAccessor, err := meta.Accessor(oldObj)
+	if err != nil {
+		return
+	}
+	newAccessor, err := meta.Accessor(newObj)
+	if err != nil {
+		return
+	}
+	if oldAccessor.GetDeletionTimestamp() != nil || newAccessor.GetDeletionTimestamp() == nil {
+		return
+	}
+	// The controllee is being gracefully deleted. Count the deletion as
+	// observed now, the final delete event may only arrive much later.
+	if key, ok := h.controllerKey(newObj); ok {
+		h.expectations.DeletionObserved(key, string(newAccessor.GetUID()))
+	}
+}
+
+func (h *observingEventHandler) OnDelete(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	key, ok := h.controllerKey(obj)
+	if !ok {
+		return
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+	h.expectations.DeletionObserved(key, string(accessor.GetUID()))
+}
@@ -68,6 +68,18 @@ type RetryMetric interface {
 	IncrementRetry(ctx context.Context, code string, method string, host string)
 }
 
+// RateLimiterQPSMetric sets the current rate of an adaptive client side rate
+// limiter, partitioned by limiter name and priority level.
+type RateLimiterQPSMetric interface {
+	Set(name string, priorityLevel string, qps float64)
+}
+
+// WarningMetric counts the warnings returned by the server, partitioned by
+// warning code and the group, version and resource of the request.
+type WarningMetric interface {
+	Increment(code string, group string, version string, resource string)
+}
+
 // TransportCacheMetric shows the number of entries in the internal transport cache
 type TransportCacheMetric interface {
 	Observe(value int)
@@ -94,6 +106,8 @@ var (
 	ResponseSize SizeMetric = noopSize{}
 	// RateLimiterLatency is the client side rate limiter latency metric.
 	RateLimiterLatency LatencyMetric = noopLatency{}
+	// RateLimiterQPS is the current rate of the adaptive client side rate limiters.
+	RateLimiterQPS RateLimiterQPSMetric = noopRateLimiterQPS{}
 	// RequestResult is the result metric that rest clients will update.
 	RequestResult ResultMetric = noopResult{}
 	// ExecPluginCalls is the number of calls made to an exec plugin, partitioned by
@@ -102,6 +116,9 @@ var (
 	// RequestRetry is the retry metric that tracks the number of
 	// retries sent to the server.
 	RequestRetry RetryMetric = noopRetry{}
+	// Warnings is the metric that counts warnings returned by the server to
+	// clients using an aggregating warning handler.
+	Warnings WarningMetric = noopWarning{}
 	// TransportCacheEntries is the metric that tracks the number of entries in the
 	// internal transport cache.
 	TransportCacheEntries TransportCacheMetric = noopTransportCache{}
@@ -119,9 +136,11 @@ type RegisterOpts struct {
 	RequestSize           SizeMetric
 	ResponseSize          SizeMetric
 	RateLimiterLatency    LatencyMetric
+	RateLimiterQPS        RateLimiterQPSMetric
 	RequestResult         ResultMetric
 	ExecPluginCalls       CallsMetric
 	RequestRetry          RetryMetric
+	Warnings              WarningMetric
 	TransportCacheEntries TransportCacheMetric
 	TransportCreateCalls  TransportCreateCallsMetric
 }
@@ -151,6 +170,9 @@ func Register(opts RegisterOpts) {
 		if opts.RateLimiterLatency != nil {
 			RateLimiterLatency = opts.RateLimiterLatency
 		}
+		if opts.RateLimiterQPS != nil {
+			RateLimiterQPS = opts.RateLimiterQPS
+		}
 		if opts.RequestResult != nil {
 			RequestResult = opts.RequestResult
 		}
@@ -160,6 +182,9 @@ func Register(opts RegisterOpts) {
 		if opts.RequestRetry != nil {
 			RequestRetry = opts.RequestRetry
 		}
+		if opts.Warnings != nil {
+			Warnings = opts.Warnings
+		}
 		if opts.TransportCacheEntries != nil {
 			TransportCacheEntries = opts.TransportCacheEntries
 		}
@@ -190,6 +215,10 @@ type noopSize struct{}
 
 func (noopSize) Observe(context.Context, string, string, float64) {}
 
+type noopRateLimiterQPS struct{}
+
+func (noopRateLimiterQPS) Set(string, string, float64) {}
+
 type noopResult struct{}
 
 func (noopResult) Increment(context.Context, string, string, string) {}
@@ -209,3 +238,7 @@ func (noopTransportCache) Observe(int) {}
 type noopTransportCreateCalls struct{}
 
 func (noopTransportCreateCalls) Increment(string) {}
+
+type noopWarning struct{}
+
+func (noopWarning) Increment(string, string, string, string) {}
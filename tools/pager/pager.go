@@ -41,6 +41,11 @@ func SimplePageFunc(fn func(opts metav1.ListOptions) (runtime.Object, error)) Li
 	}
 }
 
+// ListPageItemsFunc calls fn with every item of the list for the given list
+// options, as the items are decoded, and returns the metadata of the list. If
+// fn returns an error, it stops and returns that error.
+type ListPageItemsFunc func(ctx context.Context, opts metav1.ListOptions, fn func(obj runtime.Object) error) (*metav1.ListMeta, error)
+
 // ListPager assists client code in breaking large list queries into multiple
 // smaller chunks of PageSize or smaller. PageFn is expected to accept a
 // metav1.ListOptions that supports paging and return a list. The pager does
@@ -49,6 +54,11 @@ type ListPager struct {
 	PageSize int64
 	PageFn   ListPageFunc
 
+	// PageItemsFn optionally retrieves the pages item by item, e.g. by means
+	// of rest.Request.EachListItem. If set, it is used instead of PageFn so
+	// that no page is ever held in memory as a whole.
+	PageItemsFn ListPageItemsFunc
+
 	FullListIfExpired bool
 
 	// Number of pages to buffer
@@ -90,6 +100,9 @@ func (p *ListPager) ListWithAlloc(ctx context.Context, options metav1.ListOption
 }
 
 func (p *ListPager) list(ctx context.Context, options metav1.ListOptions, allocNew bool) (runtime.Object, bool, error) {
+	if p.PageItemsFn != nil {
+		return p.listItems(ctx, options)
+	}
 	if options.Limit == 0 {
 		options.Limit = p.PageSize
 	}
@@ -167,6 +180,68 @@ func (p *ListPager) list(ctx context.Context, options metav1.ListOptions, allocN
 	}
 }
 
+// listItems works like list, but retrieves the pages by means of p.PageItemsFn.
+// The items it decodes are never shared with other objects, so it always
+// returns a *metainternalversion.List.
+func (p *ListPager) listItems(ctx context.Context, options metav1.ListOptions) (runtime.Object, bool, error) {
+	if options.Limit == 0 {
+		options.Limit = p.PageSize
+	}
+	requestedResourceVersion := options.ResourceVersion
+	requestedResourceVersionMatch := options.ResourceVersionMatch
+	list := &metainternalversion.List{}
+	appendItem := func(obj runtime.Object) error {
+		list.Items = append(list.Items, obj)
+		return nil
+	}
+	paginatedResult := false
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, paginatedResult, ctx.Err()
+		default:
+		}
+
+		m, err := p.PageItemsFn(ctx, options, appendItem)
+		if err != nil {
+			// See list for when to fall back to a full list.
+			if !errors.IsResourceExpired(err) || !p.FullListIfExpired || options.Continue == "" {
+				return nil, paginatedResult, err
+			}
+			options.Limit = 0
+			options.Continue = ""
+			options.ResourceVersion = requestedResourceVersion
+			options.ResourceVersionMatch = requestedResourceVersionMatch
+			list = &metainternalversion.List{}
+			m, err = p.PageItemsFn(ctx, options, appendItem)
+			if err != nil {
+				return nil, paginatedResult, err
+			}
+			list.ResourceVersion = m.ResourceVersion
+			list.SelfLink = m.SelfLink
+			return list, paginatedResult, nil
+		}
+
+		if !paginatedResult {
+			list.ResourceVersion = m.ResourceVersion
+			list.SelfLink = m.SelfLink
+		}
+
+		// if we have no more items, return the list
+		if len(m.Continue) == 0 {
+			return list, paginatedResult, nil
+		}
+
+		// set the next loop up
+		options.Continue = m.Continue
+		// Clear the ResourceVersion(Match) on the subsequent List calls, see list.
+		options.ResourceVersion = ""
+		options.ResourceVersionMatch = ""
+		paginatedResult = true
+	}
+}
+
 // EachListItem fetches runtime.Object items using this ListPager and invokes fn on each item. If
 // fn returns an error, processing stops and that error is returned. If fn does not return an error,
 // any error encountered while retrieving the list from the server is returned. If the context
@@ -180,7 +255,13 @@ func (p *ListPager) list(ctx context.Context, options metav1.ListOptions, allocN
 // If items passed to fn are retained for different durations, and you want to avoid
 // retaining the whole slice returned by p.PageFn as long as any item is referenced,
 // use EachListItemWithAlloc instead.
+//
+// If p.PageItemsFn is set, the items are instead passed to fn as they are decoded, and no
+// chunks are buffered.
 func (p *ListPager) EachListItem(ctx context.Context, options metav1.ListOptions, fn func(obj runtime.Object) error) error {
+	if p.PageItemsFn != nil {
+		return p.eachPageItem(ctx, options, fn)
+	}
 	return p.eachListChunkBuffered(ctx, options, func(obj runtime.Object) error {
 		return meta.EachListItem(obj, fn)
 	})
@@ -191,6 +272,10 @@ func (p *ListPager) EachListItem(ctx context.Context, options metav1.ListOptions
 //
 // If the items passed to fn are not retained, or are retained for the same duration, use EachListItem instead for memory efficiency.
 func (p *ListPager) EachListItemWithAlloc(ctx context.Context, options metav1.ListOptions, fn func(obj runtime.Object) error) error {
+	if p.PageItemsFn != nil {
+		// The decoded items never share memory, there is nothing to copy.
+		return p.eachPageItem(ctx, options, fn)
+	}
 	return p.eachListChunkBuffered(ctx, options, func(obj runtime.Object) error {
 		return meta.EachListItemWithAlloc(obj, fn)
 	})
@@ -287,3 +372,29 @@ func (p *ListPager) eachListChunk(ctx context.Context, options metav1.ListOption
 		options.Continue = m.GetContinue()
 	}
 }
+
+// eachPageItem fetches the pages using p.PageItemsFn and invokes fn on each of their items, see
+// EachListItem.
+func (p *ListPager) eachPageItem(ctx context.Context, options metav1.ListOptions, fn func(obj runtime.Object) error) error {
+	if options.Limit == 0 {
+		options.Limit = p.PageSize
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		m, err := p.PageItemsFn(ctx, options, fn)
+		if err != nil {
+			return err
+		}
+		// if we have no more items, return.
+		if len(m.Continue) == 0 {
+			return nil
+		}
+		// set the next loop up
+		options.Continue = m.Continue
+	}
+}
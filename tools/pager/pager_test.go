@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metainternalversion "k8s.io/apimachinery/pkg/apis/meta/internalversion"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	metav1beta1 "k8s.io/apimachinery/pkg/apis/meta/v1beta1"
@@ -469,3 +470,116 @@ func TestListPager_eachListPageBuffered(t *testing.T) {
 		})
 	}
 }
+
+// pageItems adapts fn into a ListPageItemsFunc.
+func pageItems(fn ListPageFunc) ListPageItemsFunc {
+	return func(ctx context.Context, options metav1.ListOptions, itemFn func(obj runtime.Object) error) (*metav1.ListMeta, error) {
+		obj, err := fn(ctx, options)
+		if err != nil {
+			return nil, err
+		}
+		if err := meta.EachListItem(obj, itemFn); err != nil {
+			return nil, err
+		}
+		m, err := meta.ListAccessor(obj)
+		if err != nil {
+			return nil, err
+		}
+		return &metav1.ListMeta{ResourceVersion: m.GetResourceVersion(), Continue: m.GetContinue()}, nil
+	}
+}
+
+func TestListPager_ListPageItems(t *testing.T) {
+	tests := []struct {
+		name              string
+		pageFn            func(p *testPager) ListPageFunc
+		remaining         int
+		fullListIfExpired bool
+		options           metav1.ListOptions
+		want              runtime.Object
+		wantPaged         bool
+		isExpired         bool
+	}{
+		{
+			name:      "one page",
+			pageFn:    func(p *testPager) ListPageFunc { return p.PagedList },
+			remaining: 9,
+			want:      list(9, "rv:20"),
+		},
+		{
+			name:      "three pages with resourceVersion",
+			pageFn:    func(p *testPager) ListPageFunc { return p.PagedList },
+			remaining: 29,
+			options:   metav1.ListOptions{ResourceVersion: "rv:10"},
+			want:      list(29, "rv:20"),
+			wantPaged: true,
+		},
+		{
+			name:      "expires on second page",
+			pageFn:    func(p *testPager) ListPageFunc { return p.ExpiresOnSecondPage },
+			remaining: 29,
+			wantPaged: true,
+			isExpired: true,
+		},
+		{
+			name:              "expires on second page and then lists",
+			pageFn:            func(p *testPager) ListPageFunc { return p.ExpiresOnSecondPageThenFullList },
+			remaining:         29,
+			fullListIfExpired: true,
+			want:              list(29, "rv:20"),
+			wantPaged:         true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &ListPager{
+				PageSize:          10,
+				PageItemsFn:       pageItems(tt.pageFn(&testPager{t: t, expectPage: 10, remaining: tt.remaining, rv: "rv:20"})),
+				FullListIfExpired: tt.fullListIfExpired,
+			}
+			got, paginatedResult, err := p.List(context.Background(), tt.options)
+			if tt.isExpired != errors.IsResourceExpired(err) || (err != nil && !tt.isExpired) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantPaged != paginatedResult {
+				t.Errorf("paginatedResult = %t, want %t", paginatedResult, tt.wantPaged)
+			}
+			if tt.want != nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListPager.List() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListPager_EachPageItem(t *testing.T) {
+	p := &ListPager{
+		PageSize:    10,
+		PageItemsFn: pageItems((&testPager{t: t, expectPage: 10, remaining: 25, rv: "rv:20"}).PagedList),
+	}
+	var items []runtime.Object
+	if err := p.EachListItem(context.Background(), metav1.ListOptions{}, func(obj runtime.Object) error {
+		items = append(items, obj)
+		return nil
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := list(25, "rv:20").Items; !reflect.DeepEqual(items, want) {
+		t.Errorf("expected %v, got %v", want, items)
+	}
+
+	stop := fmt.Errorf("stop")
+	count := 0
+	p.PageItemsFn = pageItems((&testPager{t: t, expectPage: 10, remaining: 25, rv: "rv:20"}).PagedList)
+	if err := p.EachListItemWithAlloc(context.Background(), metav1.ListOptions{}, func(obj runtime.Object) error {
+		count++
+		if count == 12 {
+			return stop
+		}
+		return nil
+	}); err != stop {
+		t.Errorf("expected %v, got %v", stop, err)
+	}
+	if count != 12 {
+		t.Errorf("expected 12 items, got %d", count)
+	}
+}
@@ -0,0 +1,29 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package reconcile runs the reconciliation loop of a controller.
+//
+// Most controllers built with client-go share the same loop: informer event
+// handlers turn objects into keys and add them to a rate limited workqueue,
+// and a number of workers wait for the caches to sync, then take keys from
+// the queue, reconcile them, and forget, requeue or rate limit them
+// depending on the outcome. A Runner implements that loop around a
+// Reconciler, so that a controller only has to provide the reconciliation
+// of a single key.
+//
+// A Runner can also be run only while holding a leader election lease, see
+// Runner.RunWithLeaderElection.
+package reconcile // import "k8s.io/client-go/tools/reconcile"
@@ -0,0 +1,87 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcile
+
+import (
+	"context"
+	"sync"
+
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/klog/v2"
+)
+
+// RunWithLeaderElection runs leader election with config, and runs the
+// Runner while holding the lease. It returns when ctx is done or the lease
+// is lost, once the Runner has stopped.
+//
+// Once the lease is lost, the Runner drains its queue without holding the
+// lease anymore, so Options.DrainTimeout should be set well below
+// config.LeaseDuration to keep it from overlapping with the next leader.
+//
+// The callbacks of config are optional: OnStartedLeading is called in
+// parallel with Run, and OnStoppedLeading once the Runner has stopped. Since
+// a Runner can only be run once, a process that loses the lease usually
+// exits from OnStoppedLeading.
+func (r *Runner[K]) RunWithLeaderElection(ctx context.Context, config leaderelection.LeaderElectionConfig) error {
+	callbacks := config.Callbacks
+
+	// OnStartedLeading is called asynchronously, so it may only get to run
+	// after the leader election has stopped. It does not run the Runner
+	// anymore then.
+	var lock sync.Mutex
+	running, abandoned := false, false
+	stopped := make(chan struct{})
+
+	config.Callbacks.OnStartedLeading = func(ctx context.Context) {
+		lock.Lock()
+		if abandoned {
+			lock.Unlock()
+			return
+		}
+		running = true
+		lock.Unlock()
+
+		defer close(stopped)
+		if callbacks.OnStartedLeading != nil {
+			go callbacks.OnStartedLeading(ctx)
+		}
+		if err := r.Run(ctx); err != nil {
+			klog.Errorf("Failed to run %s: %v", r.name, err)
+		}
+	}
+	config.Callbacks.OnStoppedLeading = func() {}
+
+	le, err := leaderelection.NewLeaderElector(config)
+	if err != nil {
+		return err
+	}
+	if config.WatchDog != nil {
+		config.WatchDog.SetLeaderElection(le)
+	}
+	le.Run(ctx)
+
+	lock.Lock()
+	abandoned = !running
+	lock.Unlock()
+	if !abandoned {
+		<-stopped
+	}
+	if callbacks.OnStoppedLeading != nil {
+		callbacks.OnStoppedLeading()
+	}
+	return nil
+}
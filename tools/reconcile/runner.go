@@ -0,0 +1,304 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+)
+
+// Result is the outcome of a successful reconciliation of a key.
+type Result struct {
+	// Requeue requeues the key after the delay given by the rate limiter
+	// of the queue, as if the reconciliation had failed.
+	Requeue bool
+
+	// RequeueAfter, if positive, requeues the key after the given
+	// duration, and resets its rate limiting. It takes precedence over
+	// Requeue.
+	RequeueAfter time.Duration
+}
+
+// Reconciler reconciles the state of the object identified by a key.
+type Reconciler[K comparable] interface {
+	// Reconcile reconciles the state of the object identified by key. If
+	// it returns an error, the key is requeued with rate limiting.
+	// Otherwise, it is requeued according to the Result, if at all.
+	//
+	// A key is never reconciled by several workers at the same time.
+	Reconcile(ctx context.Context, key K) (Result, error)
+}
+
+// Func is a function that implements Reconciler.
+type Func[K comparable] func(ctx context.Context, key K) (Result, error)
+
+// Reconcile calls f(ctx, key).
+func (f Func[K]) Reconcile(ctx context.Context, key K) (Result, error) {
+	return f(ctx, key)
+}
+
+// KeyFunc returns the key to reconcile for an object received by an informer
+// event handler, and false if nothing is to be reconciled. The object may be
+// a cache.DeletedFinalStateUnknown.
+type KeyFunc[K comparable] func(obj interface{}) (K, bool)
+
+// MetaNamespaceKeyFunc is a KeyFunc returning the <namespace>/<name> key of
+// the object, see cache.DeletionHandlingMetaNamespaceKeyFunc.
+func MetaNamespaceKeyFunc(obj interface{}) (string, bool) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return "", false
+	}
+	return key, true
+}
+
+// Options configures a Runner.
+type Options[K comparable] struct {
+	// Name identifies the Runner in logs. It is also the name of the
+	// queue, which is used for its metrics, unless Queue is set.
+	Name string
+
+	// Workers is the number of keys reconciled concurrently. If
+	// unset/unspecified, it defaults to 1.
+	Workers int
+
+	// RateLimiter is the rate limiter of the queue. If unset/unspecified,
+	// it defaults to workqueue.DefaultTypedControllerRateLimiter. It is
+	// ignored if Queue is set.
+	RateLimiter workqueue.TypedRateLimiter[K]
+
+	// Queue optionally allows injecting a custom queue, like a priority or
+	// fair queue, instead of the default one. The Runner shuts it down when
+	// it stops.
+	Queue workqueue.TypedRateLimitingInterface[K]
+
+	// DrainTimeout, if positive, bounds how long Run keeps reconciling
+	// once its context is done. When it expires, the context of the
+	// ongoing reconciliations is cancelled and the keys still queued are
+	// dropped. If unset/unspecified, Run drains the queue however long it
+	// takes.
+	DrainTimeout time.Duration
+
+	// HandlerPanicPolicy, if set, makes the informers passed to Watch
+	// recover from panics of the KeyFunc instead of crashing, see
+	// cache.HandlerPanicPolicy. Watch then fails for informers which do not
+	// implement cache.HandlerOptionsAdder.
+	HandlerPanicPolicy *cache.HandlerPanicPolicy
+}
+
+// Runner runs the reconciliation loop of a Reconciler: it feeds the keys of
+// the objects received by informers into a queue, and reconciles them with a
+// number of workers once the informers have synced.
+type Runner[K comparable] struct {
+	name         string
+	reconciler   Reconciler[K]
+	workers      int
+	queue        workqueue.TypedRateLimitingInterface[K]
+	drainTimeout time.Duration
+	panicPolicy  *cache.HandlerPanicPolicy
+
+	lock      sync.Mutex
+	hasSynced []cache.InformerSynced
+	started   bool
+}
+
+// NewRunner returns a Runner of reconciler.
+func NewRunner[K comparable](reconciler Reconciler[K], options Options[K]) *Runner[K] {
+	if options.Workers <= 0 {
+		options.Workers = 1
+	}
+	if options.Queue == nil {
+		if options.RateLimiter == nil {
+			options.RateLimiter = workqueue.DefaultTypedControllerRateLimiter[K]()
+		}
+		options.Queue = workqueue.NewTypedRateLimitingQueueWithConfig(options.RateLimiter, workqueue.TypedRateLimitingQueueConfig[K]{
+			Name: options.Name,
+		})
+	}
+	return &Runner[K]{
+		name:         options.Name,
+		reconciler:   reconciler,
+		workers:      options.Workers,
+		queue:        options.Queue,
+		drainTimeout: options.DrainTimeout,
+		panicPolicy:  options.HandlerPanicPolicy,
+	}
+}
+
+// Watch adds an event handler to informer which enqueues the key of every
+// object that is added, updated or deleted. Run waits for the handler to
+// sync before starting the workers, so Watch should be called before Run.
+func (r *Runner[K]) Watch(informer cache.SharedInformer, keyFunc KeyFunc[K]) error {
+	enqueue := func(obj interface{}) {
+		if key, ok := keyFunc(obj); ok {
+			r.queue.Add(key)
+		}
+	}
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    enqueue,
+		UpdateFunc: func(_, obj interface{}) { enqueue(obj) },
+		DeleteFunc: enqueue,
+	}
+	var registration cache.ResourceEventHandlerRegistration
+	var err error
+	if r.panicPolicy != nil {
+		adder, ok := informer.(cache.HandlerOptionsAdder)
+		if !ok {
+			return fmt.Errorf("%s: informer of type %T does not support handler panic policies", r.name, informer)
+		}
+		registration, err = adder.AddEventHandlerWithOptions(handler, cache.HandlerOptions{PanicPolicy: r.panicPolicy})
+	} else {
+		registration, err = informer.AddEventHandler(handler)
+	}
+	if err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.hasSynced = append(r.hasSynced, registration.HasSynced)
+	return nil
+}
+
+// Enqueue adds key to the queue of keys to reconcile.
+func (r *Runner[K]) Enqueue(key K) {
+	r.queue.Add(key)
+}
+
+// EnqueueAfter adds key to the queue of keys to reconcile after the given
+// duration.
+func (r *Runner[K]) EnqueueAfter(key K, duration time.Duration) {
+	r.queue.AddAfter(key, duration)
+}
+
+// Run waits for the event handlers added by Watch to sync, then reconciles
+// keys until ctx is done.
+//
+// Once ctx is done, Run drains the queue: it stops accepting keys, including
+// requeued ones, but finishes the ongoing reconciliations and reconciles the
+// keys still in the queue before returning. Keys waiting to be added after a
+// delay are dropped. The context passed to Reconcile carries the values of
+// ctx, but is not done when ctx is; it is only cancelled when
+// Options.DrainTimeout expires. A Runner can only be run once.
+func (r *Runner[K]) Run(ctx context.Context) error {
+	defer utilruntime.HandleCrash()
+
+	r.lock.Lock()
+	if r.started {
+		r.lock.Unlock()
+		return fmt.Errorf("%s: already run", r.name)
+	}
+	r.started = true
+	hasSynced := r.hasSynced
+	r.lock.Unlock()
+
+	// Stop the waiting loop of the queue in any case, ShutDownWithDrain
+	// does not.
+	defer r.queue.ShutDown()
+
+	klog.Infof("Starting %s", r.name)
+	defer klog.Infof("Shutting down %s", r.name)
+
+	if !cache.WaitForNamedCacheSync(r.name, ctx.Done(), hasSynced...) {
+		return fmt.Errorf("%s: failed to wait for caches to sync", r.name)
+	}
+
+	reconcileCtx, abort := context.WithCancel(withoutCancel{ctx})
+	defer abort()
+
+	var wg sync.WaitGroup
+	wg.Add(r.workers)
+	for i := 0; i < r.workers; i++ {
+		go func() {
+			defer wg.Done()
+			for r.processNextWorkItem(reconcileCtx) {
+			}
+		}()
+	}
+
+	<-ctx.Done()
+	if r.drainTimeout > 0 {
+		timer := time.AfterFunc(r.drainTimeout, func() {
+			klog.Infof("%s: timed out draining the queue, aborting", r.name)
+			abort()
+		})
+		defer timer.Stop()
+	}
+	r.queue.ShutDownWithDrain()
+	// ShutDownWithDrain returns once no key is being reconciled, which may
+	// happen while the workers still have keys to get.
+	wg.Wait()
+	return nil
+}
+
+func (r *Runner[K]) processNextWorkItem(ctx context.Context) bool {
+	key, quit := r.queue.Get()
+	if quit {
+		return false
+	}
+	defer r.queue.Done(key)
+
+	if ctx.Err() != nil {
+		// The drain timed out, drop the keys left in the queue.
+		return true
+	}
+
+	result, err := r.reconciler.Reconcile(ctx, key)
+	switch {
+	case err != nil:
+		utilruntime.HandleError(fmt.Errorf("%s: failed to reconcile %v: %w", r.name, key, err))
+		r.queue.AddRateLimited(key)
+	case result.RequeueAfter > 0:
+		r.queue.Forget(key)
+		r.queue.AddAfter(key, result.RequeueAfter)
+	case result.Requeue:
+		r.queue.AddRateLimited(key)
+	default:
+		r.queue.Forget(key)
+	}
+	return true
+}
+
+// withoutCancel is a context with the values of its parent, which is never
+// done, so that the reconciliations survive the shutdown of the Runner.
+type withoutCancel struct {
+	parent context.Context
+}
+
+func (withoutCancel) Deadline() (deadline time.Time, ok bool) {
+	return time.Time{}, false
+}
+
+func (withoutCancel) Done() <-chan struct{} {
+	return nil
+}
+
+func (withoutCancel) Err() error {
+	return nil
+}
+
+func (c withoutCancel) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
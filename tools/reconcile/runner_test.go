@@ -0,0 +1,322 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	fcache "k8s.io/client-go/tools/cache/testing"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/client-go/util/workqueue"
+)
+
+type recorder struct {
+	lock  sync.Mutex
+	calls map[string]int
+}
+
+func (r *recorder) record(key string) int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.calls[key]++
+	return r.calls[key]
+}
+
+func (r *recorder) count(key string) int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.calls[key]
+}
+
+func newPodInformer(names ...string) cache.SharedIndexInformer {
+	source := fcache.NewFakeControllerSource()
+	for _, name := range names {
+		source.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"}})
+	}
+	return cache.NewSharedIndexInformer(source, &v1.Pod{}, 0, cache.Indexers{})
+}
+
+func TestRunner(t *testing.T) {
+	rec := &recorder{calls: map[string]int{}}
+	runner := NewRunner[string](Func[string](func(ctx context.Context, key string) (Result, error) {
+		n := rec.record(key)
+		switch {
+		case key == "ns/failing" && n == 1:
+			return Result{}, errors.New("failed")
+		case key == "ns/requeue" && n == 1:
+			return Result{Requeue: true}, nil
+		case key == "ns/requeue-after" && n == 1:
+			return Result{RequeueAfter: time.Millisecond}, nil
+		}
+		return Result{}, nil
+	}), Options[string]{
+		Name:        "test",
+		Workers:     2,
+		RateLimiter: workqueue.NewTypedItemExponentialFailureRateLimiter[string](time.Millisecond, time.Millisecond),
+	})
+
+	informer := newPodInformer("ok", "failing", "requeue", "requeue-after")
+	if err := runner.Watch(informer, MetaNamespaceKeyFunc); err != nil {
+		t.Fatal(err)
+	}
+	runner.Enqueue("extra")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go informer.Run(ctx.Done())
+	done := make(chan error)
+	go func() {
+		done <- runner.Run(ctx)
+	}()
+
+	expected := map[string]int{"ns/ok": 1, "ns/failing": 2, "ns/requeue": 2, "ns/requeue-after": 2, "extra": 1}
+	err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		for key, n := range expected {
+			if rec.count(key) != n {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		t.Fatalf("expected calls %v, got %v", expected, rec.calls)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if err := runner.Run(context.Background()); err == nil {
+		t.Error("expected an error when running the runner twice")
+	}
+}
+
+func TestRunnerHandlerPanicPolicy(t *testing.T) {
+	rec := &recorder{calls: map[string]int{}}
+	panicked := make(chan string, 1)
+	runner := NewRunner[string](Func[string](func(ctx context.Context, key string) (Result, error) {
+		rec.record(key)
+		return Result{}, nil
+	}), Options[string]{
+		Name: "test",
+		HandlerPanicPolicy: &cache.HandlerPanicPolicy{
+			OnPanic: func(key string, recovered interface{}) { panicked <- key },
+		},
+	})
+
+	// Informers which do not support handler options are rejected.
+	if err := runner.Watch(struct{ cache.SharedIndexInformer }{newPodInformer()}, MetaNamespaceKeyFunc); err == nil {
+		t.Error("expected an error for an informer without handler options")
+	}
+
+	informer := newPodInformer("bad", "ok")
+	if err := runner.Watch(informer, func(obj interface{}) (string, bool) {
+		key, ok := MetaNamespaceKeyFunc(obj)
+		if key == "ns/bad" {
+			panic("bad pod")
+		}
+		return key, ok
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go informer.Run(ctx.Done())
+	go runner.Run(ctx)
+
+	select {
+	case key := <-panicked:
+		if key != "ns/bad" {
+			t.Errorf("expected a panic for ns/bad, got %s", key)
+		}
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("expected the panic to be recovered")
+	}
+	if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		return rec.count("ns/ok") == 1, nil
+	}); err != nil {
+		t.Errorf("expected ns/ok to be reconciled, got %v", rec.calls)
+	}
+}
+
+func TestRunnerDrain(t *testing.T) {
+	started := make(chan struct{})
+	stopping := make(chan struct{})
+	rec := &recorder{calls: map[string]int{}}
+	runner := NewRunner[string](Func[string](func(ctx context.Context, key string) (Result, error) {
+		if rec.record(key) == 1 && key == "first" {
+			close(started)
+			<-stopping
+			if ctx.Err() != nil {
+				t.Errorf("expected the reconciliation to survive the shutdown, got %v", ctx.Err())
+			}
+			// Requeued keys are not reconciled again once shutting down.
+			return Result{Requeue: true}, nil
+		}
+		return Result{}, nil
+	}), Options[string]{Name: "test"})
+
+	runner.Enqueue("first")
+	runner.Enqueue("second")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- runner.Run(ctx)
+	}()
+	<-started
+	cancel()
+	if err := wait.PollImmediate(time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		return runner.queue.ShuttingDown(), nil
+	}); err != nil {
+		t.Fatal("Run did not start draining the queue")
+	}
+	close(stopping)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if n := rec.count("first"); n != 1 {
+		t.Errorf("expected the ongoing key to be reconciled once, got %d calls", n)
+	}
+	if n := rec.count("second"); n != 1 {
+		t.Errorf("expected the queued key to be drained, got %d calls", n)
+	}
+}
+
+func TestRunnerDrainTimeout(t *testing.T) {
+	started := make(chan struct{})
+	rec := &recorder{calls: map[string]int{}}
+	runner := NewRunner[string](Func[string](func(ctx context.Context, key string) (Result, error) {
+		rec.record(key)
+		close(started)
+		<-ctx.Done()
+		return Result{}, nil
+	}), Options[string]{Name: "test", DrainTimeout: 10 * time.Millisecond})
+
+	runner.Enqueue("first")
+	runner.Enqueue("second")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- runner.Run(ctx)
+	}()
+	<-started
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("Run did not abort the reconciliation when the drain timed out")
+	}
+	if n := rec.count("second"); n != 0 {
+		t.Errorf("expected the queued key to be dropped, got %d calls", n)
+	}
+}
+
+// fakeLock is an in-memory resourcelock.Interface.
+type fakeLock struct {
+	lock   sync.Mutex
+	record *resourcelock.LeaderElectionRecord
+}
+
+func (l *fakeLock) Get(ctx context.Context) (*resourcelock.LeaderElectionRecord, []byte, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.record == nil {
+		return nil, nil, apierrors.NewNotFound(schema.GroupResource{Resource: "leases"}, "fake")
+	}
+	record := *l.record
+	return &record, []byte(record.HolderIdentity), nil
+}
+
+func (l *fakeLock) Create(ctx context.Context, ler resourcelock.LeaderElectionRecord) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.record = &ler
+	return nil
+}
+
+func (l *fakeLock) Update(ctx context.Context, ler resourcelock.LeaderElectionRecord) error {
+	return l.Create(ctx, ler)
+}
+
+func (l *fakeLock) RecordEvent(string) {}
+
+func (l *fakeLock) Identity() string {
+	return "test"
+}
+
+func (l *fakeLock) Describe() string {
+	return "fake"
+}
+
+func TestRunWithLeaderElection(t *testing.T) {
+	reconciled := make(chan string, 1)
+	runner := NewRunner[string](Func[string](func(ctx context.Context, key string) (Result, error) {
+		reconciled <- key
+		return Result{}, nil
+	}), Options[string]{Name: "test"})
+	runner.Enqueue("key")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- runner.RunWithLeaderElection(ctx, leaderelection.LeaderElectionConfig{
+			Lock:          &fakeLock{},
+			LeaseDuration: time.Second,
+			RenewDeadline: 500 * time.Millisecond,
+			RetryPeriod:   100 * time.Millisecond,
+			Callbacks: leaderelection.LeaderCallbacks{
+				OnStoppedLeading: func() { close(stopped) },
+			},
+		})
+	}()
+
+	select {
+	case key := <-reconciled:
+		if key != "key" {
+			t.Errorf("expected key, got %s", key)
+		}
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("the key was not reconciled")
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-stopped:
+	default:
+		t.Error("expected OnStoppedLeading to be called")
+	}
+}
@@ -77,6 +77,10 @@ type Config struct {
 	//
 	// socks5 proxying does not currently support spdy streaming endpoints.
 	Proxy func(*http.Request) (*url.URL, error)
+
+	// ResponseCache optionally caches the responses to GET requests per user
+	// identity, see NewCacheRoundTripper.
+	ResponseCache ResponseCache
 }
 
 // DialHolder is used to make the wrapped function comparable so that it can be used as a map key.
@@ -0,0 +1,294 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transport
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gregjones/httpcache"
+	"github.com/peterbourgon/diskv"
+)
+
+// ResponseCache stores the responses cached by the round trippers of
+// NewCacheRoundTripper.
+type ResponseCache interface {
+	// Get returns the response stored with key, if any.
+	Get(key string) (responseBytes []byte, ok bool)
+	// Set stores the response with key.
+	Set(key string, responseBytes []byte)
+	// Delete removes the response stored with key.
+	Delete(key string)
+}
+
+// NewMemoryResponseCache returns a ResponseCache which stores the responses in
+// memory. Its size is not bounded: it holds one response per user and URL of
+// the objects read, which makes it suitable for clients reading a bounded set
+// of objects, like discovery documents, rather than arbitrary objects. List
+// responses are never cached, see NewCacheRoundTripper.
+func NewMemoryResponseCache() ResponseCache {
+	return httpcache.NewMemoryCache()
+}
+
+// NewDiskResponseCache returns a ResponseCache which stores the responses in
+// files in dir, which may be shared between processes.
+func NewDiskResponseCache(dir string) ResponseCache {
+	return &sumDiskCache{
+		disk: diskv.New(diskv.Options{
+			PathPerm: os.FileMode(0750),
+			FilePerm: os.FileMode(0660),
+			BasePath: dir,
+			TempDir:  filepath.Join(dir, ".diskv-temp"),
+		}),
+	}
+}
+
+// NewCacheRoundTripper returns a round tripper which caches the responses to
+// GET requests in cache. Only responses with an ETag or Last-Modified header
+// are stored; they are revalidated with If-None-Match and If-Modified-Since
+// requests, and served from the cache if the server responds with 304 Not
+// Modified. Such responses are marked with the X-From-Cache header.
+//
+// Watches, upgrades, paginated lists, i.e. requests with a limit or continue
+// parameter, and requests whose context was returned by WithoutResponseCache
+// bypass the cache. The rest client passes such a context for all list
+// requests, whose responses may be arbitrarily large.
+//
+// The responses are cached per user identity, i.e. by the Authorization and
+// Impersonate-* headers of the requests, so rt must not set these headers.
+func NewCacheRoundTripper(cache ResponseCache, rt http.RoundTripper) http.RoundTripper {
+	return &cacheRoundTripper{cache: cache, delegate: rt}
+}
+
+// newCacheRoundTripperForConfig returns the cache round tripper of
+// config.ResponseCache, which also caches the responses per client
+// certificate.
+func newCacheRoundTripperForConfig(config *Config, rt http.RoundTripper) http.RoundTripper {
+	cache := &cacheRoundTripper{cache: config.ResponseCache, delegate: rt}
+	switch {
+	case config.HasCertCallback():
+		getCert := config.TLS.GetCertHolder.GetCert
+		cache.clientCert = func() []byte {
+			cert, err := getCert()
+			if err != nil || cert == nil || len(cert.Certificate) == 0 {
+				return nil
+			}
+			return cert.Certificate[0]
+		}
+	case config.HasCertAuth():
+		clientCert := append([]byte(config.TLS.CertFile+"\x00"), config.TLS.CertData...)
+		cache.clientCert = func() []byte {
+			return clientCert
+		}
+	}
+	return cache
+}
+
+type cacheRoundTripper struct {
+	cache    ResponseCache
+	delegate http.RoundTripper
+	// clientCert optionally returns the client certificate of the requests,
+	// or an identifier of it.
+	clientCert func() []byte
+}
+
+type noResponseCacheKey struct{}
+
+// WithoutResponseCache returns a context which makes the round trippers of
+// NewCacheRoundTripper neither serve nor store the responses to the requests
+// made with it.
+func WithoutResponseCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noResponseCacheKey{}, true)
+}
+
+func (rt *cacheRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if bypassResponseCache(req) {
+		return rt.delegate.RoundTrip(req)
+	}
+	validated := &validatedRoundTripper{delegate: rt.delegate}
+	t := &httpcache.Transport{
+		Transport:           validated,
+		Cache:               identityCache{ResponseCache: rt.cache, identity: rt.identity(req)},
+		MarkCachedResponses: true,
+	}
+	resp, err := t.RoundTrip(req)
+	validated.restore(resp)
+	return resp, err
+}
+
+// bypassResponseCache returns whether the response to req must neither be
+// served from nor stored in the cache.
+func bypassResponseCache(req *http.Request) bool {
+	if len(req.Header.Get("Upgrade")) > 0 {
+		return true
+	}
+	if noCache, _ := req.Context().Value(noResponseCacheKey{}).(bool); noCache {
+		return true
+	}
+	query := req.URL.Query()
+	return query.Get("watch") == "true" || query.Has("limit") || query.Has("continue")
+}
+
+// validatedRoundTripper keeps httpcache from storing the responses without an
+// ETag or Last-Modified header, which could never be revalidated, by adding
+// no-store to their Cache-Control header. httpcache then neither buffers nor
+// stores them.
+type validatedRoundTripper struct {
+	delegate http.RoundTripper
+	// noStore is the response marked, and cacheControl its original
+	// Cache-Control header, which is restored before the response is
+	// returned. There is at most one since the round tripper is used for a
+	// single request.
+	noStore      *http.Response
+	cacheControl []string
+}
+
+func (rt *validatedRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := rt.delegate.RoundTrip(req)
+	if err != nil || resp.StatusCode == http.StatusNotModified {
+		// httpcache merges the headers of a 304 response into the cached
+		// response, which has a validator.
+		return resp, err
+	}
+	if len(resp.Header.Get("ETag")) > 0 || len(resp.Header.Get("Last-Modified")) > 0 {
+		return resp, err
+	}
+	if resp.Header == nil {
+		resp.Header = http.Header{}
+	}
+	rt.noStore, rt.cacheControl = resp, resp.Header.Values("Cache-Control")
+	// httpcache only parses the first Cache-Control header.
+	resp.Header.Set("Cache-Control", strings.Join(append(rt.cacheControl, "no-store"), ", "))
+	return resp, err
+}
+
+// restore restores the Cache-Control header of resp if it was marked.
+func (rt *validatedRoundTripper) restore(resp *http.Response) {
+	if resp == nil || resp != rt.noStore {
+		return
+	}
+	if len(rt.cacheControl) == 0 {
+		resp.Header.Del("Cache-Control")
+		return
+	}
+	resp.Header["Cache-Control"] = rt.cacheControl
+}
+
+// identity returns a digest of the identity of the user of req.
+func (rt *cacheRoundTripper) identity(req *http.Request) string {
+	h := sha256.New()
+	var keys []string
+	for key := range req.Header {
+		if key == "Authorization" || strings.HasPrefix(key, "Impersonate-") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(h, "%s:%q\n", key, req.Header[key])
+	}
+	if rt.clientCert != nil {
+		h.Write(rt.clientCert())
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (rt *cacheRoundTripper) CancelRequest(req *http.Request) {
+	tryCancelRequest(rt.WrappedRoundTripper(), req)
+}
+
+func (rt *cacheRoundTripper) WrappedRoundTripper() http.RoundTripper { return rt.delegate }
+
+// identityCache is a ResponseCache of the responses for the user with the
+// given identity.
+type identityCache struct {
+	ResponseCache
+	identity string
+}
+
+func (c identityCache) Get(key string) ([]byte, bool) {
+	return c.ResponseCache.Get(c.identity + " " + key)
+}
+
+func (c identityCache) Set(key string, responseBytes []byte) {
+	c.ResponseCache.Set(c.identity+" "+key, responseBytes)
+}
+
+func (c identityCache) Delete(key string) {
+	c.ResponseCache.Delete(c.identity + " " + key)
+}
+
+// A sumDiskCache is a cache backend for github.com/gregjones/httpcache. It is
+// similar to httpcache's diskcache package, but uses SHA256 sums to ensure
+// cache integrity at read time rather than fsyncing each cache entry to
+// increase the likelihood they will be persisted at write time. This avoids
+// significant performance degradation on MacOS.
+//
+// See https://github.com/kubernetes/kubernetes/issues/110753 for more.
+type sumDiskCache struct {
+	disk *diskv.Diskv
+}
+
+// Get the requested key from the cache on disk. If Get encounters an error, or
+// the returned value is not a SHA256 sum followed by bytes with a matching
+// checksum it will return false to indicate a cache miss.
+func (c *sumDiskCache) Get(key string) ([]byte, bool) {
+	b, err := c.disk.Read(sanitize(key))
+	if err != nil || len(b) < sha256.Size {
+		return []byte{}, false
+	}
+
+	response := b[sha256.Size:]
+	want := b[:sha256.Size] // The first 32 bytes of the file should be the SHA256 sum.
+	got := sha256.Sum256(response)
+	if !bytes.Equal(want, got[:]) {
+		return []byte{}, false
+	}
+
+	return response, true
+}
+
+// Set writes the response to a file on disk. The filename will be the SHA256
+// sum of the key. The file will contain a SHA256 sum of the response bytes,
+// followed by said response bytes.
+func (c *sumDiskCache) Set(key string, response []byte) {
+	s := sha256.Sum256(response)
+	_ = c.disk.Write(sanitize(key), append(s[:], response...)) // Nothing we can do with this error.
+}
+
+func (c *sumDiskCache) Delete(key string) {
+	_ = c.disk.Erase(sanitize(key)) // Nothing we can do with this error.
+}
+
+// Sanitize an httpcache key such that it can be used as a diskv key, which must
+// be a valid filename. The httpcache key will either be the requested URL (if
+// the request method was GET) or "<method> <url>" for other methods, per the
+// httpcache.cacheKey function.
+func sanitize(key string) string {
+	// These keys are not sensitive. We use sha256 to avoid a (potentially
+	// malicious) collision causing the wrong cache data to be written or
+	// accessed.
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(key)))
+}
@@ -0,0 +1,367 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transport
+
+import (
+	"context"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/peterbourgon/diskv"
+	"github.com/stretchr/testify/assert"
+)
+
+func BenchmarkDiskCache(b *testing.B) {
+	cacheDir, err := os.MkdirTemp("", "cache-rt")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(cacheDir)
+
+	d := diskv.New(diskv.Options{
+		PathPerm: os.FileMode(0750),
+		FilePerm: os.FileMode(0660),
+		BasePath: cacheDir,
+		TempDir:  filepath.Join(cacheDir, ".diskv-temp"),
+	})
+
+	k := "localhost:8080/apis/batch/v1.json"
+	v, err := os.ReadFile("../discovery/testdata/apis/batch/v1.json")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	c := sumDiskCache{disk: d}
+
+	for n := 0; n < b.N; n++ {
+		c.Set(k, v)
+		c.Get(k)
+		c.Delete(k)
+	}
+}
+
+// etagRoundTripper serves the same object with an ETag, unless noETag is set,
+// and records the If-None-Match headers of the requests.
+type etagRoundTripper struct {
+	noETag bool
+
+	lock        sync.Mutex
+	ifNoneMatch []string
+}
+
+func (rt *etagRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.lock.Lock()
+	defer rt.lock.Unlock()
+	rt.ifNoneMatch = append(rt.ifNoneMatch, req.Header.Get("If-None-Match"))
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header: http.Header{
+			"Etag":          []string{`"1"`},
+			"Cache-Control": []string{"no-cache, private"},
+		},
+		Body:    io.NopCloser(strings.NewReader("object")),
+		Request: req,
+	}
+	if rt.noETag {
+		resp.Header.Del("Etag")
+	}
+	if req.Header.Get("If-None-Match") == `"1"` {
+		resp.StatusCode = http.StatusNotModified
+		resp.Body = http.NoBody
+	}
+	return resp, nil
+}
+
+func (rt *etagRoundTripper) requests() []string {
+	rt.lock.Lock()
+	defer rt.lock.Unlock()
+	requests := rt.ifNoneMatch
+	rt.ifNoneMatch = nil
+	return requests
+}
+
+func getCached(t *testing.T, rt http.RoundTripper, url, token string) (string, bool) {
+	resp, body := getCachedWithContext(t, context.Background(), rt, url, token)
+	return body, resp.Header.Get("X-From-Cache") == "1"
+}
+
+func getCachedWithContext(t *testing.T, ctx context.Context, rt http.RoundTripper, url, token string) (*http.Response, string) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(body)
+}
+
+func TestCacheRoundTripper(t *testing.T) {
+	s := &etagRoundTripper{}
+	rt := NewCacheRoundTripper(NewMemoryResponseCache(), s)
+
+	for i, tc := range []struct {
+		path, token string
+		fromCache   bool
+		ifNoneMatch string
+	}{
+		{path: "/api", token: "a"},
+		{path: "/api", token: "a", fromCache: true, ifNoneMatch: `"1"`},
+		// The responses are cached per user.
+		{path: "/api", token: "b"},
+		{path: "/api", token: "b", fromCache: true, ifNoneMatch: `"1"`},
+		{path: "/api"},
+		// Watches are not cached.
+		{path: "/api?watch=true", token: "a"},
+		{path: "/api?watch=true", token: "a"},
+		// Neither are paginated lists.
+		{path: "/api?limit=500", token: "a"},
+		{path: "/api?limit=500", token: "a"},
+		{path: "/api?continue=abc", token: "a"},
+		{path: "/api?continue=abc", token: "a"},
+	} {
+		body, fromCache := getCached(t, rt, "https://127.0.0.1"+tc.path, tc.token)
+		if body != "object" {
+			t.Errorf("%d: expected the object, got %q", i, body)
+		}
+		if fromCache != tc.fromCache {
+			t.Errorf("%d: expected the response to be from the cache: %v, got %v", i, tc.fromCache, fromCache)
+		}
+		assert.Equal(t, []string{tc.ifNoneMatch}, s.requests(), "%d: unexpected If-None-Match headers", i)
+	}
+}
+
+func TestCacheRoundTripperWithoutResponseCache(t *testing.T) {
+	s := &etagRoundTripper{}
+	rt := NewCacheRoundTripper(NewMemoryResponseCache(), s)
+	ctx := WithoutResponseCache(context.Background())
+
+	for i := 0; i < 2; i++ {
+		resp, body := getCachedWithContext(t, ctx, rt, "https://127.0.0.1/api/v1/pods", "a")
+		assert.Equal(t, "object", body)
+		assert.Empty(t, resp.Header.Get("X-From-Cache"), "%d: unexpected response from the cache", i)
+		assert.Equal(t, []string{""}, s.requests(), "%d: unexpected If-None-Match headers", i)
+	}
+}
+
+func TestCacheRoundTripperWithoutValidator(t *testing.T) {
+	s := &etagRoundTripper{noETag: true}
+	cache := &countingCache{ResponseCache: NewMemoryResponseCache()}
+	rt := NewCacheRoundTripper(cache, s)
+
+	for i := 0; i < 2; i++ {
+		resp, body := getCachedWithContext(t, context.Background(), rt, "https://127.0.0.1/api", "a")
+		assert.Equal(t, "object", body)
+		assert.Empty(t, resp.Header.Get("X-From-Cache"), "%d: unexpected response from the cache", i)
+		// The Cache-Control header the cache relies on is not exposed.
+		assert.Equal(t, []string{"no-cache, private"}, resp.Header.Values("Cache-Control"), "%d: unexpected Cache-Control header", i)
+		assert.Equal(t, []string{""}, s.requests(), "%d: unexpected If-None-Match headers", i)
+	}
+	assert.Equal(t, 0, cache.sets, "responses without a validator must not be stored")
+}
+
+// countingCache counts the responses stored in a ResponseCache.
+type countingCache struct {
+	ResponseCache
+	sets int
+}
+
+func (c *countingCache) Set(key string, responseBytes []byte) {
+	c.sets++
+	c.ResponseCache.Set(key, responseBytes)
+}
+
+func TestCacheRoundTripperForConfig(t *testing.T) {
+	s := &etagRoundTripper{}
+	cache := NewDiskResponseCache(t.TempDir())
+	newRoundTripper := func(config *Config) http.RoundTripper {
+		config.ResponseCache = cache
+		rt, err := HTTPWrappersForConfig(config, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rt
+	}
+
+	_, fromCache := getCached(t, newRoundTripper(&Config{BearerToken: "a"}), "https://127.0.0.1/api", "")
+	assert.False(t, fromCache)
+	// The cache is shared with other round trippers of the same user.
+	_, fromCache = getCached(t, newRoundTripper(&Config{BearerToken: "a"}), "https://127.0.0.1/api", "")
+	assert.True(t, fromCache)
+	_, fromCache = getCached(t, newRoundTripper(&Config{BearerToken: "b"}), "https://127.0.0.1/api", "")
+	assert.False(t, fromCache)
+	_, fromCache = getCached(t, newRoundTripper(&Config{BearerToken: "a", Impersonate: ImpersonationConfig{UserName: "c"}}), "https://127.0.0.1/api", "")
+	assert.False(t, fromCache)
+}
+
+func TestSumDiskCache(t *testing.T) {
+	assert := assert.New(t)
+
+	// Ensure that we'll return a cache miss if the backing file doesn't exist.
+	t.Run("NoSuchKey", func(t *testing.T) {
+		cacheDir, err := os.MkdirTemp("", "cache-test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(cacheDir)
+		d := diskv.New(diskv.Options{BasePath: cacheDir, TempDir: filepath.Join(cacheDir, ".diskv-temp")})
+		c := &sumDiskCache{disk: d}
+
+		key := "testing"
+
+		got, ok := c.Get(key)
+		assert.False(ok)
+		assert.Equal([]byte{}, got)
+	})
+
+	// Ensure that we'll return a cache miss if the backing file is empty.
+	t.Run("EmptyFile", func(t *testing.T) {
+		cacheDir, err := os.MkdirTemp("", "cache-test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(cacheDir)
+		d := diskv.New(diskv.Options{BasePath: cacheDir, TempDir: filepath.Join(cacheDir, ".diskv-temp")})
+		c := &sumDiskCache{disk: d}
+
+		key := "testing"
+
+		f, err := os.Create(filepath.Join(cacheDir, sanitize(key)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+
+		got, ok := c.Get(key)
+		assert.False(ok)
+		assert.Equal([]byte{}, got)
+	})
+
+	// Ensure that we'll return a cache miss if the backing has an invalid
+	// checksum.
+	t.Run("InvalidChecksum", func(t *testing.T) {
+		cacheDir, err := os.MkdirTemp("", "cache-test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(cacheDir)
+		d := diskv.New(diskv.Options{BasePath: cacheDir, TempDir: filepath.Join(cacheDir, ".diskv-temp")})
+		c := &sumDiskCache{disk: d}
+
+		key := "testing"
+		value := []byte("testing")
+		mismatchedValue := []byte("testink")
+		sum := sha256.Sum256(value)
+
+		// Create a file with the sum of 'value' followed by the bytes of
+		// 'mismatchedValue'.
+		f, err := os.Create(filepath.Join(cacheDir, sanitize(key)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write(sum[:])
+		f.Write(mismatchedValue)
+		f.Close()
+
+		// The mismatched checksum should result in a cache miss.
+		got, ok := c.Get(key)
+		assert.False(ok)
+		assert.Equal([]byte{}, got)
+	})
+
+	// Ensure that our disk cache will happily cache over the top of an existing
+	// value. We depend on this behaviour to recover from corrupted cache
+	// entries. When Get detects a bad checksum it will return a cache miss.
+	// This should cause httpcache to fall back to its underlying transport and
+	// to subsequently cache the new value, overwriting the corrupt one.
+	t.Run("OverwriteExistingKey", func(t *testing.T) {
+		cacheDir, err := os.MkdirTemp("", "cache-test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(cacheDir)
+		d := diskv.New(diskv.Options{BasePath: cacheDir, TempDir: filepath.Join(cacheDir, ".diskv-temp")})
+		c := &sumDiskCache{disk: d}
+
+		key := "testing"
+		value := []byte("cool value!")
+
+		// Write a value.
+		c.Set(key, value)
+		got, ok := c.Get(key)
+
+		// Ensure we can read back what we wrote.
+		assert.True(ok)
+		assert.Equal(value, got)
+
+		differentValue := []byte("I'm different!")
+
+		// Write a different value.
+		c.Set(key, differentValue)
+		got, ok = c.Get(key)
+
+		// Ensure we can read back the different value.
+		assert.True(ok)
+		assert.Equal(differentValue, got)
+	})
+
+	// Ensure that deleting a key does in fact delete it.
+	t.Run("DeleteKey", func(t *testing.T) {
+		cacheDir, err := os.MkdirTemp("", "cache-test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(cacheDir)
+		d := diskv.New(diskv.Options{BasePath: cacheDir, TempDir: filepath.Join(cacheDir, ".diskv-temp")})
+		c := &sumDiskCache{disk: d}
+
+		key := "testing"
+		value := []byte("coolValue")
+
+		c.Set(key, value)
+
+		// Ensure we successfully set the value.
+		got, ok := c.Get(key)
+		assert.True(ok)
+		assert.Equal(value, got)
+
+		c.Delete(key)
+
+		// Ensure the value is gone.
+		got, ok = c.Get(key)
+		assert.False(ok)
+		assert.Equal([]byte{}, got)
+
+		// Ensure that deleting a non-existent value is a no-op.
+		c.Delete(key)
+	})
+}
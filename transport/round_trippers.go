@@ -37,6 +37,11 @@ import (
 // HTTP2 clients). Pure HTTP clients should use the RoundTripper returned from
 // New.
 func HTTPWrappersForConfig(config *Config, rt http.RoundTripper) (http.RoundTripper, error) {
+	// The cache comes first, to see the authentication and impersonation
+	// headers of the requests it keys the responses by.
+	if config.ResponseCache != nil {
+		rt = newCacheRoundTripperForConfig(config, rt)
+	}
 	if config.WrapTransport != nil {
 		rt = config.WrapTransport(rt)
 	}
@@ -0,0 +1,338 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cbor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"reflect"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer/recognizer"
+)
+
+// ContentTypeCBOR is the media type of CBOR encoded objects and of CBOR watch
+// streams, which are sequences of CBOR encoded watch events (RFC 8742).
+const ContentTypeCBOR = "application/cbor"
+
+// IsContentTypeCBOR returns whether contentType is the CBOR media type,
+// ignoring its parameters.
+func IsContentTypeCBOR(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == ContentTypeCBOR
+}
+
+// selfDescribedCBOR is the encoding of the self-described CBOR tag (RFC 8949,
+// section 3.4.6), which prefixes every object encoded by the Serializer and
+// lets it recognize CBOR data.
+var selfDescribedCBOR = []byte{0xd9, 0xd9, 0xf7}
+
+var encMode = func() cbor.EncMode {
+	mode, err := cbor.EncOptions{
+		// Encode maps deterministically, like encoding/json does.
+		Sort: cbor.SortBytewiseLexical,
+	}.EncMode()
+	if err != nil {
+		panic(err)
+	}
+	return mode
+}()
+
+var decMode = func() cbor.DecMode {
+	mode, err := cbor.DecOptions{
+		// Decode to the types of the JSON data model, which is the one of
+		// unstructured objects.
+		DefaultMapType: reflect.TypeOf(map[string]interface{}(nil)),
+		IntDec:         cbor.IntDecConvertSignedOrFail,
+		DupMapKey:      cbor.DupMapKeyEnforcedAPF,
+		// Objects, and lists in particular, can be much larger and deeper
+		// than the defaults of the library allow.
+		MaxNestedLevels:  10000,
+		MaxArrayElements: 2147483647,
+		MaxMapPairs:      2147483647,
+	}.DecMode()
+	if err != nil {
+		panic(err)
+	}
+	return mode
+}()
+
+// Serializer encodes objects to CBOR and decodes them from CBOR, by way of
+// their unstructured content, like the JSON serializer of
+// k8s.io/apimachinery/pkg/runtime/serializer/json does by way of their JSON
+// encoding.
+type Serializer struct {
+	creater runtime.ObjectCreater
+	typer   runtime.ObjectTyper
+}
+
+var _ runtime.Serializer = &Serializer{}
+var _ recognizer.RecognizingDecoder = &Serializer{}
+
+// NewSerializer creates a CBOR serializer which creates and types the objects
+// it decodes with the given creater and typer.
+func NewSerializer(creater runtime.ObjectCreater, typer runtime.ObjectTyper) *Serializer {
+	return &Serializer{
+		creater: creater,
+		typer:   typer,
+	}
+}
+
+// NewSerializerInfo returns the runtime.SerializerInfo of a CBOR serializer
+// created with the given creater and typer, for negotiated serializers to
+// support CBOR with.
+func NewSerializerInfo(creater runtime.ObjectCreater, typer runtime.ObjectTyper) runtime.SerializerInfo {
+	serializer := NewSerializer(creater, typer)
+	return runtime.SerializerInfo{
+		MediaType:        ContentTypeCBOR,
+		MediaTypeType:    "application",
+		MediaTypeSubType: "cbor",
+		Serializer:       serializer,
+		StreamSerializer: &runtime.StreamSerializerInfo{
+			Serializer: serializer,
+			Framer:     Framer,
+		},
+	}
+}
+
+// NegotiatedSerializer returns a runtime.NegotiatedSerializer which supports
+// the media types of ns and CBOR, by means of a CBOR serializer created with
+// the given creater and typer. It lets clients of codec factories which do not
+// support CBOR use it, e.g.
+//
+//	config.ContentType = cbor.ContentTypeCBOR
+//	config.NegotiatedSerializer = cbor.NegotiatedSerializer(scheme.Codecs.WithoutConversion(), scheme.Scheme, scheme.Scheme)
+func NegotiatedSerializer(ns runtime.NegotiatedSerializer, creater runtime.ObjectCreater, typer runtime.ObjectTyper) runtime.NegotiatedSerializer {
+	return negotiatedSerializer{
+		NegotiatedSerializer: ns,
+		info:                 NewSerializerInfo(creater, typer),
+	}
+}
+
+type negotiatedSerializer struct {
+	runtime.NegotiatedSerializer
+	info runtime.SerializerInfo
+}
+
+func (s negotiatedSerializer) SupportedMediaTypes() []runtime.SerializerInfo {
+	infos := s.NegotiatedSerializer.SupportedMediaTypes()
+	for _, info := range infos {
+		if info.MediaType == ContentTypeCBOR {
+			return infos
+		}
+	}
+	return append(infos[:len(infos):len(infos)], s.info)
+}
+
+// Identifier implements runtime.Encoder.
+func (s *Serializer) Identifier() runtime.Identifier {
+	return "cbor"
+}
+
+// Encode serializes the provided object to the given writer.
+func (s *Serializer) Encode(obj runtime.Object, w io.Writer) error {
+	if co, ok := obj.(runtime.CacheableObject); ok {
+		return co.CacheEncode(s.Identifier(), s.doEncode, w)
+	}
+	return s.doEncode(obj, w)
+}
+
+func (s *Serializer) doEncode(obj runtime.Object, w io.Writer) error {
+	var content interface{}
+	switch t := obj.(type) {
+	case *metav1.WatchEvent:
+		event, err := s.toWatchEvent(t)
+		if err != nil {
+			return err
+		}
+		content = event
+	case runtime.Unstructured:
+		content = t.UnstructuredContent()
+	default:
+		u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return err
+		}
+		content = u
+	}
+	data, err := encMode.Marshal(cbor.Tag{Number: 55799, Content: content})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// watchEvent is the CBOR encoding of metav1.WatchEvent, whose object is
+// embedded as is rather than as a runtime.RawExtension.
+type watchEvent struct {
+	Type   string          `cbor:"type"`
+	Object cbor.RawMessage `cbor:"object"`
+}
+
+func (s *Serializer) toWatchEvent(event *metav1.WatchEvent) (*watchEvent, error) {
+	raw := event.Object.Raw
+	if event.Object.Object != nil {
+		var buf bytes.Buffer
+		if err := s.Encode(event.Object.Object, &buf); err != nil {
+			return nil, err
+		}
+		raw = buf.Bytes()
+	}
+	return &watchEvent{Type: event.Type, Object: raw}, nil
+}
+
+// Decode decodes CBOR data into an object. It follows the rules of the JSON
+// serializer of k8s.io/apimachinery/pkg/runtime/serializer/json: the kind of
+// the data is defaulted from gvk, into is used if it is of that kind, is
+// unstructured or is not registered with the typer, and otherwise a new object
+// is created with the creater.
+func (s *Serializer) Decode(data []byte, gvk *schema.GroupVersionKind, into runtime.Object) (runtime.Object, *schema.GroupVersionKind, error) {
+	if event, ok := into.(*metav1.WatchEvent); ok {
+		return decodeWatchEvent(data, event)
+	}
+
+	var content map[string]interface{}
+	if err := decMode.Unmarshal(bytes.TrimPrefix(data, selfDescribedCBOR), &content); err != nil {
+		return nil, nil, fmt.Errorf("unable to decode CBOR: %w", err)
+	}
+	actual, err := kindOf(content)
+	if err != nil {
+		return nil, nil, err
+	}
+	if gvk != nil {
+		*actual = gvkWithDefaults(*actual, *gvk)
+	}
+
+	if unk, ok := into.(*runtime.Unknown); ok && unk != nil {
+		unk.Raw = data
+		unk.ContentType = ContentTypeCBOR
+		unk.GetObjectKind().SetGroupVersionKind(*actual)
+		return unk, actual, nil
+	}
+
+	if into != nil {
+		_, isUnstructured := into.(runtime.Unstructured)
+		types, _, err := s.typer.ObjectKinds(into)
+		switch {
+		case runtime.IsNotRegisteredError(err), isUnstructured:
+			if err := fromContent(content, into); err != nil {
+				return nil, actual, err
+			}
+			if isUnstructured {
+				*actual = into.GetObjectKind().GroupVersionKind()
+				if len(actual.Kind) == 0 {
+					return nil, actual, runtime.NewMissingKindErr("<CBOR>")
+				}
+			}
+			return into, actual, nil
+		case err != nil:
+			return nil, actual, err
+		default:
+			*actual = gvkWithDefaults(*actual, types[0])
+		}
+	}
+
+	if len(actual.Kind) == 0 {
+		return nil, actual, runtime.NewMissingKindErr("<CBOR>")
+	}
+	if len(actual.Version) == 0 {
+		return nil, actual, runtime.NewMissingVersionErr("<CBOR>")
+	}
+
+	obj, err := runtime.UseOrCreateObject(s.typer, s.creater, *actual, into)
+	if err != nil {
+		return nil, actual, err
+	}
+	if err := fromContent(content, obj); err != nil {
+		return nil, actual, err
+	}
+	return obj, actual, nil
+}
+
+// RecognizesData implements recognizer.RecognizingDecoder.
+func (s *Serializer) RecognizesData(data []byte) (ok, unknown bool, err error) {
+	return bytes.HasPrefix(data, selfDescribedCBOR), false, nil
+}
+
+func decodeWatchEvent(data []byte, into *metav1.WatchEvent) (runtime.Object, *schema.GroupVersionKind, error) {
+	var event watchEvent
+	if err := decMode.Unmarshal(bytes.TrimPrefix(data, selfDescribedCBOR), &event); err != nil {
+		return nil, nil, fmt.Errorf("unable to decode CBOR watch event: %w", err)
+	}
+	into.Type = event.Type
+	into.Object = runtime.RawExtension{Raw: event.Object}
+	return into, &schema.GroupVersionKind{Group: metav1.GroupName, Version: "v1", Kind: "WatchEvent"}, nil
+}
+
+// kindOf returns the kind stored in the unstructured content of an object.
+func kindOf(content map[string]interface{}) (*schema.GroupVersionKind, error) {
+	apiVersion, _ := content["apiVersion"].(string)
+	kind, _ := content["kind"].(string)
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return nil, err
+	}
+	gvk := gv.WithKind(kind)
+	return &gvk, nil
+}
+
+// fromContent sets the unstructured content of obj.
+func fromContent(content map[string]interface{}, obj runtime.Object) error {
+	switch t := obj.(type) {
+	case *unstructured.UnstructuredList:
+		// Like unstructured.UnstructuredJSONScheme, hold the items only in
+		// Items and default their kind to the one of the list.
+		t.SetUnstructuredContent(content)
+		delete(t.Object, "items")
+		apiVersion, kind := t.GetAPIVersion(), strings.TrimSuffix(t.GetKind(), "List")
+		for i := range t.Items {
+			item := &t.Items[i]
+			if len(item.GetKind()) == 0 && len(item.GetAPIVersion()) == 0 {
+				item.SetAPIVersion(apiVersion)
+				item.SetKind(kind)
+			}
+		}
+		return nil
+	case runtime.Unstructured:
+		t.SetUnstructuredContent(content)
+		return nil
+	}
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(content, obj)
+}
+
+func gvkWithDefaults(actual, defaultGVK schema.GroupVersionKind) schema.GroupVersionKind {
+	if len(actual.Kind) == 0 {
+		actual.Kind = defaultGVK.Kind
+	}
+	if len(actual.Version) == 0 && len(actual.Group) == 0 {
+		actual.Group = defaultGVK.Group
+		actual.Version = defaultGVK.Version
+	}
+	if len(actual.Version) == 0 && actual.Group == defaultGVK.Group {
+		actual.Version = defaultGVK.Version
+	}
+	return actual
+}
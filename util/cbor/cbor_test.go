@@ -0,0 +1,219 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cbor
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer/streaming"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+func testPod(name string) *v1.Pod {
+	return &v1.Pod{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns", Labels: map[string]string{"app": "test"}},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{{Name: "c", Image: "image"}},
+		},
+	}
+}
+
+func TestSerializerRoundTrip(t *testing.T) {
+	s := NewSerializer(scheme.Scheme, scheme.Scheme)
+	pod := testPod("a")
+
+	data, err := runtime.Encode(s, pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ok, _, _ := s.RecognizesData(data); !ok {
+		t.Errorf("expected the encoded data to be recognized")
+	}
+	if ok, _, _ := s.RecognizesData([]byte(`{"kind":"Pod"}`)); ok {
+		t.Errorf("expected JSON data not to be recognized")
+	}
+
+	obj, gvk, err := s.Decode(data, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := v1.SchemeGroupVersion.WithKind("Pod"), *gvk; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if !reflect.DeepEqual(pod, obj) {
+		t.Errorf("expected %#v, got %#v", pod, obj)
+	}
+
+	u := &unstructured.Unstructured{}
+	if _, _, err := s.Decode(data, nil, u); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := "a", u.GetName(); e != a {
+		t.Errorf("expected %q, got %q", e, a)
+	}
+	// Unstructured content must only hold the types of the JSON data model.
+	expected, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(expected, u.Object) {
+		t.Errorf("expected %#v, got %#v", expected, u.Object)
+	}
+
+	data, err = runtime.Encode(s, u)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	obj, _, err = s.Decode(data, nil, &v1.Pod{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(pod, obj) {
+		t.Errorf("expected %#v, got %#v", pod, obj)
+	}
+}
+
+func TestSerializerDecodeList(t *testing.T) {
+	s := NewSerializer(scheme.Scheme, scheme.Scheme)
+	list := &unstructured.UnstructuredList{Object: map[string]interface{}{"apiVersion": "v1", "kind": "PodList"}}
+	for _, name := range []string{"a", "b"} {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(testPod(name))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		list.Items = append(list.Items, unstructured.Unstructured{Object: content})
+	}
+	data, err := runtime.Encode(s, list)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	obj, _, err := s.Decode(data, nil, &unstructured.UnstructuredList{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(list, obj) {
+		t.Errorf("expected %#v, got %#v", list, obj)
+	}
+
+	obj, _, err = s.Decode(data, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	podList, ok := obj.(*v1.PodList)
+	if !ok {
+		t.Fatalf("expected a *v1.PodList, got %T", obj)
+	}
+	if e, a := 2, len(podList.Items); e != a {
+		t.Errorf("expected %d items, got %d", e, a)
+	}
+}
+
+func TestSerializerDecodeDefaults(t *testing.T) {
+	s := NewSerializer(scheme.Scheme, scheme.Scheme)
+	data, err := runtime.Encode(s, &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "a"},
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, _, err := s.Decode(data, nil, nil); !runtime.IsMissingKind(err) {
+		t.Errorf("expected a missing kind error, got %v", err)
+	}
+	defaults := v1.SchemeGroupVersion.WithKind("Pod")
+	obj, gvk, err := s.Decode(data, &defaults, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := defaults, *gvk; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if pod, ok := obj.(*v1.Pod); !ok || pod.Name != "a" {
+		t.Errorf("unexpected object %#v", obj)
+	}
+}
+
+func TestWatchStream(t *testing.T) {
+	s := NewSerializer(scheme.Scheme, scheme.Scheme)
+	// The second pod does not fit the initial buffer of the decoder, which
+	// has to read its frame in several calls.
+	large := testPod("b")
+	large.Annotations = map[string]string{"large": strings.Repeat("x", 4096)}
+	pods := []*v1.Pod{testPod("a"), large}
+
+	var buf bytes.Buffer
+	w := Framer.NewFrameWriter(&buf)
+	for _, pod := range pods {
+		event := &metav1.WatchEvent{Type: "ADDED", Object: runtime.RawExtension{Object: pod}}
+		if err := s.Encode(event, w); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	decoder := streaming.NewDecoder(Framer.NewFrameReader(io.NopCloser(&buf)), s)
+	for _, expected := range pods {
+		var event metav1.WatchEvent
+		obj, gvk, err := decoder.Decode(nil, &event)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if obj != &event {
+			t.Fatalf("expected the event to be decoded into")
+		}
+		if e, a := (schema.GroupVersionKind{Group: metav1.GroupName, Version: "v1", Kind: "WatchEvent"}), *gvk; e != a {
+			t.Errorf("expected %v, got %v", e, a)
+		}
+		if e, a := "ADDED", event.Type; e != a {
+			t.Errorf("expected %q, got %q", e, a)
+		}
+		pod, err := runtime.Decode(s, event.Object.Raw)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(expected, pod) {
+			t.Errorf("expected %#v, got %#v", expected, pod)
+		}
+	}
+	if _, _, err := decoder.Decode(nil, &metav1.WatchEvent{}); err != io.EOF {
+		t.Errorf("expected %v, got %v", io.EOF, err)
+	}
+}
+
+func TestIsContentTypeCBOR(t *testing.T) {
+	for contentType, expected := range map[string]bool{
+		"application/cbor":               true,
+		"application/cbor; charset=utf8": true,
+		"application/json":               false,
+		"application/cbor-seq":           false,
+		"":                               false,
+	} {
+		if actual := IsContentTypeCBOR(contentType); actual != expected {
+			t.Errorf("%q: expected %t, got %t", contentType, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cbor
+
+import (
+	"io"
+
+	"github.com/fxamacker/cbor/v2"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Framer frames CBOR watch streams. CBOR data items are self-delimiting, so
+// the frames of a stream are simply its consecutive data items.
+var Framer = framer{}
+
+type framer struct{}
+
+// NewFrameReader implements runtime.Framer.
+func (framer) NewFrameReader(r io.ReadCloser) io.ReadCloser {
+	return &frameReader{
+		r:       r,
+		decoder: decMode.NewDecoder(r),
+	}
+}
+
+// NewFrameWriter implements runtime.Framer.
+func (framer) NewFrameWriter(w io.Writer) io.Writer {
+	return w
+}
+
+var _ runtime.Framer = Framer
+
+// frameReader reads the data items of a CBOR stream one at a time, and
+// returns io.ErrShortBuffer when a data item does not fit the buffer passed to
+// Read, like the JSON framer does.
+type frameReader struct {
+	r         io.ReadCloser
+	decoder   *cbor.Decoder
+	remaining []byte
+}
+
+func (r *frameReader) Read(data []byte) (int, error) {
+	if len(r.remaining) == 0 {
+		var item cbor.RawMessage
+		if err := r.decoder.Decode(&item); err != nil {
+			return 0, err
+		}
+		r.remaining = item
+	}
+	n := copy(data, r.remaining)
+	r.remaining = r.remaining[n:]
+	if len(r.remaining) > 0 {
+		return n, io.ErrShortBuffer
+	}
+	return n, nil
+}
+
+func (r *frameReader) Close() error {
+	return r.r.Close()
+}
@@ -0,0 +1,298 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flowcontrol
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/tools/metrics"
+	"k8s.io/utils/clock"
+)
+
+// AdaptiveRateLimiter is a RateLimiter which adapts its rate to the feedback
+// of the server about the requests it let through.
+type AdaptiveRateLimiter interface {
+	RateLimiter
+	// Observe reports the feedback of the server to a request. ctx carries the
+	// key of the request, see WithRequestKey.
+	Observe(ctx context.Context, feedback ServerFeedback)
+}
+
+// ServerFeedback is the feedback of the server to a request, as conveyed by
+// the API Priority and Fairness response headers and status codes.
+type ServerFeedback struct {
+	// FlowSchemaUID is the UID of the FlowSchema the request was classified
+	// into, if any.
+	FlowSchemaUID string
+	// PriorityLevelUID is the UID of the priority level the request was
+	// classified into, if any.
+	PriorityLevelUID string
+	// Throttled tells whether the server rejected the request with a 429.
+	Throttled bool
+	// RetryAfter is the time the server asked to wait before retrying, if any.
+	RetryAfter time.Duration
+}
+
+type requestKey struct{}
+
+// WithRequestKey returns a context carrying the key of a request, e.g. its verb
+// and URL template. Requests with the same key are expected to be classified
+// into the same priority level by the server, which lets an adaptive rate
+// limiter throttle a request at the rate of its priority level before it is
+// sent.
+func WithRequestKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, requestKey{}, key)
+}
+
+// RequestKeyFrom returns the key of a request carried by ctx, or the empty
+// string if there is none.
+func RequestKeyFrom(ctx context.Context) string {
+	key, _ := ctx.Value(requestKey{}).(string)
+	return key
+}
+
+// AdaptiveRateLimiterConfig specifies the configuration of an adaptive rate
+// limiter.
+type AdaptiveRateLimiterConfig struct {
+	// Name of the limiter. If unnamed, the metrics will not be reported.
+	Name string
+
+	// InitialQPS is the rate every priority level starts with. Defaults to 5.
+	InitialQPS float32
+
+	// MinQPS is the lowest rate of a priority level. Defaults to 1.
+	MinQPS float32
+
+	// MaxQPS is the highest rate of a priority level. Zero means the rate is
+	// only bounded by the feedback of the server.
+	MaxQPS float32
+
+	// Burst is the maximum burst of every priority level. Defaults to 10.
+	Burst int
+
+	// AdditiveIncrease is the increase of the rate of a priority level, in QPS,
+	// over every second of requests which are not throttled by the server at
+	// that rate. Defaults to 1.
+	AdditiveIncrease float32
+
+	// MultiplicativeDecrease is the factor the rate of a priority level is
+	// multiplied by when the server throttles one of its requests. It must be
+	// between 0 and 1, and defaults to 0.5.
+	MultiplicativeDecrease float32
+
+	// Clock optionally allows injecting a real or fake clock for testing purposes.
+	Clock clock.Clock
+}
+
+// adaptiveDecreaseInterval is the minimum time between two decreases of the
+// rate of a priority level, so that the requests rejected in a burst only
+// decrease it once.
+const adaptiveDecreaseInterval = time.Second
+
+// NewAdaptiveRateLimiter returns a rate limiter which adapts its rate to the
+// API Priority and Fairness feedback of the server, e.g. by setting it as
+// rest.Config.RateLimiter.
+//
+// The limiter keeps a token bucket per priority level of the server. Requests
+// are throttled by the bucket of the priority level the server classified the
+// last request with the same key into, or by the bucket of the unknown priority
+// level if there is none. The rate of each bucket is adjusted by additive
+// increase and multiplicative decrease: it grows while the requests of the
+// priority level are served, and shrinks whenever one is throttled by the
+// server. A priority level is not used at all until the time a throttled
+// request was asked to retry after has passed.
+func NewAdaptiveRateLimiter(config AdaptiveRateLimiterConfig) (AdaptiveRateLimiter, error) {
+	if config.InitialQPS == 0 {
+		config.InitialQPS = 5
+	}
+	if config.MinQPS == 0 {
+		config.MinQPS = 1
+	}
+	if config.Burst == 0 {
+		config.Burst = 10
+	}
+	if config.AdditiveIncrease == 0 {
+		config.AdditiveIncrease = 1
+	}
+	if config.MultiplicativeDecrease == 0 {
+		config.MultiplicativeDecrease = 0.5
+	}
+	if config.Clock == nil {
+		config.Clock = clock.RealClock{}
+	}
+	switch {
+	case config.MinQPS < 0 || config.InitialQPS < config.MinQPS:
+		return nil, fmt.Errorf("MinQPS must be positive and InitialQPS at least MinQPS, got %v and %v", config.MinQPS, config.InitialQPS)
+	case config.MaxQPS != 0 && config.MaxQPS < config.InitialQPS:
+		return nil, fmt.Errorf("MaxQPS must be at least InitialQPS, got %v and %v", config.MaxQPS, config.InitialQPS)
+	case config.Burst < 0 || config.AdditiveIncrease < 0:
+		return nil, fmt.Errorf("Burst and AdditiveIncrease must be positive, got %v and %v", config.Burst, config.AdditiveIncrease)
+	case config.MultiplicativeDecrease <= 0 || config.MultiplicativeDecrease >= 1:
+		return nil, fmt.Errorf("MultiplicativeDecrease must be between 0 and 1, got %v", config.MultiplicativeDecrease)
+	}
+
+	l := &adaptiveRateLimiter{
+		config: config,
+		levels: map[string]*priorityLevel{},
+		keys:   map[string]string{},
+	}
+	l.unknown = l.levelLocked("")
+	return l, nil
+}
+
+type adaptiveRateLimiter struct {
+	config AdaptiveRateLimiterConfig
+
+	lock sync.Mutex
+	// levels holds every priority level seen so far by UID, including the
+	// unknown priority level with the empty UID.
+	levels map[string]*priorityLevel
+	// keys holds the UID of the priority level of the last request with each key.
+	keys    map[string]string
+	unknown *priorityLevel
+}
+
+type priorityLevel struct {
+	uid     string
+	limiter *rate.Limiter
+	// blockedUntil is the time until which the priority level is not used,
+	// as asked by the server.
+	blockedUntil time.Time
+	lastDecrease time.Time
+}
+
+func (l *adaptiveRateLimiter) levelLocked(uid string) *priorityLevel {
+	level, exists := l.levels[uid]
+	if !exists {
+		level = &priorityLevel{
+			uid:     uid,
+			limiter: rate.NewLimiter(rate.Limit(l.config.InitialQPS), l.config.Burst),
+		}
+		l.levels[uid] = level
+		l.reportLocked(level)
+	}
+	return level
+}
+
+func (l *adaptiveRateLimiter) reportLocked(level *priorityLevel) {
+	if l.config.Name != "" {
+		metrics.RateLimiterQPS.Set(l.config.Name, level.uid, float64(level.limiter.Limit()))
+	}
+}
+
+// levelFor returns the priority level of the request with the key carried by
+// ctx, and the time until which it is blocked.
+func (l *adaptiveRateLimiter) levelFor(ctx context.Context) (*priorityLevel, time.Time) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	level := l.unknown
+	if uid, exists := l.keys[RequestKeyFrom(ctx)]; exists {
+		level = l.levels[uid]
+	}
+	return level, level.blockedUntil
+}
+
+func (l *adaptiveRateLimiter) TryAccept() bool {
+	level, blockedUntil := l.levelFor(context.Background())
+	now := l.config.Clock.Now()
+	return !now.Before(blockedUntil) && level.limiter.AllowN(now, 1)
+}
+
+func (l *adaptiveRateLimiter) Stop() {
+}
+
+// QPS returns the current rate of the unknown priority level.
+func (l *adaptiveRateLimiter) QPS() float32 {
+	return float32(l.unknown.limiter.Limit())
+}
+
+func (l *adaptiveRateLimiter) Accept() {
+	l.Wait(context.Background())
+}
+
+func (l *adaptiveRateLimiter) Wait(ctx context.Context) error {
+	level, blockedUntil := l.levelFor(ctx)
+	if err := l.sleep(ctx, blockedUntil.Sub(l.config.Clock.Now())); err != nil {
+		return err
+	}
+
+	now := l.config.Clock.Now()
+	reservation := level.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return fmt.Errorf("rate: Wait(n=1) exceeds limiter's burst %d", level.limiter.Burst())
+	}
+	if err := l.sleep(ctx, reservation.DelayFrom(now)); err != nil {
+		reservation.CancelAt(l.config.Clock.Now())
+		return err
+	}
+	return nil
+}
+
+func (l *adaptiveRateLimiter) sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(l.config.Clock.Now().Add(d)) {
+		return fmt.Errorf("rate: Wait(n=1) would exceed context deadline")
+	}
+	t := l.config.Clock.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C():
+		return nil
+	}
+}
+
+func (l *adaptiveRateLimiter) Observe(ctx context.Context, feedback ServerFeedback) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	level := l.levelLocked(feedback.PriorityLevelUID)
+	if key := RequestKeyFrom(ctx); key != "" && feedback.PriorityLevelUID != "" {
+		l.keys[key] = feedback.PriorityLevelUID
+	}
+
+	now := l.config.Clock.Now()
+	qps := float32(level.limiter.Limit())
+	if feedback.Throttled {
+		if blockedUntil := now.Add(feedback.RetryAfter); blockedUntil.After(level.blockedUntil) {
+			level.blockedUntil = blockedUntil
+		}
+		if now.Sub(level.lastDecrease) < adaptiveDecreaseInterval {
+			return
+		}
+		level.lastDecrease = now
+		qps *= l.config.MultiplicativeDecrease
+		if qps < l.config.MinQPS {
+			qps = l.config.MinQPS
+		}
+	} else {
+		// Every request served at the current rate takes 1/qps of a second.
+		qps += l.config.AdditiveIncrease / qps
+		if l.config.MaxQPS != 0 && qps > l.config.MaxQPS {
+			qps = l.config.MaxQPS
+		}
+	}
+	level.limiter.SetLimitAt(now, rate.Limit(qps))
+	l.reportLocked(level)
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flowcontrol
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/tools/metrics"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+type fakeRateLimiterQPSMetric map[string]float64
+
+func (m fakeRateLimiterQPSMetric) Set(name string, priorityLevel string, qps float64) {
+	m[name+"/"+priorityLevel] = qps
+}
+
+func TestAdaptiveRateLimiter(t *testing.T) {
+	metric := fakeRateLimiterQPSMetric{}
+	defer func(original metrics.RateLimiterQPSMetric) { metrics.RateLimiterQPS = original }(metrics.RateLimiterQPS)
+	metrics.RateLimiterQPS = metric
+
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	limiter, err := NewAdaptiveRateLimiter(AdaptiveRateLimiterConfig{
+		Name:       "test",
+		InitialQPS: 10,
+		MinQPS:     4,
+		MaxQPS:     11,
+		Clock:      fakeClock,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l := limiter.(*adaptiveRateLimiter)
+	qps := func(uid string) float64 {
+		l.lock.Lock()
+		defer l.lock.Unlock()
+		return float64(l.levels[uid].limiter.Limit())
+	}
+
+	pods := WithRequestKey(context.Background(), "GET /api/v1/namespaces/{namespace}/pods")
+	throttled := ServerFeedback{PriorityLevelUID: "workload-low", Throttled: true}
+	limiter.Observe(pods, throttled)
+	if e, a := 5.0, qps("workload-low"); e != a {
+		t.Errorf("expected %v QPS, got %v", e, a)
+	}
+	// Requests throttled in a burst only decrease the rate once.
+	limiter.Observe(pods, throttled)
+	if e, a := 5.0, qps("workload-low"); e != a {
+		t.Errorf("expected %v QPS, got %v", e, a)
+	}
+	fakeClock.Step(adaptiveDecreaseInterval)
+	limiter.Observe(pods, throttled)
+	if e, a := 4.0, qps("workload-low"); e != a {
+		t.Errorf("expected the rate to be capped at MinQPS %v, got %v", e, a)
+	}
+	limiter.Observe(pods, ServerFeedback{PriorityLevelUID: "workload-low"})
+	if e, a := 4.25, qps("workload-low"); e != a {
+		t.Errorf("expected %v QPS, got %v", e, a)
+	}
+
+	// The other priority levels are not affected.
+	for i := 0; i < 20; i++ {
+		limiter.Observe(context.Background(), ServerFeedback{})
+	}
+	if e, a := 11.0, qps(""); e != a {
+		t.Errorf("expected the rate to be capped at MaxQPS %v, got %v", e, a)
+	}
+	if e, a := float32(11), limiter.QPS(); e != a {
+		t.Errorf("expected %v QPS, got %v", e, a)
+	}
+	if e, a := 4.25, metric["test/workload-low"]; e != a {
+		t.Errorf("expected metric %v, got %v", e, a)
+	}
+	if e, a := 11.0, metric["test/"]; e != a {
+		t.Errorf("expected metric %v, got %v", e, a)
+	}
+}
+
+func TestAdaptiveRateLimiterRetryAfter(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	limiter, err := NewAdaptiveRateLimiter(AdaptiveRateLimiterConfig{Clock: fakeClock})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pods := WithRequestKey(context.Background(), "GET /api/v1/pods")
+	limiter.Observe(pods, ServerFeedback{PriorityLevelUID: "workload-low", Throttled: true, RetryAfter: 2 * time.Second})
+
+	// Requests of other priority levels are not blocked.
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- limiter.Wait(pods)
+	}()
+	for !fakeClock.HasWaiters() {
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("expected Wait to block until the retry after, got %v", err)
+	default:
+	}
+	fakeClock.Step(2 * time.Second)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(pods)
+	cancel()
+	limiter.Observe(pods, ServerFeedback{PriorityLevelUID: "workload-low", Throttled: true, RetryAfter: time.Second})
+	if err := limiter.Wait(ctx); err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestAdaptiveRateLimiterConfigValidation(t *testing.T) {
+	for _, config := range []AdaptiveRateLimiterConfig{
+		{InitialQPS: 1, MinQPS: 2},
+		{InitialQPS: 5, MaxQPS: 2},
+		{Burst: -1},
+		{MultiplicativeDecrease: 1.5},
+	} {
+		if _, err := NewAdaptiveRateLimiter(config); err == nil {
+			t.Errorf("expected an error for %+v", config)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workqueue
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// QueueDebugInfo is a point-in-time description of the state of a queue,
+// intended for debugging running processes.
+type QueueDebugInfo struct {
+	// Name is the name of the queue.
+	Name string `json:"name"`
+	// Depth is the number of items waiting to be processed.
+	Depth int `json:"depth"`
+	// Processing is the number of items currently being processed.
+	Processing int `json:"processing"`
+	// LongestRunningItem is the item that has been processed for the
+	// longest time, if any.
+	LongestRunningItem string `json:"longestRunningItem,omitempty"`
+	// LongestRunningDuration is the time for which LongestRunningItem has
+	// been processed.
+	LongestRunningDuration time.Duration `json:"longestRunningDuration,omitempty"`
+}
+
+// DebugInfo describes the current state of the queue.
+func (q *Typed[T]) DebugInfo() QueueDebugInfo {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+
+	info := QueueDebugInfo{
+		Name:       q.name,
+		Depth:      q.queue.len(),
+		Processing: q.processing.len(),
+	}
+	now := q.clock.Now()
+	for item, start := range q.processingStartTimes {
+		if d := now.Sub(start); info.LongestRunningItem == "" || d > info.LongestRunningDuration {
+			info.LongestRunningItem = fmt.Sprintf("%v", item)
+			info.LongestRunningDuration = d
+		}
+	}
+	return info
+}
+
+// debugQueue is implemented by Typed queues of every item type.
+type debugQueue interface {
+	DebugInfo() QueueDebugInfo
+}
+
+// debugQueuesEnabled is set by EnableNamedQueueDebugInfo.
+var debugQueuesEnabled atomic.Bool
+
+// EnableNamedQueueDebugInfo makes DebugInfoForNamedQueues describe the named
+// queues which are created after it is called, until they are shut down.
+//
+// It is disabled by default because every such queue is referenced until it
+// is shut down: a process which creates named queues without shutting them
+// down should not enable it.
+func EnableNamedQueueDebugInfo() {
+	debugQueuesEnabled.Store(true)
+}
+
+// debugQueues holds the named queues which were created while debug info was
+// enabled and have not been shut down.
+var debugQueues = struct {
+	lock   sync.Mutex
+	queues map[debugQueue]struct{}
+}{
+	queues: map[debugQueue]struct{}{},
+}
+
+func registerDebugQueue(q debugQueue) {
+	if !debugQueuesEnabled.Load() {
+		return
+	}
+	debugQueues.lock.Lock()
+	defer debugQueues.lock.Unlock()
+	debugQueues.queues[q] = struct{}{}
+}
+
+func unregisterDebugQueue(q debugQueue) {
+	debugQueues.lock.Lock()
+	defer debugQueues.lock.Unlock()
+	delete(debugQueues.queues, q)
+}
+
+// DebugInfoForNamedQueues describes the current state of every named queue
+// in the process that was created after EnableNamedQueueDebugInfo was called
+// and has not been shut down, sorted by name.
+func DebugInfoForNamedQueues() []QueueDebugInfo {
+	debugQueues.lock.Lock()
+	queues := make([]debugQueue, 0, len(debugQueues.queues))
+	for q := range debugQueues.queues {
+		queues = append(queues, q)
+	}
+	debugQueues.lock.Unlock()
+
+	infos := make([]QueueDebugInfo, 0, len(queues))
+	for _, q := range queues {
+		infos = append(infos, q.DebugInfo())
+	}
+	sort.SliceStable(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workqueue
+
+import (
+	"testing"
+	"time"
+
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestDebugInfo(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	disabled := NewWithConfig(QueueConfig{Name: "debug-disabled"})
+	defer disabled.ShutDown()
+	EnableNamedQueueDebugInfo()
+	defer debugQueuesEnabled.Store(false)
+	q := NewWithConfig(QueueConfig{Name: "debug-test", Clock: fakeClock})
+	unnamed := New()
+	defer unnamed.ShutDown()
+
+	q.Add("a")
+	q.Add("b")
+	q.Add("c")
+	a, _ := q.Get()
+	fakeClock.Step(time.Second)
+	b, _ := q.Get()
+	fakeClock.Step(time.Second)
+
+	expected := QueueDebugInfo{
+		Name:                   "debug-test",
+		Depth:                  1,
+		Processing:             2,
+		LongestRunningItem:     "a",
+		LongestRunningDuration: 2 * time.Second,
+	}
+	if info := q.DebugInfo(); info != expected {
+		t.Errorf("expected %+v, got %+v", expected, info)
+	}
+
+	q.Done(a)
+	info := q.DebugInfo()
+	if info.LongestRunningItem != "b" || info.LongestRunningDuration != time.Second || info.Processing != 1 {
+		t.Errorf("unexpected debug info after Done: %+v", info)
+	}
+	q.Done(b)
+
+	found := false
+	for _, info := range DebugInfoForNamedQueues() {
+		if info.Name == "" {
+			t.Errorf("unnamed queues must not be listed")
+		}
+		if info.Name == "debug-disabled" {
+			t.Errorf("queues created before debug info was enabled must not be listed")
+		}
+		if info.Name == "debug-test" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected the named queue to be listed")
+	}
+
+	q.ShutDown()
+	for _, info := range DebugInfoForNamedQueues() {
+		if info.Name == "debug-test" {
+			t.Errorf("expected the queue to be unlisted after shutdown")
+		}
+	}
+}
@@ -19,6 +19,8 @@ package workqueue
 import (
 	"testing"
 	"time"
+
+	testingclock "k8s.io/utils/clock/testing"
 )
 
 func TestItemExponentialFailureRateLimiter(t *testing.T) {
@@ -229,3 +231,88 @@ func (r *StepRateLimiter) NumRequeues(item interface{}) int {
 
 func (r *StepRateLimiter) Forget(item interface{}) {
 }
+
+func TestItemRateLimiterUnbounded(t *testing.T) {
+	limiter := NewTypedItemExponentialFailureRateLimiter[string](1*time.Millisecond, 1*time.Second).(*TypedItemExponentialFailureRateLimiter[string])
+	limiter.When("one")
+	limiter.When("one")
+	limiter.When("two")
+	// Without IdleTTL or MaxItems, the failures are counted in a plain map.
+	if limiter.failures.lru != nil || limiter.failures.entries != nil {
+		t.Errorf("expected no least recently used list to be maintained")
+	}
+	if e, a := 2, limiter.NumRequeues("one"); e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	limiter.Forget("one")
+	if e, a := 1, limiter.failures.len(); e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}
+
+func TestItemRateLimiterIdleTTL(t *testing.T) {
+	fakeClock := testingclock.NewFakePassiveClock(time.Now())
+	limiter := NewItemExponentialFailureRateLimiterWithOptions(1*time.Millisecond, 1*time.Second, ItemRateLimiterOptions{
+		IdleTTL: time.Minute,
+		Clock:   fakeClock,
+	})
+
+	limiter.When("one")
+	limiter.When("one")
+	fakeClock.SetTime(fakeClock.Now().Add(30 * time.Second))
+	limiter.When("two")
+	if e, a := 2, limiter.NumRequeues("one"); e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+
+	fakeClock.SetTime(fakeClock.Now().Add(30 * time.Second))
+	if e, a := 0, limiter.NumRequeues("one"); e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if e, a := 1, limiter.NumRequeues("two"); e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if e, a := 1*time.Millisecond, limiter.When("one"); e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if e, a := 2, limiter.(*ItemExponentialFailureRateLimiter).failures.len(); e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}
+
+func TestItemRateLimiterMaxItems(t *testing.T) {
+	limiter := NewItemFastSlowRateLimiterWithOptions(5*time.Millisecond, 10*time.Second, 1, ItemRateLimiterOptions{
+		MaxItems: 2,
+	})
+
+	limiter.When("one")
+	limiter.When("two")
+	limiter.When("one")
+	limiter.When("three")
+	if e, a := 2, limiter.(*ItemFastSlowRateLimiter).failures.len(); e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	// two failed least recently.
+	for item, e := range map[string]int{"one": 2, "two": 0, "three": 1} {
+		if a := limiter.NumRequeues(item); e != a {
+			t.Errorf("expected %v for %s, got %v", e, item, a)
+		}
+	}
+	if e, a := 5*time.Millisecond, limiter.When("two"); e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}
+
+func TestItemRateLimiterJitter(t *testing.T) {
+	limiter := NewTypedItemExponentialFailureRateLimiterWithOptions[string](1*time.Second, 1*time.Minute, ItemRateLimiterOptions{
+		Jitter: 0.5,
+	})
+
+	for i := 0; i < 10; i++ {
+		delay := limiter.When("one")
+		limiter.Forget("one")
+		if delay < 1*time.Second || delay > 1500*time.Millisecond {
+			t.Errorf("expected a delay between 1s and 1.5s, got %v", delay)
+		}
+	}
+}
@@ -27,14 +27,21 @@ import (
 
 // DelayingInterface is an Interface that can Add an item at a later time. This makes it easier to
 // requeue items after failures without ending up in a hot-loop.
-type DelayingInterface interface {
-	Interface
+type DelayingInterface = TypedDelayingInterface[any]
+
+// TypedDelayingInterface is a TypedInterface that can Add an item at a later time. This makes it easier to
+// requeue items after failures without ending up in a hot-loop.
+type TypedDelayingInterface[T comparable] interface {
+	TypedInterface[T]
 	// AddAfter adds an item to the workqueue after the indicated duration has passed
-	AddAfter(item interface{}, duration time.Duration)
+	AddAfter(item T, duration time.Duration)
 }
 
 // DelayingQueueConfig specifies optional configurations to customize a DelayingInterface.
-type DelayingQueueConfig struct {
+type DelayingQueueConfig = TypedDelayingQueueConfig[any]
+
+// TypedDelayingQueueConfig specifies optional configurations to customize a TypedDelayingInterface.
+type TypedDelayingQueueConfig[T comparable] struct {
 	// Name for the queue. If unnamed, the metrics will not be registered.
 	Name string
 
@@ -46,7 +53,15 @@ type DelayingQueueConfig struct {
 	Clock clock.WithTicker
 
 	// Queue optionally allows injecting custom queue Interface instead of the default one.
-	Queue Interface
+	Queue TypedInterface[T]
+
+	// Hooks optionally allows observing the lifecycle of the items of the default queue.
+	// It is ignored if Queue is set.
+	Hooks TypedItemHooks[T]
+
+	// EnableIntrospection makes the default queue record the times reported
+	// by Inspect, see TypedQueueConfig. It is ignored if Queue is set.
+	EnableIntrospection bool
 }
 
 // NewDelayingQueue constructs a new workqueue with delayed queuing ability.
@@ -56,18 +71,34 @@ func NewDelayingQueue() DelayingInterface {
 	return NewDelayingQueueWithConfig(DelayingQueueConfig{})
 }
 
+// NewTypedDelayingQueue constructs a new workqueue of items of type T with
+// delayed queuing ability.
+// NewTypedDelayingQueue does not emit metrics. For use with a MetricsProvider, please use
+// NewTypedDelayingQueueWithConfig instead and specify a name.
+func NewTypedDelayingQueue[T comparable]() TypedDelayingInterface[T] {
+	return NewTypedDelayingQueueWithConfig(TypedDelayingQueueConfig[T]{})
+}
+
 // NewDelayingQueueWithConfig constructs a new workqueue with options to
 // customize different properties.
 func NewDelayingQueueWithConfig(config DelayingQueueConfig) DelayingInterface {
+	return NewTypedDelayingQueueWithConfig(config)
+}
+
+// NewTypedDelayingQueueWithConfig constructs a new workqueue of items of type
+// T with options to customize different properties.
+func NewTypedDelayingQueueWithConfig[T comparable](config TypedDelayingQueueConfig[T]) TypedDelayingInterface[T] {
 	if config.Clock == nil {
 		config.Clock = clock.RealClock{}
 	}
 
 	if config.Queue == nil {
-		config.Queue = NewWithConfig(QueueConfig{
-			Name:            config.Name,
-			MetricsProvider: config.MetricsProvider,
-			Clock:           config.Clock,
+		config.Queue = NewTypedWithConfig(TypedQueueConfig[T]{
+			Name:                config.Name,
+			MetricsProvider:     config.MetricsProvider,
+			Clock:               config.Clock,
+			Hooks:               config.Hooks,
+			EnableIntrospection: config.EnableIntrospection,
 		})
 	}
 
@@ -100,13 +131,14 @@ func NewDelayingQueueWithCustomClock(clock clock.WithTicker, name string) Delayi
 	})
 }
 
-func newDelayingQueue(clock clock.WithTicker, q Interface, name string, provider MetricsProvider) *delayingType {
-	ret := &delayingType{
-		Interface:       q,
+func newDelayingQueue[T comparable](clock clock.WithTicker, q TypedInterface[T], name string, provider MetricsProvider) *delayingType[T] {
+	ret := &delayingType[T]{
+		TypedInterface:  q,
 		clock:           clock,
 		heartbeat:       clock.NewTicker(maxWait),
 		stopCh:          make(chan struct{}),
-		waitingForAddCh: make(chan *waitFor, 1000),
+		waitingForAddCh: make(chan *waitFor[T], 1000),
+		waiting:         map[T]*waitFor[T]{},
 		metrics:         newRetryMetrics(name, provider),
 	}
 
@@ -114,9 +146,9 @@ func newDelayingQueue(clock clock.WithTicker, q Interface, name string, provider
 	return ret
 }
 
-// delayingType wraps an Interface and provides delayed re-enquing
-type delayingType struct {
-	Interface
+// delayingType wraps a TypedInterface and provides delayed re-enquing
+type delayingType[T comparable] struct {
+	TypedInterface[T]
 
 	// clock tracks time for delayed firing
 	clock clock.Clock
@@ -130,16 +162,23 @@ type delayingType struct {
 	heartbeat clock.Ticker
 
 	// waitingForAddCh is a buffered channel that feeds waitingForAdd
-	waitingForAddCh chan *waitFor
+	waitingForAddCh chan *waitFor[T]
+
+	// waitingLock guards waiting and the readyAt of its entries, which the
+	// waiting loop shares with Inspect.
+	waitingLock sync.Mutex
+	// waiting holds the entry of every item in the waiting loop's priority queue
+	waiting map[T]*waitFor[T]
 
 	// metrics counts the number of retries
 	metrics retryMetrics
 }
 
 // waitFor holds the data to add and the time it should be added
-type waitFor struct {
-	data    t
-	readyAt time.Time
+type waitFor[T comparable] struct {
+	data     T
+	priority int
+	readyAt  time.Time
 	// index in the priority queue (heap)
 	index int
 }
@@ -152,15 +191,15 @@ type waitFor struct {
 // it has been removed from the queue and placed at index Len()-1 by
 // container/heap. Push adds an item at index Len(), and container/heap
 // percolates it into the correct location.
-type waitForPriorityQueue []*waitFor
+type waitForPriorityQueue[T comparable] []*waitFor[T]
 
-func (pq waitForPriorityQueue) Len() int {
+func (pq waitForPriorityQueue[T]) Len() int {
 	return len(pq)
 }
-func (pq waitForPriorityQueue) Less(i, j int) bool {
+func (pq waitForPriorityQueue[T]) Less(i, j int) bool {
 	return pq[i].readyAt.Before(pq[j].readyAt)
 }
-func (pq waitForPriorityQueue) Swap(i, j int) {
+func (pq waitForPriorityQueue[T]) Swap(i, j int) {
 	pq[i], pq[j] = pq[j], pq[i]
 	pq[i].index = i
 	pq[j].index = j
@@ -168,16 +207,16 @@ func (pq waitForPriorityQueue) Swap(i, j int) {
 
 // Push adds an item to the queue. Push should not be called directly; instead,
 // use `heap.Push`.
-func (pq *waitForPriorityQueue) Push(x interface{}) {
+func (pq *waitForPriorityQueue[T]) Push(x interface{}) {
 	n := len(*pq)
-	item := x.(*waitFor)
+	item := x.(*waitFor[T])
 	item.index = n
 	*pq = append(*pq, item)
 }
 
 // Pop removes an item from the queue. Pop should not be called directly;
 // instead, use `heap.Pop`.
-func (pq *waitForPriorityQueue) Pop() interface{} {
+func (pq *waitForPriorityQueue[T]) Pop() interface{} {
 	n := len(*pq)
 	item := (*pq)[n-1]
 	item.index = -1
@@ -187,22 +226,29 @@ func (pq *waitForPriorityQueue) Pop() interface{} {
 
 // Peek returns the item at the beginning of the queue, without removing the
 // item or otherwise mutating the queue. It is safe to call directly.
-func (pq waitForPriorityQueue) Peek() interface{} {
+func (pq waitForPriorityQueue[T]) Peek() interface{} {
 	return pq[0]
 }
 
 // ShutDown stops the queue. After the queue drains, the returned shutdown bool
 // on Get() will be true. This method may be invoked more than once.
-func (q *delayingType) ShutDown() {
+func (q *delayingType[T]) ShutDown() {
 	q.stopOnce.Do(func() {
-		q.Interface.ShutDown()
+		q.TypedInterface.ShutDown()
 		close(q.stopCh)
 		q.heartbeat.Stop()
 	})
 }
 
 // AddAfter adds the given item to the work queue after the given delay
-func (q *delayingType) AddAfter(item interface{}, duration time.Duration) {
+func (q *delayingType[T]) AddAfter(item T, duration time.Duration) {
+	q.AddAfterWithPriority(item, 0, duration)
+}
+
+// AddAfterWithPriority adds the given item to the work queue with the given
+// priority after the given delay. The priority is ignored unless the wrapped
+// queue is a TypedPriorityInterface.
+func (q *delayingType[T]) AddAfterWithPriority(item T, priority int, duration time.Duration) {
 	// don't add if we're already shutting down
 	if q.ShuttingDown() {
 		return
@@ -212,15 +258,26 @@ func (q *delayingType) AddAfter(item interface{}, duration time.Duration) {
 
 	// immediately add things with no delay
 	if duration <= 0 {
-		q.Add(item)
+		q.AddWithPriority(item, priority)
 		return
 	}
 
 	select {
 	case <-q.stopCh:
 		// unblock if ShutDown() is called
-	case q.waitingForAddCh <- &waitFor{data: item, readyAt: q.clock.Now().Add(duration)}:
+	case q.waitingForAddCh <- &waitFor[T]{data: item, priority: priority, readyAt: q.clock.Now().Add(duration)}:
+	}
+}
+
+// AddWithPriority adds the given item to the work queue with the given
+// priority. The priority is ignored unless the wrapped queue is a
+// TypedPriorityInterface.
+func (q *delayingType[T]) AddWithPriority(item T, priority int) {
+	if pq, ok := q.TypedInterface.(TypedPriorityInterface[T]); ok {
+		pq.AddWithPriority(item, priority)
+		return
 	}
+	q.Add(item)
 }
 
 // maxWait keeps a max bound on the wait time. It's just insurance against weird things happening.
@@ -229,7 +286,7 @@ func (q *delayingType) AddAfter(item interface{}, duration time.Duration) {
 const maxWait = 10 * time.Second
 
 // waitingLoop runs until the workqueue is shutdown and keeps a check on the list of items to be added.
-func (q *delayingType) waitingLoop() {
+func (q *delayingType[T]) waitingLoop() {
 	defer utilruntime.HandleCrash()
 
 	// Make a placeholder channel to use when there are no items in our list
@@ -238,13 +295,11 @@ func (q *delayingType) waitingLoop() {
 	// Make a timer that expires when the item at the head of the waiting queue is ready
 	var nextReadyAtTimer clock.Timer
 
-	waitingForQueue := &waitForPriorityQueue{}
+	waitingForQueue := &waitForPriorityQueue[T]{}
 	heap.Init(waitingForQueue)
 
-	waitingEntryByData := map[t]*waitFor{}
-
 	for {
-		if q.Interface.ShuttingDown() {
+		if q.TypedInterface.ShuttingDown() {
 			return
 		}
 
@@ -252,14 +307,16 @@ func (q *delayingType) waitingLoop() {
 
 		// Add ready entries
 		for waitingForQueue.Len() > 0 {
-			entry := waitingForQueue.Peek().(*waitFor)
+			entry := waitingForQueue.Peek().(*waitFor[T])
 			if entry.readyAt.After(now) {
 				break
 			}
 
-			entry = heap.Pop(waitingForQueue).(*waitFor)
-			q.Add(entry.data)
-			delete(waitingEntryByData, entry.data)
+			q.waitingLock.Lock()
+			entry = heap.Pop(waitingForQueue).(*waitFor[T])
+			q.AddWithPriority(entry.data, entry.priority)
+			delete(q.waiting, entry.data)
+			q.waitingLock.Unlock()
 		}
 
 		// Set up a wait for the first item's readyAt (if one exists)
@@ -268,7 +325,7 @@ func (q *delayingType) waitingLoop() {
 			if nextReadyAtTimer != nil {
 				nextReadyAtTimer.Stop()
 			}
-			entry := waitingForQueue.Peek().(*waitFor)
+			entry := waitingForQueue.Peek().(*waitFor[T])
 			nextReadyAtTimer = q.clock.NewTimer(entry.readyAt.Sub(now))
 			nextReadyAt = nextReadyAtTimer.C()
 		}
@@ -285,9 +342,9 @@ func (q *delayingType) waitingLoop() {
 
 		case waitEntry := <-q.waitingForAddCh:
 			if waitEntry.readyAt.After(q.clock.Now()) {
-				insert(waitingForQueue, waitingEntryByData, waitEntry)
+				q.insert(waitingForQueue, waitEntry)
 			} else {
-				q.Add(waitEntry.data)
+				q.AddWithPriority(waitEntry.data, waitEntry.priority)
 			}
 
 			drained := false
@@ -295,9 +352,9 @@ func (q *delayingType) waitingLoop() {
 				select {
 				case waitEntry := <-q.waitingForAddCh:
 					if waitEntry.readyAt.After(q.clock.Now()) {
-						insert(waitingForQueue, waitingEntryByData, waitEntry)
+						q.insert(waitingForQueue, waitEntry)
 					} else {
-						q.Add(waitEntry.data)
+						q.AddWithPriority(waitEntry.data, waitEntry.priority)
 					}
 				default:
 					drained = true
@@ -307,11 +364,21 @@ func (q *delayingType) waitingLoop() {
 	}
 }
 
-// insert adds the entry to the priority queue, or updates the readyAt if it already exists in the queue
-func insert(q *waitForPriorityQueue, knownEntries map[t]*waitFor, entry *waitFor) {
+// insert adds the entry to the priority queue and to waiting, see insert.
+func (q *delayingType[T]) insert(waitingForQueue *waitForPriorityQueue[T], entry *waitFor[T]) {
+	q.waitingLock.Lock()
+	defer q.waitingLock.Unlock()
+	insert(waitingForQueue, q.waiting, entry)
+}
+
+// insert adds the entry to the priority queue, or updates the readyAt and priority if it already exists in the queue
+func insert[T comparable](q *waitForPriorityQueue[T], knownEntries map[T]*waitFor[T], entry *waitFor[T]) {
 	// if the entry already exists, update the time only if it would cause the item to be queued sooner
 	existing, exists := knownEntries[entry.data]
 	if exists {
+		if existing.priority < entry.priority {
+			existing.priority = entry.priority
+		}
 		if existing.readyAt.After(entry.readyAt) {
 			existing.readyAt = entry.readyAt
 			heap.Fix(q, existing.index)
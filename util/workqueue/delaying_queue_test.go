@@ -229,7 +229,7 @@ func BenchmarkDelayingQueue_AddAfter(b *testing.B) {
 	}
 }
 
-func waitForAdded(q DelayingInterface, depth int) error {
+func waitForAdded[T comparable](q TypedDelayingInterface[T], depth int) error {
 	return wait.Poll(1*time.Millisecond, 10*time.Second, func() (done bool, err error) {
 		if q.Len() == depth {
 			return true, nil
@@ -239,9 +239,9 @@ func waitForAdded(q DelayingInterface, depth int) error {
 	})
 }
 
-func waitForWaitingQueueToFill(q DelayingInterface) error {
+func waitForWaitingQueueToFill[T comparable](q TypedDelayingInterface[T]) error {
 	return wait.Poll(1*time.Millisecond, 10*time.Second, func() (done bool, err error) {
-		if len(q.(*delayingType).waitingForAddCh) == 0 {
+		if len(q.(*delayingType[T]).waitingForAddCh) == 0 {
 			return true, nil
 		}
 
@@ -0,0 +1,202 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workqueue
+
+import (
+	"k8s.io/utils/clock"
+)
+
+// FairQueueConfig specifies the configuration of a fair queue.
+type FairQueueConfig = TypedFairQueueConfig[any]
+
+// TypedFairQueueConfig specifies the configuration of a fair queue of items of
+// type T.
+type TypedFairQueueConfig[T comparable] struct {
+	// Name for the queue. If unnamed, the metrics will not be registered.
+	Name string
+
+	// MetricsProvider optionally allows specifying a metrics provider to use for the queue
+	// instead of the global provider. The depth of each flow is only reported if
+	// the provider is a FlowMetricsProvider.
+	MetricsProvider MetricsProvider
+
+	// Clock optionally allows injecting a real or fake clock for testing purposes.
+	Clock clock.WithTicker
+
+	// FlowKey returns the flow of an item, e.g. its namespace. If unset, all
+	// items belong to the same flow.
+	FlowKey func(item T) string
+
+	// FlowWeight optionally returns the weight of a flow, i.e. the number of
+	// its items handed out in a row before moving on to the next flow.
+	// Weights below 1 count as 1. If unset, every flow has weight 1.
+	FlowWeight func(flow string) int
+
+	// Hooks optionally allows observing the lifecycle of the items of the queue.
+	Hooks TypedItemHooks[T]
+
+	// EnableIntrospection makes the queue record the times reported by
+	// Inspect, see TypedQueueConfig.
+	EnableIntrospection bool
+}
+
+// FlowMetricsProvider is a MetricsProvider which also generates the metrics
+// of the flows of fair queues. A flow only has a depth metric while it has
+// queued items, so the number of metrics stays bounded by the number of flows
+// with queued items rather than growing with every flow ever seen.
+type FlowMetricsProvider interface {
+	MetricsProvider
+	// NewFlowDepthMetric returns the metric of the number of queued items
+	// of the given flow of the given queue.
+	NewFlowDepthMetric(name, flow string) GaugeMetric
+	// DeleteFlowDepthMetric deletes the metric returned by
+	// NewFlowDepthMetric, once the flow has no queued items anymore.
+	DeleteFlowDepthMetric(name, flow string)
+}
+
+// NewFairQueueWithConfig constructs a new work queue which is fair across
+// flows, see NewTypedFairQueueWithConfig.
+func NewFairQueueWithConfig(config FairQueueConfig) *Type {
+	return NewTypedFairQueueWithConfig(config)
+}
+
+// NewTypedFairQueueWithConfig constructs a new work queue of items of type T
+// which is fair across flows. Every item belongs to the flow returned by
+// config.FlowKey, and Get serves the flows which have queued items in turn,
+// each flow handing out up to its weight of items in the order they were
+// queued. This keeps a flow with many items, like a namespace with many
+// objects, from starving the others.
+//
+// Apart from the order of the items, the queue behaves like any other Typed
+// queue, and can be wrapped in the delaying and rate limiting queues by means
+// of TypedDelayingQueueConfig.Queue.
+func NewTypedFairQueueWithConfig[T comparable](config TypedFairQueueConfig[T]) *Typed[T] {
+	provider := config.MetricsProvider
+	if provider == nil {
+		provider = globalMetricsFactory.metricsProvider
+	}
+	var flowMetrics FlowMetricsProvider
+	if fp, ok := provider.(FlowMetricsProvider); ok && config.Name != "" {
+		flowMetrics = fp
+	}
+
+	return newQueueWithConfig[T](TypedQueueConfig[T]{
+		Name:                config.Name,
+		MetricsProvider:     config.MetricsProvider,
+		Clock:               config.Clock,
+		Hooks:               config.Hooks,
+		EnableIntrospection: config.EnableIntrospection,
+	}, newFairOrder(config.Name, config.FlowKey, config.FlowWeight, flowMetrics), defaultUnfinishedWorkUpdatePeriod)
+}
+
+// fairOrder is the order of a Typed queue which serves its flows round-robin.
+type fairOrder[T comparable] struct {
+	name        string
+	flowKey     func(item T) string
+	flowWeight  func(flow string) int
+	flowMetrics FlowMetricsProvider
+
+	// flows holds every flow with queued items.
+	flows map[string]*flow[T]
+	// active holds the flows in the order they are served, starting with
+	// the flow being served.
+	active []*flow[T]
+	// served is the number of items handed out by active[0] in its turn.
+	served int
+	// n is the number of queued items of all flows.
+	n int
+}
+
+type flow[T comparable] struct {
+	key    string
+	weight int
+	items  fifo[T]
+	depth  GaugeMetric
+}
+
+func newFairOrder[T comparable](name string, flowKey func(item T) string, flowWeight func(flow string) int, flowMetrics FlowMetricsProvider) *fairOrder[T] {
+	if flowKey == nil {
+		flowKey = func(T) string { return "" }
+	}
+	return &fairOrder[T]{
+		name:        name,
+		flowKey:     flowKey,
+		flowWeight:  flowWeight,
+		flowMetrics: flowMetrics,
+		flows:       map[string]*flow[T]{},
+	}
+}
+
+func (o *fairOrder[T]) touch(item T, priority int) {}
+
+func (o *fairOrder[T]) push(item T) {
+	key := o.flowKey(item)
+	f, exists := o.flows[key]
+	if !exists {
+		f = &flow[T]{
+			key:    key,
+			weight: 1,
+			depth:  o.depthMetric(key),
+		}
+		// The weight is looked up again whenever a flow becomes active.
+		if o.flowWeight != nil {
+			if weight := o.flowWeight(key); weight > 1 {
+				f.weight = weight
+			}
+		}
+		o.flows[key] = f
+		o.active = append(o.active, f)
+	}
+	f.items.push(item)
+	f.depth.Inc()
+	o.n++
+}
+
+func (o *fairOrder[T]) pop() T {
+	f := o.active[0]
+	item := f.items.pop()
+	f.depth.Dec()
+	o.n--
+	o.served++
+
+	switch {
+	case f.items.len() == 0:
+		delete(o.flows, f.key)
+		if o.flowMetrics != nil {
+			o.flowMetrics.DeleteFlowDepthMetric(o.name, f.key)
+		}
+		o.active[0] = nil
+		o.active = o.active[1:]
+		o.served = 0
+	case o.served >= f.weight:
+		o.active[0] = nil
+		o.active = append(o.active[1:], f)
+		o.served = 0
+	}
+	return item
+}
+
+func (o *fairOrder[T]) len() int {
+	return o.n
+}
+
+func (o *fairOrder[T]) depthMetric(key string) GaugeMetric {
+	if o.flowMetrics == nil {
+		return noopMetric{}
+	}
+	return o.flowMetrics.NewFlowDepthMetric(o.name, key)
+}
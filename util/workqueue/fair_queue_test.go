@@ -0,0 +1,148 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workqueue
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func namespaceOf(key string) string {
+	return strings.Split(key, "/")[0]
+}
+
+type testFlowMetricsProvider struct {
+	testMetricsProvider
+	flows   map[string]*testMetric
+	deleted []string
+}
+
+func (m *testFlowMetricsProvider) NewFlowDepthMetric(name, flow string) GaugeMetric {
+	metric := &testMetric{}
+	m.flows[flow] = metric
+	return metric
+}
+
+func (m *testFlowMetricsProvider) DeleteFlowDepthMetric(name, flow string) {
+	if m.flows[flow].gaugeValue() != 0 {
+		panic("deleted the depth metric of a flow with queued items")
+	}
+	delete(m.flows, flow)
+	m.deleted = append(m.deleted, flow)
+}
+
+func TestFairQueue(t *testing.T) {
+	mp := &testFlowMetricsProvider{flows: map[string]*testMetric{}}
+	q := NewTypedFairQueueWithConfig(TypedFairQueueConfig[string]{
+		Name:            "test",
+		MetricsProvider: mp,
+		FlowKey:         namespaceOf,
+	})
+	defer q.ShutDown()
+
+	for _, item := range []string{"a/1", "a/2", "a/3", "a/4", "b/1", "c/1", "b/2", "a/1"} {
+		q.Add(item)
+	}
+	if e, a := 7, q.Len(); e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	for flow, depth := range map[string]float64{"a": 4, "b": 2, "c": 1} {
+		if e, a := depth, mp.flows[flow].gaugeValue(); e != a {
+			t.Errorf("expected depth %v of flow %s, got %v", e, flow, a)
+		}
+	}
+
+	var items []string
+	for i := 0; i < 3; i++ {
+		item, _ := q.Get()
+		items = append(items, item)
+	}
+	// Re-adding an item being processed queues it again once it is done,
+	// behind the items of its flow.
+	q.Add("a/1")
+	q.Done("a/1")
+	items = append(items, getAll[string](q)...)
+
+	expected := []string{"a/1", "b/1", "c/1", "a/2", "b/2", "a/3", "a/4", "a/1"}
+	if !reflect.DeepEqual(expected, items) {
+		t.Errorf("expected %v, got %v", expected, items)
+	}
+	// The depth metric of a flow is deleted whenever it runs out of items.
+	if len(mp.flows) != 0 {
+		t.Errorf("expected the depth metrics of the empty flows to be deleted, got %v", mp.flows)
+	}
+	if e, a := []string{"c", "b", "a"}, mp.deleted; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected the depth metrics of flows %v to be deleted, got %v", e, a)
+	}
+}
+
+func TestFairQueueWeights(t *testing.T) {
+	q := NewTypedFairQueueWithConfig(TypedFairQueueConfig[string]{
+		FlowKey: namespaceOf,
+		FlowWeight: func(flow string) int {
+			if flow == "a" {
+				return 3
+			}
+			return 0
+		},
+	})
+	defer q.ShutDown()
+
+	for _, item := range []string{"a/1", "a/2", "a/3", "a/4", "a/5", "b/1", "b/2"} {
+		q.Add(item)
+	}
+	expected := []string{"a/1", "a/2", "a/3", "b/1", "a/4", "a/5", "b/2"}
+	if items := getAll[string](q); !reflect.DeepEqual(expected, items) {
+		t.Errorf("expected %v, got %v", expected, items)
+	}
+}
+
+func TestFairQueueRateLimited(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	delayingQueue := NewTypedDelayingQueueWithConfig(TypedDelayingQueueConfig[string]{
+		Clock: fakeClock,
+		Queue: NewTypedFairQueueWithConfig(TypedFairQueueConfig[string]{
+			Clock:   fakeClock,
+			FlowKey: namespaceOf,
+		}),
+	})
+	q := NewTypedRateLimitingQueueWithConfig(
+		NewTypedItemExponentialFailureRateLimiter[string](1*time.Millisecond, 1*time.Second),
+		TypedRateLimitingQueueConfig[string]{DelayingQueue: delayingQueue},
+	)
+	defer q.ShutDown()
+
+	q.Add("a/1")
+	q.Add("a/2")
+	q.AddRateLimited("b/1")
+	if err := waitForWaitingQueueToFill(delayingQueue); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	fakeClock.Step(1 * time.Millisecond)
+	if err := waitForAdded(delayingQueue, 3); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	expected := []string{"a/1", "b/1", "a/2"}
+	if items := getAll[string](q); !reflect.DeepEqual(expected, items) {
+		t.Errorf("expected %v, got %v", expected, items)
+	}
+}
@@ -0,0 +1,209 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workqueue
+
+import (
+	"time"
+)
+
+// TypedItemHooks are called on the lifecycle events of the items of a queue,
+// e.g. to trace an item from the event that added it to its Done. They are
+// called with the lock of the queue held, so they must be fast and must not
+// call the queue. Every hook is optional.
+type TypedItemHooks[T comparable] struct {
+	// OnAdd is called when item is added to the queue while not already
+	// queued. Adding an item which is already queued is a no-op.
+	OnAdd func(item T)
+
+	// OnGet is called when item is handed out by Get.
+	OnGet func(item T)
+
+	// OnDone is called when Done is called for item. requeued is true if
+	// the item was added again while it was processed, and is therefore
+	// queued again.
+	OnDone func(item T, requeued bool)
+}
+
+// ItemInfo is a TypedItemInfo of items of any comparable type.
+type ItemInfo = TypedItemInfo[any]
+
+// TypedItemInfo describes the state of an item of a queue. An item can be in
+// several states at once, e.g. processing, queued again, and waiting to be
+// added again after a delay.
+type TypedItemInfo[T comparable] struct {
+	Item T
+
+	// Queued is true if the item is waiting to be handed out by Get, which
+	// only happens after it is Done if it is also Processing.
+	Queued bool
+	// EnqueueTime is the time at which the item was queued. It is only set
+	// if introspection is enabled in the config of the queue.
+	EnqueueTime time.Time
+
+	// Processing is true if the item was handed out by Get, and Done was
+	// not called for it yet.
+	Processing bool
+	// ProcessingStartTime is the time at which the item was handed out by
+	// Get. It is only set if introspection is enabled in the config of the
+	// queue.
+	ProcessingStartTime time.Time
+
+	// Waiting is true if the item is to be added to the queue after a
+	// delay, by a delaying queue.
+	Waiting bool
+	// ReadyAt is the time at which the item is to be added to the queue.
+	ReadyAt time.Time
+
+	// Requeues is the number of times the item was requeued according to
+	// the rate limiter of a rate limiting queue.
+	Requeues int
+}
+
+// QueueInspector is a TypedQueueInspector of items of any comparable type.
+type QueueInspector = TypedQueueInspector[any]
+
+// TypedQueueInspector is implemented by the queues of this package to report
+// the state of their items, for debugging purposes. The state may be stale by
+// the time it is returned, so it must not be used to make decisions.
+//
+// A delaying queue wrapping a queue which is not a TypedQueueInspector only
+// reports the items waiting to be added to it. A rate limiting queue reports
+// the items known to its delaying queue, with their Requeues.
+type TypedQueueInspector[T comparable] interface {
+	// Inspect returns the state of item, and false if the queue does not
+	// know about it, i.e. it is neither queued, processing nor waiting.
+	Inspect(item T) (TypedItemInfo[T], bool)
+	// InspectAll returns the state of every item that the queue knows
+	// about, in no particular order.
+	InspectAll() []TypedItemInfo[T]
+}
+
+var _ TypedQueueInspector[any] = &Type{}
+var _ TypedQueueInspector[any] = &delayingType[any]{}
+var _ TypedQueueInspector[any] = &rateLimitingType[any]{}
+
+// Inspect returns the state of item, and false if the queue does not know
+// about it.
+func (q *Typed[T]) Inspect(item T) (TypedItemInfo[T], bool) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	return q.inspect(item)
+}
+
+// InspectAll returns the state of every queued or processing item.
+func (q *Typed[T]) InspectAll() []TypedItemInfo[T] {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+
+	infos := make([]TypedItemInfo[T], 0, q.dirty.len()+q.processing.len())
+	for item := range q.dirty {
+		info, _ := q.inspect(item)
+		infos = append(infos, info)
+	}
+	for item := range q.processing {
+		if !q.dirty.has(item) {
+			info, _ := q.inspect(item)
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+func (q *Typed[T]) inspect(item T) (TypedItemInfo[T], bool) {
+	info := TypedItemInfo[T]{Item: item}
+	if q.dirty.has(item) {
+		info.Queued = true
+		info.EnqueueTime = q.enqueueTimes[item]
+	}
+	if q.processing.has(item) {
+		info.Processing = true
+		info.ProcessingStartTime = q.processingStartTimes[item]
+	}
+	return info, info.Queued || info.Processing
+}
+
+// Inspect returns the state of item in the wrapped queue and in the waiting
+// loop, and false if neither knows about it.
+func (q *delayingType[T]) Inspect(item T) (TypedItemInfo[T], bool) {
+	info, known := TypedItemInfo[T]{Item: item}, false
+	if inspector, ok := q.TypedInterface.(TypedQueueInspector[T]); ok {
+		info, known = inspector.Inspect(item)
+	}
+
+	q.waitingLock.Lock()
+	defer q.waitingLock.Unlock()
+	if entry, waiting := q.waiting[item]; waiting {
+		info.Waiting = true
+		info.ReadyAt = entry.readyAt
+		known = true
+	}
+	return info, known
+}
+
+// InspectAll returns the state of every item known to the wrapped queue or
+// waiting to be added to it.
+func (q *delayingType[T]) InspectAll() []TypedItemInfo[T] {
+	var infos []TypedItemInfo[T]
+	if inspector, ok := q.TypedInterface.(TypedQueueInspector[T]); ok {
+		infos = inspector.InspectAll()
+	}
+	indexes := make(map[T]int, len(infos))
+	for i, info := range infos {
+		indexes[info.Item] = i
+	}
+
+	q.waitingLock.Lock()
+	defer q.waitingLock.Unlock()
+	for item, entry := range q.waiting {
+		i, exists := indexes[item]
+		if !exists {
+			i = len(infos)
+			infos = append(infos, TypedItemInfo[T]{Item: item})
+		}
+		infos[i].Waiting = true
+		infos[i].ReadyAt = entry.readyAt
+	}
+	return infos
+}
+
+// Inspect returns the state of item in the wrapped delaying queue, with its
+// number of requeues, and false if the delaying queue does not know about it.
+func (q *rateLimitingType[T]) Inspect(item T) (TypedItemInfo[T], bool) {
+	inspector, ok := q.TypedDelayingInterface.(TypedQueueInspector[T])
+	if !ok {
+		return TypedItemInfo[T]{Item: item}, false
+	}
+	info, known := inspector.Inspect(item)
+	if known {
+		info.Requeues = q.rateLimiter.NumRequeues(item)
+	}
+	return info, known
+}
+
+// InspectAll returns the state of every item known to the wrapped delaying
+// queue, with their number of requeues.
+func (q *rateLimitingType[T]) InspectAll() []TypedItemInfo[T] {
+	inspector, ok := q.TypedDelayingInterface.(TypedQueueInspector[T])
+	if !ok {
+		return nil
+	}
+	infos := inspector.InspectAll()
+	for i := range infos {
+		infos[i].Requeues = q.rateLimiter.NumRequeues(infos[i].Item)
+	}
+	return infos
+}
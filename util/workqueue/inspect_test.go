@@ -0,0 +1,121 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workqueue
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestInspect(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	var events []string
+	delayingQueue := NewTypedDelayingQueueWithConfig(TypedDelayingQueueConfig[string]{
+		Clock:               fakeClock,
+		EnableIntrospection: true,
+		Hooks: TypedItemHooks[string]{
+			OnAdd: func(item string) { events = append(events, "add "+item) },
+			OnGet: func(item string) { events = append(events, "get "+item) },
+			OnDone: func(item string, requeued bool) {
+				if requeued {
+					item += " requeued"
+				}
+				events = append(events, "done "+item)
+			},
+		},
+	})
+	q := NewTypedRateLimitingQueueWithConfig(
+		NewTypedItemExponentialFailureRateLimiter[string](1*time.Second, 1*time.Minute),
+		TypedRateLimitingQueueConfig[string]{DelayingQueue: delayingQueue},
+	)
+	defer q.ShutDown()
+	inspector := q.(TypedQueueInspector[string])
+
+	if _, known := inspector.Inspect("one"); known {
+		t.Errorf("expected one to be unknown")
+	}
+
+	start := fakeClock.Now()
+	q.AddRateLimited("one")
+	q.Add("two")
+	if err := waitForWaitingQueueToFill(delayingQueue); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	expected := TypedItemInfo[string]{Item: "one", Waiting: true, ReadyAt: start.Add(time.Second), Requeues: 1}
+	if info, known := inspector.Inspect("one"); !known || !reflect.DeepEqual(expected, info) {
+		t.Errorf("expected %+v, got %+v", expected, info)
+	}
+
+	fakeClock.Step(time.Second)
+	if err := waitForAdded(delayingQueue, 2); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	item, _ := q.Get()
+	if item != "two" {
+		t.Fatalf("expected two, got %s", item)
+	}
+	q.Add("two")
+	q.AddAfter("two", time.Minute)
+	if err := waitForWaitingQueueToFill(delayingQueue); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	now := fakeClock.Now()
+	infos := map[string]TypedItemInfo[string]{}
+	for _, info := range inspector.InspectAll() {
+		infos[info.Item] = info
+	}
+	expectedInfos := map[string]TypedItemInfo[string]{
+		"one": {Item: "one", Queued: true, EnqueueTime: now, Requeues: 1},
+		"two": {Item: "two", Queued: true, EnqueueTime: now, Processing: true, ProcessingStartTime: now, Waiting: true, ReadyAt: now.Add(time.Minute)},
+	}
+	if !reflect.DeepEqual(expectedInfos, infos) {
+		t.Errorf("expected %+v, got %+v", expectedInfos, infos)
+	}
+
+	q.Done(item)
+	item, _ = q.Get()
+	q.Done(item)
+	expectedEvents := []string{"add two", "add one", "get two", "add two", "done two requeued", "get one", "done one"}
+	if !reflect.DeepEqual(expectedEvents, events) {
+		t.Errorf("expected events %v, got %v", expectedEvents, events)
+	}
+}
+
+func TestInspectWithoutIntrospection(t *testing.T) {
+	q := NewTyped[string]()
+	defer q.ShutDown()
+	if q.enqueueTimes != nil || q.processingStartTimes != nil {
+		t.Fatalf("expected no times to be recorded without introspection")
+	}
+
+	q.Add("one")
+	q.Add("two")
+	item, _ := q.Get()
+	expected := TypedItemInfo[string]{Item: item, Processing: true}
+	if info, known := q.Inspect(item); !known || !reflect.DeepEqual(expected, info) {
+		t.Errorf("expected %+v, got %+v", expected, info)
+	}
+	expected = TypedItemInfo[string]{Item: "two", Queued: true}
+	if info, known := q.Inspect("two"); !known || !reflect.DeepEqual(expected, info) {
+		t.Errorf("expected %+v, got %+v", expected, info)
+	}
+	q.Done(item)
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workqueue
+
+import (
+	"container/list"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/clock"
+)
+
+// ItemRateLimiterOptions configures the item based rate limiters, which
+// track the failures of every item until it is forgotten.
+type ItemRateLimiterOptions struct {
+	// IdleTTL, if positive, is the time after its last failure after which
+	// an item is forgotten, as if Forget had been called. This bounds the
+	// memory used for the items that callers never call Forget for.
+	IdleTTL time.Duration
+
+	// MaxItems, if positive, is the maximum number of items tracked. Beyond
+	// it, the items whose last failure is the oldest are forgotten.
+	MaxItems int
+
+	// Jitter, if positive, adds a random delay of up to Jitter times the
+	// delay returned by When, so that items which failed together are not
+	// all retried at the same time. The result may exceed the maximum delay
+	// of the rate limiter.
+	Jitter float64
+
+	// Clock optionally allows injecting a real or fake clock for testing purposes.
+	Clock clock.PassiveClock
+}
+
+// jitter applies the Jitter option to delay.
+func (o *ItemRateLimiterOptions) jitter(delay time.Duration) time.Duration {
+	if o.Jitter <= 0 {
+		return delay
+	}
+	return wait.Jitter(delay, o.Jitter)
+}
+
+// itemFailures counts the failures of items, and forgets the items according
+// to ItemRateLimiterOptions. It is not safe for concurrent use.
+type itemFailures[T comparable] struct {
+	idleTTL  time.Duration
+	maxItems int
+	clock    clock.PassiveClock
+
+	// counts holds the number of failures of every item if neither idleTTL
+	// nor maxItems is set, in which case entries and lru are not used.
+	counts map[T]int
+
+	entries map[T]*list.Element
+	// lru holds the itemFailure of every item, the one that failed most
+	// recently first.
+	lru *list.List
+}
+
+type itemFailure[T comparable] struct {
+	item        T
+	failures    int
+	lastFailure time.Time
+}
+
+func newItemFailures[T comparable](options ItemRateLimiterOptions) *itemFailures[T] {
+	if options.IdleTTL <= 0 && options.MaxItems <= 0 {
+		return &itemFailures[T]{counts: map[T]int{}}
+	}
+	if options.Clock == nil {
+		options.Clock = clock.RealClock{}
+	}
+	return &itemFailures[T]{
+		idleTTL:  options.IdleTTL,
+		maxItems: options.MaxItems,
+		clock:    options.Clock,
+		entries:  map[T]*list.Element{},
+		lru:      list.New(),
+	}
+}
+
+// failed records a failure of item, and returns its number of failures.
+func (f *itemFailures[T]) failed(item T) int {
+	if f.counts != nil {
+		f.counts[item]++
+		return f.counts[item]
+	}
+	now := f.clock.Now()
+	f.evict(now)
+
+	element, exists := f.entries[item]
+	if !exists {
+		element = f.lru.PushFront(&itemFailure[T]{item: item})
+		f.entries[item] = element
+	} else {
+		f.lru.MoveToFront(element)
+	}
+	entry := element.Value.(*itemFailure[T])
+	entry.failures++
+	entry.lastFailure = now
+
+	if f.maxItems > 0 && f.lru.Len() > f.maxItems {
+		f.remove(f.lru.Back())
+	}
+	return entry.failures
+}
+
+// get returns the number of failures of item.
+func (f *itemFailures[T]) get(item T) int {
+	if f.counts != nil {
+		return f.counts[item]
+	}
+	f.evict(f.clock.Now())
+	if element, exists := f.entries[item]; exists {
+		return element.Value.(*itemFailure[T]).failures
+	}
+	return 0
+}
+
+func (f *itemFailures[T]) forget(item T) {
+	if f.counts != nil {
+		delete(f.counts, item)
+		return
+	}
+	if element, exists := f.entries[item]; exists {
+		f.remove(element)
+	}
+}
+
+func (f *itemFailures[T]) len() int {
+	if f.counts != nil {
+		return len(f.counts)
+	}
+	return f.lru.Len()
+}
+
+// evict forgets the items which have been idle for longer than idleTTL.
+func (f *itemFailures[T]) evict(now time.Time) {
+	if f.idleTTL <= 0 {
+		return
+	}
+	for element := f.lru.Back(); element != nil; element = f.lru.Back() {
+		if now.Sub(element.Value.(*itemFailure[T]).lastFailure) < f.idleTTL {
+			return
+		}
+		f.remove(element)
+	}
+}
+
+func (f *itemFailures[T]) remove(element *list.Element) {
+	f.lru.Remove(element)
+	delete(f.entries, element.Value.(*itemFailure[T]).item)
+}
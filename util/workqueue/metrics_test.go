@@ -41,7 +41,7 @@ func TestMetricShutdown(t *testing.T) {
 		updateCalled: ch,
 	}
 	c := testingclock.NewFakeClock(time.Now())
-	q := newQueue(c, m, time.Millisecond)
+	q := newQueue[any](c, &fifo[any]{}, m, time.Millisecond)
 	for !c.HasWaiters() {
 		// Wait for the go routine to call NewTicker()
 		time.Sleep(time.Millisecond)
@@ -176,7 +176,7 @@ func TestMetrics(t *testing.T) {
 		Clock:           c,
 		MetricsProvider: &mp,
 	}
-	q := newQueueWithConfig(config, time.Millisecond)
+	q := newQueueWithConfig[any](config, &fifo[any]{}, time.Millisecond)
 	defer q.ShutDown()
 	for !c.HasWaiters() {
 		// Wait for the go routine to call NewTicker()
@@ -19,14 +19,26 @@ package workqueue
 import (
 	"context"
 	"sync"
+	"time"
 
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 )
 
 type DoWorkPieceFunc func(piece int)
 
+// DoWorkPieceWithErrorFunc does a piece of work for
+// ParallelizeUntilWithError. ctx is done when the piece should be abandoned.
+type DoWorkPieceWithErrorFunc func(ctx context.Context, piece int) error
+
 type options struct {
 	chunkSize int
+
+	// The following options only apply to ParallelizeUntilWithError.
+	cancelOnError bool
+	allErrors     bool
+	pieceTimeout  time.Duration
+	progress      func(done, pieces int)
 }
 
 type Options func(*options)
@@ -41,17 +53,118 @@ func WithChunkSize(c int) func(*options) {
 	}
 }
 
+// WithCancelOnError makes ParallelizeUntilWithError stop handing out pieces
+// once a piece failed, and cancel the context of the pieces in progress.
+func WithCancelOnError() func(*options) {
+	return func(o *options) {
+		o.cancelOnError = true
+	}
+}
+
+// WithAllErrors makes ParallelizeUntilWithError return the errors of all the
+// pieces that failed as an aggregate, rather than only the first one.
+func WithAllErrors() func(*options) {
+	return func(o *options) {
+		o.allErrors = true
+	}
+}
+
+// WithPieceTimeout makes ParallelizeUntilWithError cancel the context of
+// each piece after the given timeout.
+func WithPieceTimeout(timeout time.Duration) func(*options) {
+	return func(o *options) {
+		o.pieceTimeout = timeout
+	}
+}
+
+// WithProgress makes ParallelizeUntilWithError call progress after each
+// piece, successful or not, with the number of pieces done so far. The calls
+// are serialized.
+func WithProgress(progress func(done, pieces int)) func(*options) {
+	return func(o *options) {
+		o.progress = progress
+	}
+}
+
 // ParallelizeUntil is a framework that allows for parallelizing N
 // independent pieces of work until done or the context is canceled.
 func ParallelizeUntil(ctx context.Context, workers, pieces int, doWorkPiece DoWorkPieceFunc, opts ...Options) {
-	if pieces == 0 {
-		return
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
 	}
+
+	var stop <-chan struct{}
+	if ctx != nil {
+		stop = ctx.Done()
+	}
+	parallelize(stop, workers, pieces, o.chunkSize, doWorkPiece)
+}
+
+// ParallelizeUntilWithError is ParallelizeUntil for pieces of work that can
+// fail. Every piece gets its own context, derived from ctx. It returns the
+// first error returned by a piece, or all of them with WithAllErrors, and the
+// error of ctx if it is done before all pieces were handed out.
+//
+// By default, all pieces are handed out even when some fail, see
+// WithCancelOnError.
+func ParallelizeUntilWithError(ctx context.Context, workers, pieces int, doWorkPiece DoWorkPieceWithErrorFunc, opts ...Options) error {
 	o := options{}
 	for _, opt := range opts {
 		opt(&o)
 	}
-	chunkSize := o.chunkSize
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var lock sync.Mutex
+	var errs []error
+	done := 0
+	parallelize(ctx.Done(), workers, pieces, o.chunkSize, func(piece int) {
+		pieceCtx, pieceCancel := ctx, context.CancelFunc(func() {})
+		if o.pieceTimeout > 0 {
+			pieceCtx, pieceCancel = context.WithTimeout(ctx, o.pieceTimeout)
+		}
+		err := doWorkPiece(pieceCtx, piece)
+		pieceCancel()
+
+		lock.Lock()
+		defer lock.Unlock()
+		if err != nil {
+			errs = append(errs, err)
+			if o.cancelOnError {
+				cancel()
+			}
+		}
+		done++
+		if o.progress != nil {
+			o.progress(done, pieces)
+		}
+	})
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(errs) == 0 {
+		if done < pieces {
+			return ctx.Err()
+		}
+		return nil
+	}
+	if o.allErrors {
+		return utilerrors.NewAggregate(errs)
+	}
+	return errs[0]
+}
+
+// parallelize calls doWorkPiece for every piece with the given number of
+// workers, handing out pieces to the workers by chunks, until done or stop
+// is closed.
+func parallelize(stop <-chan struct{}, workers, pieces, chunkSize int, doWorkPiece DoWorkPieceFunc) {
+	if pieces == 0 {
+		return
+	}
 	if chunkSize < 1 {
 		chunkSize = 1
 	}
@@ -63,10 +176,6 @@ func ParallelizeUntil(ctx context.Context, workers, pieces int, doWorkPiece DoWo
 	}
 	close(toProcess)
 
-	var stop <-chan struct{}
-	if ctx != nil {
-		stop = ctx.Done()
-	}
 	if chunks < workers {
 		workers = chunks
 	}
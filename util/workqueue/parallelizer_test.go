@@ -18,11 +18,14 @@ package workqueue
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 )
 
 type testCase struct {
@@ -78,6 +81,91 @@ func TestParallelizeUntil(t *testing.T) {
 	}
 }
 
+func TestParallelizeUntilWithError(t *testing.T) {
+	for _, tc := range cases {
+		t.Run(tc.String(), func(t *testing.T) {
+			seen := make([]int32, tc.pieces)
+			lastDone := 0
+			err := ParallelizeUntilWithError(context.Background(), tc.workers, tc.pieces, func(ctx context.Context, p int) error {
+				atomic.AddInt32(&seen[p], 1)
+				if p%100 == 1 {
+					return fmt.Errorf("piece %d failed", p)
+				}
+				return nil
+			}, WithChunkSize(tc.chunkSize), WithAllErrors(), WithProgress(func(done, pieces int) {
+				if done != lastDone+1 || pieces != tc.pieces {
+					t.Errorf("unexpected progress %d/%d after %d", done, pieces, lastDone)
+				}
+				lastDone = done
+			}))
+
+			wantSeen := make([]int32, tc.pieces)
+			for i := 0; i < tc.pieces; i++ {
+				wantSeen[i] = 1
+			}
+			if diff := cmp.Diff(wantSeen, seen); diff != "" {
+				t.Errorf("bad number of visits (-want,+got):\n%s", diff)
+			}
+			var agg utilerrors.Aggregate
+			if !errors.As(err, &agg) || len(agg.Errors()) != 10 {
+				t.Errorf("expected 10 errors, got %v", err)
+			}
+			if lastDone != tc.pieces {
+				t.Errorf("expected progress to reach %d, got %d", tc.pieces, lastDone)
+			}
+		})
+	}
+}
+
+func TestParallelizeUntilWithErrorCancelOnError(t *testing.T) {
+	var visits int32
+	failure := errors.New("failed")
+	err := ParallelizeUntilWithError(context.Background(), 1, 100, func(ctx context.Context, p int) error {
+		atomic.AddInt32(&visits, 1)
+		if p == 10 {
+			return failure
+		}
+		return nil
+	}, WithCancelOnError())
+	if err != failure {
+		t.Errorf("expected %v, got %v", failure, err)
+	}
+	if visits != 11 {
+		t.Errorf("expected 11 visits, got %d", visits)
+	}
+
+	// The pieces in progress are canceled.
+	err = ParallelizeUntilWithError(context.Background(), 2, 2, func(ctx context.Context, p int) error {
+		if p == 0 {
+			return failure
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithCancelOnError())
+	if err != failure {
+		t.Errorf("expected %v, got %v", failure, err)
+	}
+}
+
+func TestParallelizeUntilWithErrorContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := ParallelizeUntilWithError(ctx, 1, 10, func(ctx context.Context, p int) error {
+		return nil
+	})
+	if err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+
+	err = ParallelizeUntilWithError(context.Background(), 2, 2, func(ctx context.Context, p int) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithPieceTimeout(time.Millisecond))
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
 func BenchmarkParallelizeUntil(b *testing.B) {
 	for _, tc := range cases {
 		b.Run(tc.String(), func(b *testing.B) {
@@ -0,0 +1,343 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workqueue
+
+import (
+	"container/heap"
+	"time"
+
+	"k8s.io/utils/clock"
+)
+
+// PriorityInterface is a TypedPriorityInterface of items of any comparable type.
+type PriorityInterface = TypedPriorityInterface[any]
+
+// TypedPriorityInterface is a TypedInterface whose Get returns the queued item
+// with the highest priority, and among those the one queued first. Add adds
+// an item with priority 0.
+//
+// Items are deduplicated like in Typed: adding an item which is already
+// queued, or waiting to be re-queued once Done, does not add it again but
+// raises its priority if the new priority is higher.
+type TypedPriorityInterface[T comparable] interface {
+	TypedInterface[T]
+	// AddWithPriority marks item as needing processing with the given priority.
+	AddWithPriority(item T, priority int)
+}
+
+// NewPriority constructs a new work queue which hands out items by priority.
+func NewPriority() PriorityInterface {
+	return NewTypedPriority[any]()
+}
+
+// NewTypedPriority constructs a new work queue of items of type T which hands
+// out items by priority.
+func NewTypedPriority[T comparable]() TypedPriorityInterface[T] {
+	return NewTypedPriorityWithConfig(TypedQueueConfig[T]{})
+}
+
+// NewPriorityWithConfig constructs a new work queue which hands out items by
+// priority, with ability to customize different properties.
+func NewPriorityWithConfig(config QueueConfig) PriorityInterface {
+	return NewTypedPriorityWithConfig[any](config)
+}
+
+// NewTypedPriorityWithConfig constructs a new work queue of items of type T
+// which hands out items by priority, with ability to customize different
+// properties.
+func NewTypedPriorityWithConfig[T comparable](config TypedQueueConfig[T]) TypedPriorityInterface[T] {
+	return &priorityType[T]{
+		Typed: newQueueWithConfig[T](config, newPriorityOrder[T](), defaultUnfinishedWorkUpdatePeriod),
+	}
+}
+
+// priorityType is a Typed queue with a priorityOrder.
+type priorityType[T comparable] struct {
+	*Typed[T]
+}
+
+func (q *priorityType[T]) AddWithPriority(item T, priority int) {
+	q.add(item, priority)
+}
+
+// PriorityDelayingInterface is a TypedPriorityDelayingInterface of items of
+// any comparable type.
+type PriorityDelayingInterface = TypedPriorityDelayingInterface[any]
+
+// TypedPriorityDelayingInterface is a TypedDelayingInterface which hands out
+// items by priority, see TypedPriorityInterface. AddAfter adds an item with
+// priority 0.
+type TypedPriorityDelayingInterface[T comparable] interface {
+	TypedDelayingInterface[T]
+	// AddWithPriority marks item as needing processing with the given priority.
+	AddWithPriority(item T, priority int)
+	// AddAfterWithPriority adds an item to the workqueue with the given
+	// priority after the indicated duration has passed.
+	AddAfterWithPriority(item T, priority int, duration time.Duration)
+}
+
+// PriorityDelayingQueueConfig specifies optional configurations to customize
+// a PriorityDelayingInterface.
+type PriorityDelayingQueueConfig = TypedPriorityDelayingQueueConfig[any]
+
+// TypedPriorityDelayingQueueConfig specifies optional configurations to
+// customize a TypedPriorityDelayingInterface.
+type TypedPriorityDelayingQueueConfig[T comparable] struct {
+	// Name for the queue. If unnamed, the metrics will not be registered.
+	Name string
+
+	// MetricsProvider optionally allows specifying a metrics provider to use for the queue
+	// instead of the global provider.
+	MetricsProvider MetricsProvider
+
+	// Clock optionally allows injecting a real or fake clock for testing purposes.
+	Clock clock.WithTicker
+
+	// Queue optionally allows injecting custom queue TypedPriorityInterface instead of the default one.
+	Queue TypedPriorityInterface[T]
+
+	// Hooks optionally allows observing the lifecycle of the items of the default queue.
+	// It is ignored if Queue is set.
+	Hooks TypedItemHooks[T]
+
+	// EnableIntrospection makes the default queue record the times reported
+	// by Inspect, see TypedQueueConfig. It is ignored if Queue is set.
+	EnableIntrospection bool
+}
+
+// NewPriorityDelayingQueueWithConfig constructs a new workqueue which hands
+// out items by priority, with delayed queuing ability.
+func NewPriorityDelayingQueueWithConfig(config PriorityDelayingQueueConfig) PriorityDelayingInterface {
+	return NewTypedPriorityDelayingQueueWithConfig(config)
+}
+
+// NewTypedPriorityDelayingQueueWithConfig constructs a new workqueue of items
+// of type T which hands out items by priority, with delayed queuing ability.
+func NewTypedPriorityDelayingQueueWithConfig[T comparable](config TypedPriorityDelayingQueueConfig[T]) TypedPriorityDelayingInterface[T] {
+	if config.Clock == nil {
+		config.Clock = clock.RealClock{}
+	}
+
+	if config.Queue == nil {
+		config.Queue = NewTypedPriorityWithConfig(TypedQueueConfig[T]{
+			Name:                config.Name,
+			MetricsProvider:     config.MetricsProvider,
+			Clock:               config.Clock,
+			Hooks:               config.Hooks,
+			EnableIntrospection: config.EnableIntrospection,
+		})
+	}
+
+	return newDelayingQueue[T](config.Clock, config.Queue, config.Name, config.MetricsProvider)
+}
+
+// PriorityRateLimitingInterface is a TypedPriorityRateLimitingInterface of
+// items of any comparable type.
+type PriorityRateLimitingInterface = TypedPriorityRateLimitingInterface[any]
+
+// TypedPriorityRateLimitingInterface is a TypedRateLimitingInterface which
+// hands out items by priority, see TypedPriorityInterface. AddRateLimited
+// adds an item with priority 0.
+type TypedPriorityRateLimitingInterface[T comparable] interface {
+	TypedRateLimitingInterface[T]
+	TypedPriorityDelayingInterface[T]
+	// AddRateLimitedWithPriority adds an item to the workqueue with the
+	// given priority after the rate limiter says it's ok.
+	AddRateLimitedWithPriority(item T, priority int)
+}
+
+// PriorityRateLimitingQueueConfig specifies optional configurations to
+// customize a PriorityRateLimitingInterface.
+type PriorityRateLimitingQueueConfig = TypedPriorityRateLimitingQueueConfig[any]
+
+// TypedPriorityRateLimitingQueueConfig specifies optional configurations to
+// customize a TypedPriorityRateLimitingInterface.
+type TypedPriorityRateLimitingQueueConfig[T comparable] struct {
+	// Name for the queue. If unnamed, the metrics will not be registered.
+	Name string
+
+	// MetricsProvider optionally allows specifying a metrics provider to use for the queue
+	// instead of the global provider.
+	MetricsProvider MetricsProvider
+
+	// Clock optionally allows injecting a real or fake clock for testing purposes.
+	Clock clock.WithTicker
+
+	// DelayingQueue optionally allows injecting custom delaying queue TypedPriorityDelayingInterface instead of the default one.
+	DelayingQueue TypedPriorityDelayingInterface[T]
+
+	// Hooks optionally allows observing the lifecycle of the items of the default queue.
+	// It is ignored if DelayingQueue is set.
+	Hooks TypedItemHooks[T]
+
+	// EnableIntrospection makes the default queue record the times reported
+	// by Inspect, see TypedQueueConfig. It is ignored if DelayingQueue is set.
+	EnableIntrospection bool
+}
+
+// NewPriorityRateLimitingQueueWithConfig constructs a new workqueue which
+// hands out items by priority, with rateLimited queuing ability.
+// Remember to call Forget!  If you don't, you may end up tracking failures forever.
+func NewPriorityRateLimitingQueueWithConfig(rateLimiter RateLimiter, config PriorityRateLimitingQueueConfig) PriorityRateLimitingInterface {
+	return NewTypedPriorityRateLimitingQueueWithConfig(rateLimiter, config)
+}
+
+// NewTypedPriorityRateLimitingQueueWithConfig constructs a new workqueue of
+// items of type T which hands out items by priority, with rateLimited queuing
+// ability.
+// Remember to call Forget!  If you don't, you may end up tracking failures forever.
+func NewTypedPriorityRateLimitingQueueWithConfig[T comparable](rateLimiter TypedRateLimiter[T], config TypedPriorityRateLimitingQueueConfig[T]) TypedPriorityRateLimitingInterface[T] {
+	if config.Clock == nil {
+		config.Clock = clock.RealClock{}
+	}
+
+	if config.DelayingQueue == nil {
+		config.DelayingQueue = NewTypedPriorityDelayingQueueWithConfig(TypedPriorityDelayingQueueConfig[T]{
+			Name:                config.Name,
+			MetricsProvider:     config.MetricsProvider,
+			Clock:               config.Clock,
+			Hooks:               config.Hooks,
+			EnableIntrospection: config.EnableIntrospection,
+		})
+	}
+
+	return &priorityRateLimitingType[T]{
+		rateLimitingType: rateLimitingType[T]{
+			TypedDelayingInterface: config.DelayingQueue,
+			rateLimiter:            rateLimiter,
+		},
+		delayingQueue: config.DelayingQueue,
+	}
+}
+
+// priorityRateLimitingType is a rateLimitingType wrapping a
+// TypedPriorityDelayingInterface.
+type priorityRateLimitingType[T comparable] struct {
+	rateLimitingType[T]
+
+	delayingQueue TypedPriorityDelayingInterface[T]
+}
+
+func (q *priorityRateLimitingType[T]) AddWithPriority(item T, priority int) {
+	q.delayingQueue.AddWithPriority(item, priority)
+}
+
+func (q *priorityRateLimitingType[T]) AddAfterWithPriority(item T, priority int, duration time.Duration) {
+	q.delayingQueue.AddAfterWithPriority(item, priority, duration)
+}
+
+// AddRateLimitedWithPriority AddAfterWithPriority's the item based on the time when the rate limiter says it's ok
+func (q *priorityRateLimitingType[T]) AddRateLimitedWithPriority(item T, priority int) {
+	q.delayingQueue.AddAfterWithPriority(item, priority, q.rateLimiter.When(item))
+}
+
+// priorityOrder is the order of a Typed queue which hands out items by
+// priority, and items of equal priority in the order they were pushed.
+type priorityOrder[T comparable] struct {
+	heap priorityHeap[T]
+	// queued holds the heap entry of every pushed item.
+	queued map[T]*priorityEntry[T]
+	// priorities holds the priority of every touched item which was not
+	// popped yet, i.e. of every dirty item of the queue.
+	priorities map[T]int
+	// seq is the sequence number of the next pushed item.
+	seq uint64
+}
+
+func newPriorityOrder[T comparable]() *priorityOrder[T] {
+	return &priorityOrder[T]{
+		queued:     map[T]*priorityEntry[T]{},
+		priorities: map[T]int{},
+	}
+}
+
+func (o *priorityOrder[T]) touch(item T, priority int) {
+	if current, exists := o.priorities[item]; exists && current >= priority {
+		return
+	}
+	o.priorities[item] = priority
+	if entry, queued := o.queued[item]; queued {
+		entry.priority = priority
+		heap.Fix(&o.heap, entry.index)
+	}
+}
+
+func (o *priorityOrder[T]) push(item T) {
+	entry := &priorityEntry[T]{
+		item:     item,
+		priority: o.priorities[item],
+		seq:      o.seq,
+	}
+	o.seq++
+	o.queued[item] = entry
+	heap.Push(&o.heap, entry)
+}
+
+func (o *priorityOrder[T]) pop() T {
+	entry := heap.Pop(&o.heap).(*priorityEntry[T])
+	delete(o.queued, entry.item)
+	delete(o.priorities, entry.item)
+	return entry.item
+}
+
+func (o *priorityOrder[T]) len() int {
+	return len(o.heap)
+}
+
+type priorityEntry[T comparable] struct {
+	item     T
+	priority int
+	seq      uint64
+	// index in the heap
+	index int
+}
+
+// priorityHeap implements heap.Interface. The entry with the highest
+// priority, and among those the lowest sequence number, is at the root.
+type priorityHeap[T comparable] []*priorityEntry[T]
+
+func (h priorityHeap[T]) Len() int {
+	return len(h)
+}
+
+func (h priorityHeap[T]) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h priorityHeap[T]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *priorityHeap[T]) Push(x interface{}) {
+	entry := x.(*priorityEntry[T])
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *priorityHeap[T]) Pop() interface{} {
+	n := len(*h)
+	entry := (*h)[n-1]
+	(*h)[n-1] = nil
+	entry.index = -1
+	*h = (*h)[:n-1]
+	return entry
+}
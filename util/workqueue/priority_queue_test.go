@@ -0,0 +1,113 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workqueue
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func getAll[T comparable](q TypedInterface[T]) []T {
+	var items []T
+	for q.Len() > 0 {
+		item, _ := q.Get()
+		q.Done(item)
+		items = append(items, item)
+	}
+	return items
+}
+
+func TestPriorityQueue(t *testing.T) {
+	q := NewTypedPriority[string]()
+	defer q.ShutDown()
+
+	q.Add("low-1")
+	q.AddWithPriority("high-1", 10)
+	q.AddWithPriority("negative", -1)
+	q.Add("low-2")
+	q.AddWithPriority("high-2", 10)
+	// Adding a queued item again does not requeue it, and only raises
+	// its priority.
+	q.AddWithPriority("low-1", -5)
+	q.AddWithPriority("low-2", 5)
+	q.Add("high-1")
+
+	expected := []string{"high-1", "high-2", "low-2", "low-1", "negative"}
+	if items := getAll[string](q); !reflect.DeepEqual(expected, items) {
+		t.Errorf("expected %v, got %v", expected, items)
+	}
+}
+
+func TestPriorityQueueWhileProcessing(t *testing.T) {
+	q := NewTypedPriority[string]()
+	defer q.ShutDown()
+
+	q.Add("a")
+	item, _ := q.Get()
+	q.Add("b")
+	// Re-adding the item being processed queues it once it is done, with
+	// the highest priority it was re-added with.
+	q.AddWithPriority(item, 3)
+	q.Add(item)
+	if e, a := 1, q.Len(); e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	q.Done(item)
+
+	expected := []string{"a", "b"}
+	if items := getAll[string](q); !reflect.DeepEqual(expected, items) {
+		t.Errorf("expected %v, got %v", expected, items)
+	}
+
+	// The priority of an item does not outlive its processing.
+	q.Add("a")
+	q.AddWithPriority("b", 1)
+	expected = []string{"b", "a"}
+	if items := getAll[string](q); !reflect.DeepEqual(expected, items) {
+		t.Errorf("expected %v, got %v", expected, items)
+	}
+}
+
+func TestPriorityRateLimitingQueue(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	delayingQueue := NewTypedPriorityDelayingQueueWithConfig(TypedPriorityDelayingQueueConfig[string]{Clock: fakeClock})
+	q := NewTypedPriorityRateLimitingQueueWithConfig(
+		NewTypedItemExponentialFailureRateLimiter[string](1*time.Millisecond, 1*time.Second),
+		TypedPriorityRateLimitingQueueConfig[string]{DelayingQueue: delayingQueue},
+	)
+	defer q.ShutDown()
+
+	q.AddRateLimited("low")
+	q.AddRateLimitedWithPriority("high", 10)
+	q.AddAfterWithPriority("medium", 5, 1*time.Millisecond)
+	q.Add("first")
+	if err := waitForWaitingQueueToFill[string](delayingQueue); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	fakeClock.Step(1 * time.Millisecond)
+	if err := waitForAdded[string](delayingQueue, 4); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	expected := []string{"high", "medium", "first", "low"}
+	if items := getAll[string](q); !reflect.DeepEqual(expected, items) {
+		t.Errorf("expected %v, got %v", expected, items)
+	}
+}
@@ -23,18 +23,26 @@ import (
 	"k8s.io/utils/clock"
 )
 
-type Interface interface {
-	Add(item interface{})
+// Interface is a work queue of items of any comparable type, see
+// TypedInterface.
+type Interface = TypedInterface[any]
+
+// TypedInterface is a work queue of items of type T (see the package comment).
+type TypedInterface[T comparable] interface {
+	Add(item T)
 	Len() int
-	Get() (item interface{}, shutdown bool)
-	Done(item interface{})
+	Get() (item T, shutdown bool)
+	Done(item T)
 	ShutDown()
 	ShutDownWithDrain()
 	ShuttingDown() bool
 }
 
 // QueueConfig specifies optional configurations to customize an Interface.
-type QueueConfig struct {
+type QueueConfig = TypedQueueConfig[any]
+
+// TypedQueueConfig specifies optional configurations to customize a TypedInterface.
+type TypedQueueConfig[T comparable] struct {
 	// Name for the queue. If unnamed, the metrics will not be registered.
 	Name string
 
@@ -44,6 +52,15 @@ type QueueConfig struct {
 
 	// Clock ability to inject real or fake clock for testing purposes.
 	Clock clock.WithTicker
+
+	// Hooks optionally allows observing the lifecycle of the items of the queue.
+	Hooks TypedItemHooks[T]
+
+	// EnableIntrospection makes the queue record when each item was added
+	// and handed out by Get, as reported by Inspect and InspectAll. It is
+	// off by default since it costs a clock read and a map update on every
+	// Add and Get.
+	EnableIntrospection bool
 }
 
 // New constructs a new work queue (see the package comment).
@@ -53,10 +70,24 @@ func New() *Type {
 	})
 }
 
+// NewTyped constructs a new work queue of items of type T (see the package
+// comment).
+func NewTyped[T comparable]() *Typed[T] {
+	return NewTypedWithConfig(TypedQueueConfig[T]{
+		Name: "",
+	})
+}
+
 // NewWithConfig constructs a new workqueue with ability to
 // customize different properties.
 func NewWithConfig(config QueueConfig) *Type {
-	return newQueueWithConfig(config, defaultUnfinishedWorkUpdatePeriod)
+	return NewTypedWithConfig[any](config)
+}
+
+// NewTypedWithConfig constructs a new workqueue of items of type T with
+// ability to customize different properties.
+func NewTypedWithConfig[T comparable](config TypedQueueConfig[T]) *Typed[T] {
+	return newQueueWithConfig[T](config, &fifo[T]{}, defaultUnfinishedWorkUpdatePeriod)
 }
 
 // NewNamed creates a new named queue.
@@ -69,7 +100,7 @@ func NewNamed(name string) *Type {
 
 // newQueueWithConfig constructs a new named workqueue
 // with the ability to customize different properties for testing purposes
-func newQueueWithConfig(config QueueConfig, updatePeriod time.Duration) *Type {
+func newQueueWithConfig[T comparable](config TypedQueueConfig[T], order order[T], updatePeriod time.Duration) *Typed[T] {
 	var metricsFactory *queueMetricsFactory
 	if config.MetricsProvider != nil {
 		metricsFactory = &queueMetricsFactory{
@@ -83,18 +114,31 @@ func newQueueWithConfig(config QueueConfig, updatePeriod time.Duration) *Type {
 		config.Clock = clock.RealClock{}
 	}
 
-	return newQueue(
+	q := newQueue(
 		config.Clock,
+		order,
 		metricsFactory.newQueueMetrics(config.Name, config.Clock),
 		updatePeriod,
 	)
+	q.hooks = config.Hooks
+	// The debug info of named queues reports their longest running item.
+	if config.EnableIntrospection || (config.Name != "" && debugQueuesEnabled.Load()) {
+		q.processingStartTimes = map[T]time.Time{}
+		q.enqueueTimes = map[T]time.Time{}
+	}
+	if config.Name != "" {
+		q.name = config.Name
+		registerDebugQueue(q)
+	}
+	return q
 }
 
-func newQueue(c clock.WithTicker, metrics queueMetrics, updatePeriod time.Duration) *Type {
-	t := &Type{
+func newQueue[T comparable](c clock.WithTicker, order order[T], metrics queueMetrics, updatePeriod time.Duration) *Typed[T] {
+	t := &Typed[T]{
 		clock:                      c,
-		dirty:                      set{},
-		processing:                 set{},
+		queue:                      order,
+		dirty:                      set[T]{},
+		processing:                 set[T]{},
 		cond:                       sync.NewCond(&sync.Mutex{}),
 		metrics:                    metrics,
 		unfinishedWorkUpdatePeriod: updatePeriod,
@@ -111,21 +155,36 @@ func newQueue(c clock.WithTicker, metrics queueMetrics, updatePeriod time.Durati
 
 const defaultUnfinishedWorkUpdatePeriod = 500 * time.Millisecond
 
-// Type is a work queue (see the package comment).
-type Type struct {
+// Type is a work queue of items of any comparable type (see the package
+// comment).
+type Type = Typed[any]
+
+// Typed is a work queue of items of type T (see the package comment).
+type Typed[T comparable] struct {
 	// queue defines the order in which we will work on items. Every
 	// element of queue should be in the dirty set and not in the
 	// processing set.
-	queue []t
+	queue order[T]
 
 	// dirty defines all of the items that need to be processed.
-	dirty set
+	dirty set[T]
 
 	// Things that are currently being processed are in the processing set.
 	// These things may be simultaneously in the dirty set. When we finish
 	// processing something and remove it from this set, we'll check if
 	// it's in the dirty set, and if so, add it to the queue.
-	processing set
+	processing set[T]
+
+	// processingStartTimes records when each item in the processing set
+	// was handed out by Get. It is nil unless introspection is enabled.
+	processingStartTimes map[T]time.Time
+
+	// enqueueTimes records when each item in the dirty set was added. It
+	// is nil unless introspection is enabled.
+	enqueueTimes map[T]time.Time
+
+	// hooks are called on the lifecycle events of the items.
+	hooks TypedItemHooks[T]
 
 	cond *sync.Cond
 
@@ -136,83 +195,140 @@ type Type struct {
 
 	unfinishedWorkUpdatePeriod time.Duration
 	clock                      clock.WithTicker
+
+	// name is the name of the queue, if any.
+	name string
+}
+
+// order decides the order in which a Typed queue hands out its items. It is
+// only used with the lock of the queue held.
+type order[T comparable] interface {
+	// touch is called whenever item is marked as needing processing, with
+	// the priority it was added with, before push if item is not dirty yet.
+	touch(item T, priority int)
+	// push adds item, which is dirty and not processing, to the order.
+	push(item T)
+	// pop removes the next item from the order and returns it. It is only
+	// called if len is not zero.
+	pop() T
+	// len returns the number of items in the order.
+	len() int
+}
+
+// fifo is the first-in, first-out order of a Typed queue.
+type fifo[T comparable] []T
+
+func (f *fifo[T]) touch(item T, priority int) {}
+
+func (f *fifo[T]) push(item T) {
+	*f = append(*f, item)
+}
+
+func (f *fifo[T]) pop() T {
+	item := (*f)[0]
+	// The underlying array still exists and reference this object, so the object will not be garbage collected.
+	(*f)[0] = *new(T)
+	*f = (*f)[1:]
+	return item
+}
+
+func (f *fifo[T]) len() int {
+	return len(*f)
 }
 
 type empty struct{}
 type t interface{}
-type set map[t]empty
+type set[T comparable] map[T]empty
 
-func (s set) has(item t) bool {
+func (s set[T]) has(item T) bool {
 	_, exists := s[item]
 	return exists
 }
 
-func (s set) insert(item t) {
+func (s set[T]) insert(item T) {
 	s[item] = empty{}
 }
 
-func (s set) delete(item t) {
+func (s set[T]) delete(item T) {
 	delete(s, item)
 }
 
-func (s set) len() int {
+func (s set[T]) len() int {
 	return len(s)
 }
 
 // Add marks item as needing processing.
-func (q *Type) Add(item interface{}) {
+func (q *Typed[T]) Add(item T) {
+	q.add(item, 0)
+}
+
+// add marks item as needing processing with the given priority, which only
+// matters to orders that take priorities into account.
+func (q *Typed[T]) add(item T, priority int) {
 	q.cond.L.Lock()
 	defer q.cond.L.Unlock()
 	if q.shuttingDown {
 		return
 	}
 	if q.dirty.has(item) {
+		q.queue.touch(item, priority)
 		return
 	}
 
 	q.metrics.add(item)
 
 	q.dirty.insert(item)
+	if q.enqueueTimes != nil {
+		q.enqueueTimes[item] = q.clock.Now()
+	}
+	q.queue.touch(item, priority)
+	if q.hooks.OnAdd != nil {
+		q.hooks.OnAdd(item)
+	}
 	if q.processing.has(item) {
 		return
 	}
 
-	q.queue = append(q.queue, item)
+	q.queue.push(item)
 	q.cond.Signal()
 }
 
 // Len returns the current queue length, for informational purposes only. You
 // shouldn't e.g. gate a call to Add() or Get() on Len() being a particular
 // value, that can't be synchronized properly.
-func (q *Type) Len() int {
+func (q *Typed[T]) Len() int {
 	q.cond.L.Lock()
 	defer q.cond.L.Unlock()
-	return len(q.queue)
+	return q.queue.len()
 }
 
 // Get blocks until it can return an item to be processed. If shutdown = true,
 // the caller should end their goroutine. You must call Done with item when you
 // have finished processing it.
-func (q *Type) Get() (item interface{}, shutdown bool) {
+func (q *Typed[T]) Get() (item T, shutdown bool) {
 	q.cond.L.Lock()
 	defer q.cond.L.Unlock()
-	for len(q.queue) == 0 && !q.shuttingDown {
+	for q.queue.len() == 0 && !q.shuttingDown {
 		q.cond.Wait()
 	}
-	if len(q.queue) == 0 {
+	if q.queue.len() == 0 {
 		// We must be shutting down.
-		return nil, true
+		return *new(T), true
 	}
 
-	item = q.queue[0]
-	// The underlying array still exists and reference this object, so the object will not be garbage collected.
-	q.queue[0] = nil
-	q.queue = q.queue[1:]
+	item = q.queue.pop()
 
 	q.metrics.get(item)
 
 	q.processing.insert(item)
+	if q.processingStartTimes != nil {
+		q.processingStartTimes[item] = q.clock.Now()
+	}
 	q.dirty.delete(item)
+	delete(q.enqueueTimes, item)
+	if q.hooks.OnGet != nil {
+		q.hooks.OnGet(item)
+	}
 
 	return item, false
 }
@@ -220,15 +336,19 @@ func (q *Type) Get() (item interface{}, shutdown bool) {
 // Done marks item as done processing, and if it has been marked as dirty again
 // while it was being processed, it will be re-added to the queue for
 // re-processing.
-func (q *Type) Done(item interface{}) {
+func (q *Typed[T]) Done(item T) {
 	q.cond.L.Lock()
 	defer q.cond.L.Unlock()
 
 	q.metrics.done(item)
 
 	q.processing.delete(item)
+	delete(q.processingStartTimes, item)
+	if q.hooks.OnDone != nil {
+		q.hooks.OnDone(item, q.dirty.has(item))
+	}
 	if q.dirty.has(item) {
-		q.queue = append(q.queue, item)
+		q.queue.push(item)
 		q.cond.Signal()
 	} else if q.processing.len() == 0 {
 		q.cond.Signal()
@@ -237,7 +357,9 @@ func (q *Type) Done(item interface{}) {
 
 // ShutDown will cause q to ignore all new items added to it and
 // immediately instruct the worker goroutines to exit.
-func (q *Type) ShutDown() {
+func (q *Typed[T]) ShutDown() {
+	unregisterDebugQueue(q)
+
 	q.cond.L.Lock()
 	defer q.cond.L.Unlock()
 
@@ -255,7 +377,9 @@ func (q *Type) ShutDown() {
 // indefinitely. It is, however, safe to call ShutDown after having called
 // ShutDownWithDrain, as to force the queue shut down to terminate immediately
 // without waiting for the drainage.
-func (q *Type) ShutDownWithDrain() {
+func (q *Typed[T]) ShutDownWithDrain() {
+	unregisterDebugQueue(q)
+
 	q.cond.L.Lock()
 	defer q.cond.L.Unlock()
 
@@ -268,14 +392,14 @@ func (q *Type) ShutDownWithDrain() {
 	}
 }
 
-func (q *Type) ShuttingDown() bool {
+func (q *Typed[T]) ShuttingDown() bool {
 	q.cond.L.Lock()
 	defer q.cond.L.Unlock()
 
 	return q.shuttingDown
 }
 
-func (q *Type) updateUnfinishedWorkLoop() {
+func (q *Typed[T]) updateUnfinishedWorkLoop() {
 	t := q.clock.NewTicker(q.unfinishedWorkUpdatePeriod)
 	defer t.Stop()
 	for range t.C() {
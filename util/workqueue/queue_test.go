@@ -361,3 +361,41 @@ func mustGarbageCollect(t *testing.T, i interface{}) {
 		}
 	})
 }
+
+func TestTyped(t *testing.T) {
+	type key struct {
+		namespace, name string
+	}
+	q := workqueue.NewTyped[key]()
+	defer q.ShutDown()
+
+	first, second := key{"ns", "a"}, key{"ns", "b"}
+	q.Add(first)
+	q.Add(second)
+	q.Add(first)
+	if e, a := 2, q.Len(); e != a {
+		t.Errorf("Expected %v, got %v", e, a)
+	}
+
+	item, _ := q.Get()
+	if item != first {
+		t.Errorf("Expected %v, got %v", first, item)
+	}
+	q.Add(first)
+	q.Done(item)
+	if e, a := 2, q.Len(); e != a {
+		t.Errorf("Expected %v, got %v", e, a)
+	}
+
+	q.ShutDown()
+	for _, expected := range []key{second, first} {
+		item, shutdown := q.Get()
+		if shutdown || item != expected {
+			t.Errorf("Expected %v, got %v (shutdown %v)", expected, item, shutdown)
+		}
+		q.Done(item)
+	}
+	if item, shutdown := q.Get(); !shutdown || item != (key{}) {
+		t.Errorf("Expected zero value and shutdown, got %v (shutdown %v)", item, shutdown)
+	}
+}
@@ -25,17 +25,18 @@ import (
 
 func TestRateLimitingQueue(t *testing.T) {
 	limiter := NewItemExponentialFailureRateLimiter(1*time.Millisecond, 1*time.Second)
-	queue := NewRateLimitingQueue(limiter).(*rateLimitingType)
+	queue := NewRateLimitingQueue(limiter).(*rateLimitingType[any])
 	fakeClock := testingclock.NewFakeClock(time.Now())
-	delayingQueue := &delayingType{
-		Interface:       New(),
+	delayingQueue := &delayingType[any]{
+		TypedInterface:  New(),
 		clock:           fakeClock,
 		heartbeat:       fakeClock.NewTicker(maxWait),
 		stopCh:          make(chan struct{}),
-		waitingForAddCh: make(chan *waitFor, 1000),
+		waitingForAddCh: make(chan *waitFor[any], 1000),
+		waiting:         map[any]*waitFor[any]{},
 		metrics:         newRetryMetrics("", nil),
 	}
-	queue.DelayingInterface = delayingQueue
+	queue.TypedDelayingInterface = delayingQueue
 
 	queue.AddRateLimited("one")
 	waitEntry := <-delayingQueue.waitingForAddCh
@@ -73,3 +74,36 @@ func TestRateLimitingQueue(t *testing.T) {
 	}
 
 }
+
+func TestTypedRateLimitingQueue(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	delayingQueue := NewTypedDelayingQueueWithConfig(TypedDelayingQueueConfig[int]{Clock: fakeClock})
+	queue := NewTypedRateLimitingQueueWithConfig(
+		NewTypedItemExponentialFailureRateLimiter[int](1*time.Millisecond, 1*time.Second),
+		TypedRateLimitingQueueConfig[int]{DelayingQueue: delayingQueue},
+	)
+	defer queue.ShutDown()
+
+	queue.AddRateLimited(1)
+	queue.AddRateLimited(1)
+	if e, a := 2, queue.NumRequeues(1); e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+
+	if err := waitForWaitingQueueToFill(delayingQueue); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	fakeClock.Step(1 * time.Millisecond)
+	if err := waitForAdded(delayingQueue, 1); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	item, _ := queue.Get()
+	if item != 1 {
+		t.Errorf("expected %v, got %v", 1, item)
+	}
+	queue.Forget(item)
+	queue.Done(item)
+	if e, a := 0, queue.NumRequeues(1); e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}